@@ -0,0 +1,100 @@
+package toolruntime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimit configures admission control for a profile or backend.
+// Requests beyond MaxConcurrent wait in a bounded queue until a slot frees up.
+type ConcurrencyLimit struct {
+	// MaxConcurrent is the maximum number of executions running at once.
+	// If zero or negative, executions are not limited.
+	MaxConcurrent int
+
+	// MaxQueued is the maximum number of requests waiting for a slot.
+	// Requests arriving when the queue is full fail with ErrQueueFull.
+	// If zero, the queue is unbounded.
+	MaxQueued int
+
+	// QueueTimeout is the maximum time a request waits for a slot before
+	// failing with ErrQueueTimeout.
+	// If zero, requests wait until their context is done.
+	QueueTimeout time.Duration
+}
+
+// limiter is a counting semaphore with a bounded wait queue.
+// A nil limiter admits every request immediately.
+type limiter struct {
+	limit   ConcurrencyLimit
+	slots   chan struct{}
+	mu      sync.Mutex
+	waiting int
+}
+
+// newLimiter returns a limiter for limit, or nil if limit is unbounded.
+func newLimiter(limit ConcurrencyLimit) *limiter {
+	if limit.MaxConcurrent <= 0 {
+		return nil
+	}
+	return &limiter{
+		limit: limit,
+		slots: make(chan struct{}, limit.MaxConcurrent),
+	}
+}
+
+// acquire waits for a slot and returns the time spent waiting.
+// On success the caller must call release.
+func (l *limiter) acquire(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	// Fast path: a slot is free
+	select {
+	case l.slots <- struct{}{}:
+		return 0, nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.limit.MaxQueued > 0 && l.waiting >= l.limit.MaxQueued {
+		l.mu.Unlock()
+		return 0, fmt.Errorf("%w: %d requests waiting", ErrQueueFull, l.limit.MaxQueued)
+	}
+	l.waiting++
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.waiting--
+		l.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if l.limit.QueueTimeout > 0 {
+		timer := time.NewTimer(l.limit.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		return time.Since(start), nil
+	case <-timeout:
+		return time.Since(start), fmt.Errorf("%w: waited %s", ErrQueueTimeout, l.limit.QueueTimeout)
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// release frees a slot obtained by acquire.
+func (l *limiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
@@ -0,0 +1,212 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingBackend blocks Execute until release is closed
+type blockingBackend struct {
+	mockBackend
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingBackend(kind BackendKind) *blockingBackend {
+	return &blockingBackend{
+		mockBackend: mockBackend{kind: kind},
+		started:     make(chan struct{}, 16),
+		release:     make(chan struct{}),
+	}
+}
+
+func (b *blockingBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ExecuteResult{}, ctx.Err()
+	}
+	return b.mockBackend.Execute(ctx, req)
+}
+
+func TestLimiterNil(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{})
+	if l != nil {
+		t.Fatal("newLimiter() with MaxConcurrent 0 should return nil")
+	}
+	wait, err := l.acquire(context.Background())
+	if err != nil || wait != 0 {
+		t.Errorf("acquire() = %v, %v; want 0, nil", wait, err)
+	}
+	l.release()
+}
+
+func TestLimiterQueueFull(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxConcurrent: 1, MaxQueued: 1})
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queued := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(ctx)
+		queued <- err
+	}()
+
+	// Wait for the goroutine to enter the queue
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.mu.Lock()
+		waiting := l.waiting
+		l.mu.Unlock()
+		if waiting == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("acquire() error = %v, want %v", err, ErrQueueFull)
+	}
+
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Errorf("queued acquire() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLimiterQueueTimeout(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxConcurrent: 1, QueueTimeout: 10 * time.Millisecond})
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	wait, err := l.acquire(context.Background())
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("acquire() error = %v, want %v", err, ErrQueueTimeout)
+	}
+	if wait < 10*time.Millisecond {
+		t.Errorf("acquire() wait = %v, want >= 10ms", wait)
+	}
+}
+
+func TestLimiterRelease(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxConcurrent: 1})
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.release()
+	}()
+
+	wait, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if wait == 0 {
+		t.Error("acquire() wait = 0, want > 0")
+	}
+}
+
+func TestDefaultRuntimeProfileLimits(t *testing.T) {
+	backend := newBlockingBackend(BackendDocker)
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{ProfileStandard: backend},
+		ProfileLimits: map[SecurityProfile]ConcurrencyLimit{
+			ProfileStandard: {MaxConcurrent: 1, QueueTimeout: 20 * time.Millisecond},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = rt.Execute(context.Background(), req)
+	}()
+	<-backend.started
+
+	result, err := rt.Execute(context.Background(), req)
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrQueueTimeout)
+	}
+	if result.QueueWait == 0 {
+		t.Error("Execute().QueueWait = 0, want > 0")
+	}
+
+	close(backend.release)
+	wg.Wait()
+}
+
+func TestDefaultRuntimeBackendLimitsReportQueueWait(t *testing.T) {
+	backend := newBlockingBackend(BackendDocker)
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{ProfileStandard: backend},
+		BackendLimits: map[BackendKind]ConcurrencyLimit{
+			BackendDocker: {MaxConcurrent: 1},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = rt.Execute(context.Background(), req)
+	}()
+	<-backend.started
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(backend.release)
+	}()
+
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.QueueWait == 0 {
+		t.Error("Execute().QueueWait = 0, want > 0")
+	}
+	wg.Wait()
+}
+
+func TestDefaultRuntimeQueueRespectsCancellation(t *testing.T) {
+	backend := newBlockingBackend(BackendDocker)
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{ProfileStandard: backend},
+		ProfileLimits: map[SecurityProfile]ConcurrencyLimit{
+			ProfileStandard: {MaxConcurrent: 1},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = rt.Execute(context.Background(), req)
+	}()
+	<-backend.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := rt.Execute(ctx, req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(backend.release)
+	wg.Wait()
+}
@@ -0,0 +1,175 @@
+package toolruntime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+)
+
+// OutputDirEnv is the environment variable through which backends tell
+// executed code where to write artifacts.
+const OutputDirEnv = "TOOLRUNTIME_OUTPUT_DIR"
+
+// Artifact is a file produced by executed code, such as a CSV export, a
+// chart or a patch.
+type Artifact struct {
+	// Path is the slash-separated path relative to the output directory.
+	Path string
+
+	// Content is the file content.
+	Content []byte
+
+	// MIMEType is detected from the file extension, or from the content
+	// when the extension is unknown.
+	MIMEType string
+
+	// SHA256 is the hex-encoded SHA-256 of Content.
+	SHA256 string
+}
+
+// NewArtifact returns the artifact at path with content, detecting its MIME
+// type and computing its hash.
+func NewArtifact(name string, content []byte) Artifact {
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+	sum := sha256.Sum256(content)
+	return Artifact{
+		Path:     name,
+		Content:  content,
+		MIMEType: mimeType,
+		SHA256:   hex.EncodeToString(sum[:]),
+	}
+}
+
+// ArtifactSpec requests the collection of artifacts and caps their size.
+type ArtifactSpec struct {
+	// MaxFileBytes caps the size of a single artifact.
+	// Default: 10 MiB
+	MaxFileBytes int64
+
+	// MaxTotalBytes caps the combined size of all artifacts.
+	// Default: 50 MiB
+	MaxTotalBytes int64
+}
+
+// FileLimit returns MaxFileBytes, or its default if unset.
+func (s ArtifactSpec) FileLimit() int64 {
+	if s.MaxFileBytes <= 0 {
+		return 10 << 20
+	}
+	return s.MaxFileBytes
+}
+
+// TotalLimit returns MaxTotalBytes, or its default if unset.
+func (s ArtifactSpec) TotalLimit() int64 {
+	if s.MaxTotalBytes <= 0 {
+		return 50 << 20
+	}
+	return s.MaxTotalBytes
+}
+
+// Validate checks that the caps are not negative.
+func (s ArtifactSpec) Validate() error {
+	if s.MaxFileBytes < 0 || s.MaxTotalBytes < 0 {
+		return fmt.Errorf("%w: artifact size caps cannot be negative", ErrInvalidLimits)
+	}
+	return nil
+}
+
+// artifactCounter enforces the caps of an ArtifactSpec across artifacts.
+type artifactCounter struct {
+	spec  ArtifactSpec
+	total int64
+}
+
+// add accounts for an artifact of size bytes.
+func (c *artifactCounter) add(name string, size int64) error {
+	if size > c.spec.FileLimit() {
+		return fmt.Errorf("%w: artifact %s is %d bytes, cap is %d", ErrResourceLimit, name, size, c.spec.FileLimit())
+	}
+	c.total += size
+	if c.total > c.spec.TotalLimit() {
+		return fmt.Errorf("%w: artifacts exceed %d bytes", ErrResourceLimit, c.spec.TotalLimit())
+	}
+	return nil
+}
+
+// CheckArtifacts checks artifacts against the caps of spec. Backends that
+// receive artifacts from elsewhere, such as a container runner, use it.
+func CheckArtifacts(artifacts []Artifact, spec ArtifactSpec) error {
+	counter := artifactCounter{spec: spec}
+	for _, a := range artifacts {
+		if err := counter.add(a.Path, int64(len(a.Content))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CollectArtifacts reads the regular files under dir, in lexical order, as
+// artifacts. Symlinks and other special files are ignored. Sizes are checked
+// before files are read; exceeding a cap of spec fails with ErrResourceLimit.
+// A missing dir yields no artifacts.
+func CollectArtifacts(dir string, spec ArtifactSpec) ([]Artifact, error) {
+	root, err := os.OpenRoot(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	var (
+		artifacts []Artifact
+		counter   = artifactCounter{spec: spec}
+	)
+	err = fs.WalkDir(root.FS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := counter.add(name, info.Size()); err != nil {
+			return err
+		}
+		content, err := readArtifact(root, name, spec.FileLimit())
+		if err != nil {
+			return fmt.Errorf("read artifact %s: %w", name, err)
+		}
+		artifacts = append(artifacts, NewArtifact(name, content))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// readArtifact reads name from root, failing if it grew past limit since it
+// was listed.
+func readArtifact(root *os.Root, name string, limit int64) ([]byte, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: artifact %s exceeds %d bytes", ErrResourceLimit, name, limit)
+	}
+	return content, nil
+}
@@ -0,0 +1,84 @@
+package toolruntime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewArtifact(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"report.csv", "a,b", "text/csv; charset=utf-8"},
+		{"chart.png", "\x89PNG\r\n\x1a\n", "image/png"},
+		{"patch", "diff --git a/x b/x", "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		a := NewArtifact(tt.name, []byte(tt.data))
+		if a.MIMEType != tt.want {
+			t.Errorf("NewArtifact(%q).MIMEType = %q, want %q", tt.name, a.MIMEType, tt.want)
+		}
+	}
+
+	a := NewArtifact("x", []byte("hello"))
+	if a.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("SHA256 = %q", a.SHA256)
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("b.txt", "bb")
+	write("charts/a.svg", "<svg/>")
+	if err := os.Symlink("/etc/passwd", filepath.Join(dir, "link")); err != nil {
+		t.Logf("symlinks not supported: %v", err)
+	}
+
+	artifacts, err := CollectArtifacts(dir, ArtifactSpec{})
+	if err != nil {
+		t.Fatalf("CollectArtifacts() error = %v", err)
+	}
+	var paths []string
+	for _, a := range artifacts {
+		paths = append(paths, a.Path)
+	}
+	if got := strings.Join(paths, ","); got != "b.txt,charts/a.svg" {
+		t.Errorf("artifact paths = %s, want b.txt,charts/a.svg", got)
+	}
+
+	if _, err := CollectArtifacts(dir, ArtifactSpec{MaxFileBytes: 4}); !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("CollectArtifacts() over file cap error = %v, want ErrResourceLimit", err)
+	}
+	if _, err := CollectArtifacts(dir, ArtifactSpec{MaxTotalBytes: 7}); !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("CollectArtifacts() over total cap error = %v, want ErrResourceLimit", err)
+	}
+	if err := CheckArtifacts(artifacts, ArtifactSpec{MaxTotalBytes: 8}); err != nil {
+		t.Errorf("CheckArtifacts() error = %v", err)
+	}
+
+	if artifacts, err := CollectArtifacts(filepath.Join(dir, "missing"), ArtifactSpec{}); err != nil || artifacts != nil {
+		t.Errorf("CollectArtifacts(missing) = %v, %v; want none", artifacts, err)
+	}
+}
+
+func TestExecuteRequestValidateArtifacts(t *testing.T) {
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Artifacts: &ArtifactSpec{MaxFileBytes: -1}}
+	if err := req.Validate(); !errors.Is(err, ErrInvalidLimits) {
+		t.Errorf("Validate() error = %v, want ErrInvalidLimits", err)
+	}
+}
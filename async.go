@@ -0,0 +1,331 @@
+package toolruntime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultJobTTL is used when AsyncConfig.TTL is zero.
+const defaultJobTTL = 10 * time.Minute
+
+// JobStatus is the lifecycle state of an asynchronous execution.
+type JobStatus string
+
+const (
+	// JobPending indicates the job was submitted but has not started yet.
+	JobPending JobStatus = "pending"
+
+	// JobRunning indicates the job is executing.
+	JobRunning JobStatus = "running"
+
+	// JobSucceeded indicates the job completed without error.
+	JobSucceeded JobStatus = "succeeded"
+
+	// JobFailed indicates the job completed with an error.
+	JobFailed JobStatus = "failed"
+
+	// JobCanceled indicates the job was canceled before it completed.
+	JobCanceled JobStatus = "canceled"
+)
+
+// Done reports whether s is a terminal status.
+func (s JobStatus) Done() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// Handle refers to an asynchronous execution submitted to an AsyncRuntime.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: Wait honors ctx cancellation; canceling the wait does not cancel the job.
+// - Ownership: results are caller-owned snapshots.
+type Handle interface {
+	// ID returns the execution ID of the job.
+	ID() string
+
+	// Status returns the current status of the job.
+	Status() JobStatus
+
+	// Wait blocks until the job completes or ctx is done, and returns the
+	// job's final result and error.
+	Wait(ctx context.Context) (ExecuteResult, error)
+
+	// Cancel requests cancellation of the job. It is a no-op once the job
+	// has completed.
+	Cancel()
+}
+
+// AsyncConfig configures an AsyncRuntime.
+type AsyncConfig struct {
+	// TTL is how long completed jobs are retained for retrieval.
+	// Default: 10 minutes
+	TTL time.Duration
+
+	// CleanupInterval is how often expired jobs are removed.
+	// Default: TTL / 2
+	CleanupInterval time.Duration
+
+	// Now returns the current time. Default: time.Now
+	Now func() time.Time
+}
+
+// AsyncRuntime runs executions in the background on top of a Runtime and
+// keeps their results in an in-memory job table keyed by execution ID.
+// Completed jobs are removed once their TTL expires.
+type AsyncRuntime struct {
+	rt  Runtime
+	ttl time.Duration
+	now func() time.Time
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	closed bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAsyncRuntime creates an AsyncRuntime that executes jobs with rt.
+// Call Close to stop the cleanup loop and cancel running jobs.
+func NewAsyncRuntime(rt Runtime, cfg AsyncConfig) *AsyncRuntime {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultJobTTL
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = cfg.TTL / 2
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	a := &AsyncRuntime{
+		rt:   rt,
+		ttl:  cfg.TTL,
+		now:  cfg.Now,
+		jobs: make(map[string]*job),
+		stop: make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.cleanupLoop(cfg.CleanupInterval)
+	return a
+}
+
+// Submit starts req in the background and returns a handle to it.
+// If req.ExecutionID is empty a new one is assigned. Submitting an
+// execution ID that is still in the job table fails with ErrJobExists.
+func (a *AsyncRuntime) Submit(req ExecuteRequest) (Handle, error) {
+	if req.ExecutionID == "" {
+		req.ExecutionID = NewExecutionID()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:        req.ExecutionID,
+		status:    JobPending,
+		submitted: a.now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("%w: async runtime closed", ErrRuntimeUnavailable)
+	}
+	if _, ok := a.jobs[j.id]; ok {
+		a.mu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("%w: %s", ErrJobExists, j.id)
+	}
+	a.jobs[j.id] = j
+	a.wg.Add(1)
+	a.mu.Unlock()
+
+	go a.run(ctx, j, req)
+	return j, nil
+}
+
+// run executes req and records its outcome on j.
+func (a *AsyncRuntime) run(ctx context.Context, j *job, req ExecuteRequest) {
+	defer a.wg.Done()
+	defer j.cancel()
+
+	j.mu.Lock()
+	if j.canceled {
+		j.mu.Unlock()
+		j.finish(JobCanceled, ExecuteResult{ExecutionID: j.id}, context.Canceled, a.now())
+		return
+	}
+	j.status = JobRunning
+	j.mu.Unlock()
+
+	result, err := a.rt.Execute(ctx, req)
+
+	status := JobSucceeded
+	switch {
+	case err != nil && j.wasCanceled():
+		status = JobCanceled
+	case err != nil:
+		status = JobFailed
+	}
+	j.finish(status, result, err, a.now())
+}
+
+// Job returns the handle for the job with the given execution ID.
+// It fails with ErrJobNotFound if the job is unknown or has expired.
+func (a *AsyncRuntime) Job(id string) (Handle, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	j, ok := a.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return j, nil
+}
+
+// Status returns the status of the job with the given execution ID.
+func (a *AsyncRuntime) Status(id string) (JobStatus, error) {
+	j, err := a.Job(id)
+	if err != nil {
+		return "", err
+	}
+	return j.Status(), nil
+}
+
+// Wait blocks until the job with the given execution ID completes or ctx is
+// done, and returns its final result and error.
+func (a *AsyncRuntime) Wait(ctx context.Context, id string) (ExecuteResult, error) {
+	j, err := a.Job(id)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	return j.Wait(ctx)
+}
+
+// Cancel requests cancellation of the job with the given execution ID.
+func (a *AsyncRuntime) Cancel(id string) error {
+	j, err := a.Job(id)
+	if err != nil {
+		return err
+	}
+	j.Cancel()
+	return nil
+}
+
+// Close cancels all running jobs, waits for them to finish and stops the
+// cleanup loop. Completed jobs remain retrievable until Close returns;
+// subsequent calls to Submit fail.
+func (a *AsyncRuntime) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	for _, j := range a.jobs {
+		j.Cancel()
+	}
+	a.mu.Unlock()
+
+	close(a.stop)
+	a.wg.Wait()
+	return nil
+}
+
+// cleanupLoop periodically removes expired jobs until Close is called.
+func (a *AsyncRuntime) cleanupLoop(interval time.Duration) {
+	defer a.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.removeExpired()
+		}
+	}
+}
+
+// removeExpired removes completed jobs whose TTL has expired.
+func (a *AsyncRuntime) removeExpired() {
+	cutoff := a.now().Add(-a.ttl)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, j := range a.jobs {
+		if finished, ok := j.finishedAt(); ok && !finished.After(cutoff) {
+			delete(a.jobs, id)
+		}
+	}
+}
+
+// job is the Handle implementation stored in the job table.
+type job struct {
+	id        string
+	submitted time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu       sync.Mutex
+	status   JobStatus
+	canceled bool
+	result   ExecuteResult
+	err      error
+	finished time.Time
+}
+
+func (j *job) ID() string { return j.id }
+
+func (j *job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *job) Wait(ctx context.Context) (ExecuteResult, error) {
+	select {
+	case <-j.done:
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return j.result, j.err
+	case <-ctx.Done():
+		return ExecuteResult{}, ctx.Err()
+	}
+}
+
+func (j *job) Cancel() {
+	j.mu.Lock()
+	if !j.status.Done() {
+		j.canceled = true
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
+
+func (j *job) wasCanceled() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.canceled
+}
+
+func (j *job) finish(status JobStatus, result ExecuteResult, err error, now time.Time) {
+	j.mu.Lock()
+	j.status = status
+	j.result = result
+	j.err = err
+	j.finished = now
+	j.mu.Unlock()
+	close(j.done)
+}
+
+// finishedAt returns when the job completed, and whether it has.
+func (j *job) finishedAt() (time.Time, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished, j.status.Done()
+}
+
+var _ Handle = (*job)(nil)
@@ -0,0 +1,182 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newAsyncTestRuntime(backend Backend) *DefaultRuntime {
+	return NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+		DefaultProfile: ProfileDev,
+	})
+}
+
+func TestAsyncRuntimeSubmitWait(t *testing.T) {
+	backend := newBlockingBackend(BackendUnsafeHost)
+	backend.result = ExecuteResult{Value: "done"}
+	a := NewAsyncRuntime(newAsyncTestRuntime(backend), AsyncConfig{})
+	defer a.Close()
+
+	h, err := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if h.ID() == "" {
+		t.Fatal("Handle.ID() is empty")
+	}
+
+	<-backend.started
+	if got := h.Status(); got != JobRunning {
+		t.Errorf("Status() = %q, want %q", got, JobRunning)
+	}
+	close(backend.release)
+
+	result, err := h.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if result.Value != "done" || result.ExecutionID != h.ID() {
+		t.Errorf("Wait() = %+v, want Value done and ExecutionID %q", result, h.ID())
+	}
+	if got, _ := a.Status(h.ID()); got != JobSucceeded {
+		t.Errorf("Status() = %q, want %q", got, JobSucceeded)
+	}
+
+	// The final result is retrievable by execution ID
+	result, err = a.Wait(context.Background(), h.ID())
+	if err != nil || result.Value != "done" {
+		t.Errorf("AsyncRuntime.Wait() = %+v, %v; want stored result", result, err)
+	}
+}
+
+func TestAsyncRuntimeFailed(t *testing.T) {
+	a := NewAsyncRuntime(newAsyncTestRuntime(&mockBackend{kind: BackendUnsafeHost, executeErr: ErrSandboxViolation}), AsyncConfig{})
+	defer a.Close()
+
+	h, err := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, ExecutionID: "job-1"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if h.ID() != "job-1" {
+		t.Errorf("Handle.ID() = %q, want %q", h.ID(), "job-1")
+	}
+	if _, err := h.Wait(context.Background()); !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("Wait() error = %v, want ErrSandboxViolation", err)
+	}
+	if got := h.Status(); got != JobFailed {
+		t.Errorf("Status() = %q, want %q", got, JobFailed)
+	}
+}
+
+func TestAsyncRuntimeCancel(t *testing.T) {
+	backend := newBlockingBackend(BackendUnsafeHost)
+	a := NewAsyncRuntime(newAsyncTestRuntime(backend), AsyncConfig{})
+	defer a.Close()
+
+	h, err := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-backend.started
+
+	if err := a.Cancel(h.ID()); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if _, err := h.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+	if got := h.Status(); got != JobCanceled {
+		t.Errorf("Status() = %q, want %q", got, JobCanceled)
+	}
+}
+
+func TestAsyncRuntimeWaitContext(t *testing.T) {
+	backend := newBlockingBackend(BackendUnsafeHost)
+	a := NewAsyncRuntime(newAsyncTestRuntime(backend), AsyncConfig{})
+	defer a.Close()
+
+	h, _ := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	<-backend.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := h.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want context.DeadlineExceeded", err)
+	}
+	if got := h.Status(); got != JobRunning {
+		t.Errorf("Status() after abandoned Wait = %q, want %q", got, JobRunning)
+	}
+	close(backend.release)
+}
+
+func TestAsyncRuntimeDuplicateAndUnknown(t *testing.T) {
+	backend := newBlockingBackend(BackendUnsafeHost)
+	a := NewAsyncRuntime(newAsyncTestRuntime(backend), AsyncConfig{})
+	defer a.Close()
+	defer close(backend.release)
+
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, ExecutionID: "dup"}
+	if _, err := a.Submit(req); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := a.Submit(req); !errors.Is(err, ErrJobExists) {
+		t.Errorf("Submit() duplicate error = %v, want ErrJobExists", err)
+	}
+	if _, err := a.Job("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Job() error = %v, want ErrJobNotFound", err)
+	}
+	if _, err := a.Status("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Status() error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestAsyncRuntimeTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a := NewAsyncRuntime(newAsyncTestRuntime(&mockBackend{kind: BackendUnsafeHost}), AsyncConfig{
+		TTL:             time.Minute,
+		CleanupInterval: time.Hour,
+		Now:             func() time.Time { return now },
+	})
+	defer a.Close()
+
+	h, err := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := h.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	a.removeExpired()
+	if _, err := a.Job(h.ID()); err != nil {
+		t.Errorf("Job() before TTL error = %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	a.removeExpired()
+	if _, err := a.Job(h.ID()); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Job() after TTL error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestAsyncRuntimeClose(t *testing.T) {
+	backend := newBlockingBackend(BackendUnsafeHost)
+	a := NewAsyncRuntime(newAsyncTestRuntime(backend), AsyncConfig{})
+
+	h, _ := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	<-backend.started
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := h.Status(); got != JobCanceled {
+		t.Errorf("Status() after Close = %q, want %q", got, JobCanceled)
+	}
+	if _, err := a.Submit(ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}); !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("Submit() after Close error = %v, want ErrRuntimeUnavailable", err)
+	}
+}
@@ -0,0 +1,292 @@
+package toolruntime
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one execution for the audit log.
+type AuditRecord struct {
+	// Time is when the execution started.
+	Time time.Time `json:"time"`
+
+	// ExecutionID identifies the execution.
+	ExecutionID string `json:"executionId"`
+
+	// Metadata is the caller metadata from the request.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// Profile is the security profile used.
+	Profile SecurityProfile `json:"profile"`
+
+	// Backend is the backend kind that produced the result, if any.
+	Backend BackendKind `json:"backend,omitempty"`
+
+	// Language is the requested language.
+	Language string `json:"language,omitempty"`
+
+	// CodeSHA256 is the hex-encoded SHA-256 of the executed code.
+	CodeSHA256 string `json:"codeSha256"`
+
+	// FileSHA256 maps each workspace file path to the hex-encoded SHA-256 of
+	// its content.
+	FileSHA256 map[string]string `json:"fileSha256,omitempty"`
+
+	// Timeout is the requested timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Limits are the requested limits.
+	Limits Limits `json:"limits"`
+
+	// LimitsEnforced reports which limits the backend enforced.
+	LimitsEnforced LimitsEnforced `json:"limitsEnforced"`
+
+	// Outcome classifies the result, as returned by OutcomeOf.
+	Outcome string `json:"outcome"`
+
+	// Error is the error message, if the execution failed.
+	Error string `json:"error,omitempty"`
+
+	// Duration is the execution time reported by the backend.
+	Duration time.Duration `json:"duration"`
+
+	// ToolCalls lists the tool invocations made during execution.
+	ToolCalls []ToolCallRecord `json:"toolCalls,omitempty"`
+
+	// ArtifactSHA256 maps each collected artifact path to the hex-encoded
+	// SHA-256 of its content.
+	ArtifactSHA256 map[string]string `json:"artifactSha256,omitempty"`
+}
+
+// AuditSink receives an AuditRecord for every execution handled by
+// DefaultRuntime.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: the context is not canceled when the execution is.
+// - Errors: returned errors are logged; they do not fail the execution.
+// - Ownership: records are read-only; copy them to retain mutable fields.
+type AuditSink interface {
+	Record(ctx context.Context, rec AuditRecord) error
+}
+
+// newAuditRecord builds the audit record for an execution.
+func newAuditRecord(start time.Time, req ExecuteRequest, profile SecurityProfile, result ExecuteResult, err error) AuditRecord {
+	sum := sha256.Sum256([]byte(req.Code))
+	rec := AuditRecord{
+		Time:           start,
+		ExecutionID:    req.ExecutionID,
+		Metadata:       req.Metadata,
+		Profile:        profile,
+		Backend:        result.Backend.Kind,
+		Language:       req.Language,
+		CodeSHA256:     hex.EncodeToString(sum[:]),
+		Timeout:        req.Timeout,
+		Limits:         req.Limits,
+		LimitsEnforced: result.LimitsEnforced,
+		Outcome:        OutcomeOf(err),
+		Duration:       result.Duration,
+		ToolCalls:      result.ToolCalls,
+	}
+	if len(req.Files) > 0 {
+		rec.FileSHA256 = make(map[string]string, len(req.Files))
+		for name, f := range req.Files {
+			sum := sha256.Sum256(f.Content)
+			rec.FileSHA256[name] = hex.EncodeToString(sum[:])
+		}
+	}
+	if len(result.Artifacts) > 0 {
+		rec.ArtifactSHA256 = make(map[string]string, len(result.Artifacts))
+		for _, a := range result.Artifacts {
+			rec.ArtifactSHA256[a.Path] = a.SHA256
+		}
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// AuditEntry is one line of an AuditLog. Each entry's Hash covers its
+// sequence number, the previous entry's hash and the record, so editing,
+// reordering or deleting an entry breaks the chain. Without a key, anyone who
+// can write the log can recompute the chain after editing it, so an unkeyed
+// chain only detects accidental corruption; set AuditLogConfig.Key to detect
+// deliberate tampering.
+type AuditEntry struct {
+	// Seq is the 1-based position of the entry in the log.
+	Seq uint64 `json:"seq"`
+
+	// PrevHash is the Hash of the previous entry, or empty for the first.
+	PrevHash string `json:"prevHash"`
+
+	// Record is the JSON-encoded AuditRecord.
+	Record json.RawMessage `json:"record"`
+
+	// Hash is the hex-encoded SHA-256, or HMAC-SHA256 for a keyed log, of
+	// the entry without Hash.
+	Hash string `json:"hash"`
+}
+
+// digest computes the entry's hash, keyed with key if it is not empty.
+func (e AuditEntry) digest(key []byte) (string, error) {
+	data, err := json.Marshal(struct {
+		Seq      uint64          `json:"seq"`
+		PrevHash string          `json:"prevHash"`
+		Record   json.RawMessage `json:"record"`
+	}{e.Seq, e.PrevHash, e.Record})
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// AuditLogConfig configures an AuditLog.
+type AuditLogConfig struct {
+	// Key is the secret for HMAC-SHA256 entry hashes. Keep it outside the
+	// log's host: only holders of the key can extend or rewrite a keyed
+	// chain. Without a key, entries are hashed with plain SHA-256 and the
+	// chain only detects accidental corruption.
+	// Default: none
+	Key []byte
+}
+
+// AuditLog is an AuditSink that appends hash-chained AuditEntry values to a
+// writer as JSON lines. Use VerifyAuditLog to check a log for tampering.
+type AuditLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	key    []byte
+	seq    uint64
+	last   string
+}
+
+// NewAuditLog creates an AuditLog that starts a new chain on w.
+func NewAuditLog(w io.Writer, cfg AuditLogConfig) *AuditLog {
+	return &AuditLog{w: w, key: cfg.Key}
+}
+
+// OpenAuditLog opens or creates the audit log file at path for appending.
+// An existing log is verified with cfg.Key first and its chain is continued;
+// if it fails verification, OpenAuditLog returns an error wrapping
+// ErrAuditLogTampered.
+func OpenAuditLog(path string, cfg AuditLogConfig) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	seq, last, err := VerifyAuditLog(f, cfg.Key)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("open audit log %s: %w", path, err)
+	}
+	return &AuditLog{w: f, closer: f, key: cfg.Key, seq: seq, last: last}, nil
+}
+
+// Record implements AuditSink.
+func (l *AuditLog) Record(_ context.Context, rec AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode audit record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := AuditEntry{Seq: l.seq + 1, PrevHash: l.last, Record: data}
+	if entry.Hash, err = entry.digest(l.key); err != nil {
+		return fmt.Errorf("hash audit entry: %w", err)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit entry: %w", err)
+	}
+	l.seq, l.last = entry.Seq, entry.Hash
+	return nil
+}
+
+// Head returns the sequence number and hash of the last entry. Storing the
+// head elsewhere lets VerifyAuditLog's result be checked for truncation.
+func (l *AuditLog) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.last
+}
+
+// Close closes the underlying file if the log was opened with OpenAuditLog.
+func (l *AuditLog) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// VerifyAuditLog reads an audit log written by AuditLog and checks its hash
+// chain, using key for a log written with AuditLogConfig.Key. It returns the sequence number and hash of the last entry, or an
+// error wrapping ErrAuditLogTampered that identifies the first bad line.
+// Removing entries from the end of the log keeps the chain valid; compare the
+// returned head with one recorded elsewhere to detect that.
+func VerifyAuditLog(r io.Reader, key []byte) (uint64, string, error) {
+	var (
+		seq  uint64
+		last string
+		line int
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for scanner.Scan() {
+		line++
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return seq, last, fmt.Errorf("%w: line %d: %v", ErrAuditLogTampered, line, err)
+		}
+		if entry.Seq != seq+1 {
+			return seq, last, fmt.Errorf("%w: line %d: sequence %d, want %d", ErrAuditLogTampered, line, entry.Seq, seq+1)
+		}
+		if entry.PrevHash != last {
+			return seq, last, fmt.Errorf("%w: line %d: previous hash mismatch", ErrAuditLogTampered, line)
+		}
+		hash, err := entry.digest(key)
+		if err != nil || !hmac.Equal([]byte(hash), []byte(entry.Hash)) {
+			return seq, last, fmt.Errorf("%w: line %d: hash mismatch", ErrAuditLogTampered, line)
+		}
+		seq, last = entry.Seq, entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return seq, last, err
+	}
+	return seq, last, nil
+}
+
+// recordAudit sends the audit record for an execution to the configured sink.
+func (r *DefaultRuntime) recordAudit(ctx context.Context, start time.Time, req ExecuteRequest, result ExecuteResult, err error) {
+	if r.audit == nil {
+		return
+	}
+	profile := req.Profile
+	if profile == "" {
+		profile = r.defaultProfile
+	}
+	rec := newAuditRecord(start, req, profile, result, err)
+	if auditErr := r.audit.Record(context.WithoutCancel(ctx), rec); auditErr != nil && r.logger != nil {
+		r.logger.Error("audit record failed", "executionID", req.ExecutionID, "error", auditErr)
+	}
+}
@@ -0,0 +1,247 @@
+package toolruntime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryAuditSink collects audit records
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+	err     error
+}
+
+func (s *memoryAuditSink) Record(_ context.Context, rec AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return s.err
+}
+
+func TestDefaultRuntimeAudit(t *testing.T) {
+	sink := &memoryAuditSink{}
+	backend := &mockBackend{
+		kind: BackendDocker,
+		result: ExecuteResult{
+			ToolCalls:      []ToolCallRecord{{ToolID: "ns:tool"}},
+			LimitsEnforced: LimitsEnforced{Timeout: true},
+		},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		Audit:          sink,
+		DefaultProfile: ProfileStandard,
+	})
+
+	req := ExecuteRequest{
+		Language: "go",
+		Code:     "__out = 1",
+		Timeout:  time.Second,
+		Limits:   Limits{MaxToolCalls: 5},
+		Metadata: map[string]any{"tenant": "acme"},
+		Gateway:  &mockToolGateway{},
+	}
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if _, err := rt.Execute(context.Background(), ExecuteRequest{Code: "x", Profile: ProfileHardened, Gateway: &mockToolGateway{}}); err == nil {
+		t.Fatal("Execute() on unconfigured profile should fail")
+	}
+
+	if len(sink.records) != 2 {
+		t.Fatalf("audit records = %d, want 2", len(sink.records))
+	}
+	rec := sink.records[0]
+	sum := sha256.Sum256([]byte(req.Code))
+	if rec.ExecutionID != result.ExecutionID || rec.CodeSHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("record ID/hash = %q/%q, want %q/%x", rec.ExecutionID, rec.CodeSHA256, result.ExecutionID, sum)
+	}
+	if rec.Profile != ProfileStandard || rec.Backend != BackendDocker || rec.Outcome != OutcomeSuccess {
+		t.Errorf("record = %+v, want standard/docker/success", rec)
+	}
+	if rec.Limits.MaxToolCalls != 5 || rec.Timeout != time.Second || !rec.LimitsEnforced.Timeout {
+		t.Errorf("record limits = %+v/%v/%+v", rec.Limits, rec.Timeout, rec.LimitsEnforced)
+	}
+	if rec.Metadata["tenant"] != "acme" || len(rec.ToolCalls) != 1 || rec.Time.IsZero() {
+		t.Errorf("record = %+v, want metadata, tool calls and time", rec)
+	}
+
+	failed := sink.records[1]
+	if failed.Profile != ProfileHardened || failed.Outcome != OutcomeUnavailable || failed.Error == "" {
+		t.Errorf("failed record = %+v, want hardened/unavailable with error", failed)
+	}
+}
+
+func TestDefaultRuntimeAuditRejected(t *testing.T) {
+	sink := &memoryAuditSink{}
+	backend := &mockBackend{kind: BackendDocker, executeErr: NewRuntimeError(BackendDocker, "run", ErrSandboxViolation, false)}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		Audit:          sink,
+		DefaultProfile: ProfileStandard,
+	})
+
+	// A failed execution keeps the tool calls recorded by the gateway
+	gateway := &sharedRecorderGateway{calls: []ToolCallRecord{{ToolID: "ns:tool"}}}
+	if _, err := rt.Execute(context.Background(), ExecuteRequest{Code: "x", Gateway: gateway}); err == nil {
+		t.Fatal("Execute() error = nil, want backend error")
+	}
+
+	// Requests rejected before they run are audited too
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rt.Execute(ctx, ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() canceled error = %v", err)
+	}
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if _, err := rt.Execute(context.Background(), ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}); err == nil {
+		t.Fatal("Execute() after Shutdown error = nil")
+	}
+
+	if len(sink.records) != 3 {
+		t.Fatalf("audit records = %d, want 3", len(sink.records))
+	}
+	if failed := sink.records[0]; failed.Outcome != OutcomeError || len(failed.ToolCalls) != 1 {
+		t.Errorf("failed record = %+v, want error with tool calls", failed)
+	}
+	for _, rec := range sink.records[1:] {
+		if rec.ExecutionID == "" || rec.Error == "" || rec.Outcome == OutcomeSuccess {
+			t.Errorf("rejected record = %+v, want ID and error", rec)
+		}
+	}
+}
+
+func TestDefaultRuntimeAuditErrorDoesNotFail(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: &mockBackend{kind: BackendDocker}},
+		Audit:          &memoryAuditSink{err: errors.New("disk full")},
+		DefaultProfile: ProfileStandard,
+	})
+	if _, err := rt.Execute(context.Background(), ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}); err != nil {
+		t.Errorf("Execute() error = %v, want nil", err)
+	}
+}
+
+func writeAuditLog(t *testing.T, n int, key []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	log := NewAuditLog(&buf, AuditLogConfig{Key: key})
+	for i := range n {
+		if err := log.Record(context.Background(), AuditRecord{ExecutionID: string(rune('a' + i)), Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	_, head := log.Head()
+	return &buf, head
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	buf, head := writeAuditLog(t, 3, nil)
+	seq, last, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("VerifyAuditLog() error = %v", err)
+	}
+	if seq != 3 || last != head {
+		t.Errorf("VerifyAuditLog() = %d, %q; want 3, %q", seq, last, head)
+	}
+
+	lines := strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{"edited", strings.Replace(buf.String(), `"executionId":"b"`, `"executionId":"z"`, 1)},
+		{"deleted", lines[0] + lines[2]},
+		{"reordered", lines[1] + lines[0] + lines[2]},
+		{"truncated head", lines[1] + lines[2]},
+		{"garbage", buf.String() + "not json\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := VerifyAuditLog(strings.NewReader(tt.log), nil); !errors.Is(err, ErrAuditLogTampered) {
+				t.Errorf("VerifyAuditLog() error = %v, want ErrAuditLogTampered", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditLogKeyed(t *testing.T) {
+	key := []byte("audit key")
+	buf, head := writeAuditLog(t, 3, key)
+	if seq, last, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), key); err != nil || seq != 3 || last != head {
+		t.Fatalf("VerifyAuditLog() = %d, %q, %v; want 3, %q, nil", seq, last, err, head)
+	}
+	for _, wrong := range [][]byte{nil, []byte("other key")} {
+		if _, _, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), wrong); !errors.Is(err, ErrAuditLogTampered) {
+			t.Errorf("VerifyAuditLog(key %q) error = %v, want ErrAuditLogTampered", wrong, err)
+		}
+	}
+
+	// An unkeyed chain recomputed after editing does not pass as keyed
+	forged, _ := writeAuditLog(t, 3, nil)
+	if _, _, err := VerifyAuditLog(forged, key); !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("VerifyAuditLog(forged) error = %v, want ErrAuditLogTampered", err)
+	}
+}
+
+func TestOpenAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log, err := OpenAuditLog(path, AuditLogConfig{})
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	for range 2 {
+		if err := log.Record(context.Background(), AuditRecord{Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening continues the chain
+	log, err = OpenAuditLog(path, AuditLogConfig{})
+	if err != nil {
+		t.Fatalf("OpenAuditLog() existing error = %v", err)
+	}
+	if seq, _ := log.Head(); seq != 2 {
+		t.Errorf("Head() seq = %d, want 2", seq)
+	}
+	if err := log.Record(context.Background(), AuditRecord{Outcome: OutcomeError}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	_, head := log.Head()
+	_ = log.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if seq, last, err := VerifyAuditLog(f, nil); err != nil || seq != 3 || last != head {
+		t.Errorf("VerifyAuditLog() = %d, %q, %v; want 3, %q, nil", seq, last, err, head)
+	}
+
+	// A tampered log is refused
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, bytes.Replace(data, []byte(`"outcome":"error"`), []byte(`"outcome":"success"`), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAuditLog(path, AuditLogConfig{}); !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("OpenAuditLog() tampered error = %v, want ErrAuditLogTampered", err)
+	}
+}
@@ -96,7 +96,7 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		return toolruntime.ExecuteResult{}, err
 	}
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendContainerd, "validate", err, false)
 	}
 
 	start := time.Now()
@@ -112,7 +112,8 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		},
 	}
 
-	return result, fmt.Errorf("%w: containerd backend not fully implemented", ErrContainerdNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendContainerd, "execute",
+		fmt.Errorf("%w: %w: containerd backend not fully implemented", ErrContainerdNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
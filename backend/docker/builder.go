@@ -65,6 +65,34 @@ func (b *SpecBuilder) WithTmpfs(target string) *SpecBuilder {
 	})
 }
 
+// WithTmpfsSize adds a tmpfs mount at the given target, capped at sizeBytes.
+func (b *SpecBuilder) WithTmpfsSize(target string, sizeBytes int64) *SpecBuilder {
+	return b.WithMount(Mount{
+		Type:      MountTypeTmpfs,
+		Target:    target,
+		SizeBytes: sizeBytes,
+	})
+}
+
+// WithStdin sets the data written to the command's standard input.
+func (b *SpecBuilder) WithStdin(data []byte) *SpecBuilder {
+	b.spec.Stdin = data
+	return b
+}
+
+// WithFile adds a file to write into the container before the command runs.
+func (b *SpecBuilder) WithFile(f File) *SpecBuilder {
+	b.spec.Files = append(b.spec.Files, f)
+	return b
+}
+
+// WithOutputDir sets the directory copied out of the container after the
+// command exits.
+func (b *SpecBuilder) WithOutputDir(dir string) *SpecBuilder {
+	b.spec.OutputDir = dir
+	return b
+}
+
 // WithResources sets the resource limits.
 func (b *SpecBuilder) WithResources(r ResourceSpec) *SpecBuilder {
 	b.spec.Resources = r
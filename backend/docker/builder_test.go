@@ -134,6 +134,26 @@ func TestSpecBuilder(t *testing.T) {
 		}
 	})
 
+	t.Run("with sized tmpfs and files", func(t *testing.T) {
+		spec, err := NewSpecBuilder("alpine:latest").
+			WithTmpfsSize("/workspace", 1024).
+			WithFile(File{Path: "/workspace/main.py", Content: []byte("print(1)"), Mode: 0o644}).
+			Build()
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		if len(spec.Mounts) != 1 || spec.Mounts[0].SizeBytes != 1024 {
+			t.Fatalf("Mounts = %+v, want one 1024-byte tmpfs", spec.Mounts)
+		}
+		if len(spec.Files) != 1 || spec.Files[0].Path != "/workspace/main.py" {
+			t.Errorf("Files = %+v, want /workspace/main.py", spec.Files)
+		}
+
+		if _, err := NewSpecBuilder("alpine:latest").WithFile(File{Path: "/etc/passwd"}).Build(); err == nil {
+			t.Error("Build() with file outside tmpfs should fail")
+		}
+	})
+
 	t.Run("with resources", func(t *testing.T) {
 		spec, err := NewSpecBuilder("alpine:latest").
 			WithResources(ResourceSpec{
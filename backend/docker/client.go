@@ -10,8 +10,12 @@ import "context"
 //
 // Implementations are expected to:
 //   - Create a container from the spec
-//   - Start and wait for container completion
-//   - Capture stdout/stderr
+//   - Write spec.Files into their tmpfs mounts before the command runs
+//   - Start the container with spec.Stdin attached as its standard input
+//     and wait for its completion
+//   - Capture stdout/stderr unmodified, including the result envelope
+//     framed with the nonce in spec.Env (see toolruntime.ResultEnvelope)
+//   - Copy the files under spec.OutputDir out before removing the container
 //   - Remove the container after execution
 //   - Respect context cancellation and spec timeout
 type ContainerRunner interface {
@@ -19,6 +19,22 @@ func (m *MockContainerRunner) Run(ctx context.Context, spec ContainerSpec) (Cont
 	return ContainerResult{}, nil
 }
 
+// MockStreamRunner is a test double for StreamRunner.
+type MockStreamRunner struct {
+	MockContainerRunner
+	RunStreamFunc func(ctx context.Context, spec ContainerSpec) (<-chan StreamEvent, error)
+}
+
+func (m *MockStreamRunner) RunStream(ctx context.Context, spec ContainerSpec) (<-chan StreamEvent, error) {
+	if m.RunStreamFunc != nil {
+		return m.RunStreamFunc(ctx, spec)
+	}
+	ch := make(chan StreamEvent, 1)
+	ch <- StreamEvent{Type: StreamEventExit}
+	close(ch)
+	return ch, nil
+}
+
 // MockImageResolver is a test double for ImageResolver.
 type MockImageResolver struct {
 	ResolveFunc func(ctx context.Context, image string) (string, error)
@@ -6,6 +6,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jonwraymond/toolruntime"
@@ -14,6 +20,7 @@ import (
 // Errors for Docker backend operations.
 var (
 	// ErrDockerNotAvailable is returned when Docker is not available.
+	// Backend errors wrapping it also wrap toolruntime.ErrRuntimeUnavailable.
 	ErrDockerNotAvailable = errors.New("docker not available")
 
 	// ErrImageNotFound is returned when the execution image is not found.
@@ -38,6 +45,9 @@ var (
 	ErrImagePull = errors.New("image pull failed")
 
 	// ErrDaemonUnavailable is returned when the Docker daemon is not reachable.
+	// Backend errors wrapping it also wrap toolruntime.ErrRuntimeUnavailable,
+	// whether the health check or the client reports it, so the runtime
+	// falls back to another backend.
 	ErrDaemonUnavailable = errors.New("docker daemon unavailable")
 
 	// ErrResourceLimit is returned when a resource limit is exceeded.
@@ -97,15 +107,39 @@ type ContainerOptions struct {
 
 // Config configures a Docker backend.
 type Config struct {
-	// ImageName is the Docker image to use for execution.
-	// Default: toolruntime-sandbox:latest
+	// ImageName is the Docker image to use for every execution. When empty,
+	// requests that name a language use the image of the language, and
+	// other requests use the sandbox image, which is expected to provide
+	// the toolchains of all registered languages.
+	// Default: the language's image, or toolruntime-sandbox:latest
 	ImageName string
 
+	// Languages resolves ExecuteRequest.Language. The wrapped code is
+	// written to the workspace and run with the command of its language.
+	// Default: toolruntime.DefaultLanguages()
+	Languages *toolruntime.LanguageRegistry
+
 	// SeccompPath is the path to a custom seccomp profile for hardened mode.
 	SeccompPath string
 
+	// WorkspaceDir is where request files are mounted inside the container,
+	// as a tmpfs that is also the working directory.
+	// Default: /workspace
+	WorkspaceDir string
+
+	// WorkspaceSizeBytes caps the workspace tmpfs. Limits.DiskBytes, when
+	// set, takes precedence.
+	// Default: 64 MiB
+	WorkspaceSizeBytes int64
+
+	// OutputDir is where executed code writes artifacts inside the
+	// container, as a tmpfs capped at the request's total artifact size.
+	// Default: /output
+	OutputDir string
+
 	// Client is the container runner implementation.
 	// If nil, Execute() returns ErrClientNotConfigured.
+	// If it implements io.Closer, Close closes it.
 	Client ContainerRunner
 
 	// ImageResolver optionally resolves/pulls images before execution.
@@ -116,6 +150,17 @@ type Config struct {
 	// If nil, health checks are skipped.
 	HealthChecker HealthChecker
 
+	// SkipExecuteHealthCheck disables the HealthChecker ping before
+	// executions. Set it when a toolruntime.HealthMonitor probes the backend
+	// through HealthCheck instead.
+	SkipExecuteHealthCheck bool
+
+	// HealthCheckInterval is how long a successful ping before an execution
+	// is trusted; executions within it do not ping the daemon again.
+	// Failed pings are not cached.
+	// Default: 30 seconds
+	HealthCheckInterval time.Duration
+
 	// Logger is an optional logger for backend events.
 	Logger Logger
 }
@@ -134,26 +179,61 @@ type Logger interface {
 // Backend executes code in Docker containers with security isolation.
 type Backend struct {
 	imageName     string
+	languages     *toolruntime.LanguageRegistry
 	seccompPath   string
+	workspaceDir  string
+	workspaceSize int64
+	outputDir     string
 	client        ContainerRunner
 	imageResolver ImageResolver
 	healthChecker HealthChecker
+	skipPing      bool
+	pingInterval  time.Duration
 	logger        Logger
+
+	pingMu   sync.Mutex
+	pingedAt time.Time
 }
 
 // New creates a new Docker backend with the given configuration.
 func New(cfg Config) *Backend {
-	imageName := cfg.ImageName
-	if imageName == "" {
-		imageName = "toolruntime-sandbox:latest"
+	languages := cfg.Languages
+	if languages == nil {
+		languages = toolruntime.DefaultLanguages()
+	}
+
+	workspaceDir := cfg.WorkspaceDir
+	if workspaceDir == "" {
+		workspaceDir = "/workspace"
+	}
+
+	workspaceSize := cfg.WorkspaceSizeBytes
+	if workspaceSize <= 0 {
+		workspaceSize = 64 << 20
+	}
+
+	outputDir := cfg.OutputDir
+	if outputDir == "" {
+		outputDir = "/output"
+	}
+
+	pingInterval := cfg.HealthCheckInterval
+	if pingInterval <= 0 {
+		pingInterval = 30 * time.Second
 	}
 
 	return &Backend{
-		imageName:     imageName,
+		imageName:     cfg.ImageName,
+		languages:     languages,
 		seccompPath:   cfg.SeccompPath,
+		workspaceDir:  workspaceDir,
+		workspaceSize: workspaceSize,
+		outputDir:     outputDir,
 		client:        cfg.Client,
 		imageResolver: cfg.ImageResolver,
 		healthChecker: cfg.HealthChecker,
+		skipPing:      cfg.SkipExecuteHealthCheck,
+		pingInterval:  pingInterval,
 		logger:        cfg.Logger,
 	}
 }
@@ -163,102 +243,397 @@ func (b *Backend) Kind() toolruntime.BackendKind {
 	return toolruntime.BackendDocker
 }
 
+// Capabilities describes the Docker backend. Network and filesystem defaults
+// are those of the standard and hardened profiles; the dev profile enables
+// both.
+func (b *Backend) Capabilities() toolruntime.Capabilities {
+	_, streaming := b.client.(StreamRunner)
+	return toolruntime.Capabilities{
+		Languages: b.languages.Names(),
+		Limits: toolruntime.LimitsEnforced{
+			Timeout:    true,
+			Memory:     true,
+			CPU:        true,
+			Pids:       true,
+			Disk:       true, // Caps the workspace tmpfs
+			ToolCalls:  true, // Enforced by gateway
+			ChainSteps: true, // Enforced by gateway
+			Output:     true,
+		},
+		Isolation: toolruntime.BackendDocker.Isolation(),
+		Streaming: streaming,
+		Files:     true,
+		Artifacts: true,
+	}
+}
+
+// HealthCheck pings the Docker daemon through the configured HealthChecker.
+// It returns nil if no HealthChecker is configured. It implements
+// toolruntime.HealthProber.
+func (b *Backend) HealthCheck(ctx context.Context) error {
+	if b.healthChecker == nil {
+		return nil
+	}
+	_, span := toolruntime.StartSpan(ctx, "docker.health_check")
+	err := b.healthChecker.Ping(ctx)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return runtimeError(opHealthCheck, fmt.Errorf("%w: %w: %v", ErrDaemonUnavailable, toolruntime.ErrRuntimeUnavailable, err))
+	}
+	return nil
+}
+
+// ping runs the health check before an execution, unless it is disabled or
+// a ping succeeded within the health check interval.
+func (b *Backend) ping(ctx context.Context) error {
+	if b.skipPing || b.healthChecker == nil {
+		return nil
+	}
+	b.pingMu.Lock()
+	fresh := !b.pingedAt.IsZero() && time.Since(b.pingedAt) < b.pingInterval
+	b.pingMu.Unlock()
+	if fresh {
+		return nil
+	}
+
+	if err := b.HealthCheck(ctx); err != nil {
+		return err
+	}
+	b.pingMu.Lock()
+	b.pingedAt = time.Now()
+	b.pingMu.Unlock()
+	return nil
+}
+
+// Close closes the Client if it implements io.Closer. It implements
+// toolruntime.ClosableBackend.
+func (b *Backend) Close(_ context.Context) error {
+	if closer, ok := b.client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // Execute runs code in a Docker container with security isolation.
 func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, runtimeError(opValidate, err)
 	}
 
 	// Check client is configured
 	if b.client == nil {
-		return toolruntime.ExecuteResult{}, ErrClientNotConfigured
+		return toolruntime.ExecuteResult{}, errClientNotConfigured()
 	}
 
-	// Apply timeout
-	timeout := req.Timeout
-	if timeout == 0 {
-		timeout = 30 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout(req))
+	defer cancel()
+
+	start := time.Now()
+
+	capture := toolruntime.NewOutputCapture(req)
+	spec, profile, err := b.prepare(ctx, req, capture.Nonce())
+	if err != nil {
+		return toolruntime.ExecuteResult{}, err
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	// Execute via client
+	runCtx, span := toolruntime.StartSpan(ctx, "docker.container_run", toolruntime.Attr("docker.image", spec.Image))
+	containerResult, err := b.client.Run(runCtx, spec)
+	span.SetAttributes(toolruntime.Attr("docker.exit_code", containerResult.ExitCode))
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return toolruntime.ExecuteResult{
+			Duration: time.Since(start),
+			Backend:  b.backendInfo(profile, spec.Image),
+		}, runtimeError(opContainerRun, err)
+	}
+
+	_, _ = io.WriteString(capture.Stdout, containerResult.Stdout)
+	_, _ = io.WriteString(capture.Stderr, containerResult.Stderr)
+	return b.toResult(req, spec, profile, containerResult, capture)
+}
+
+// ExecuteStream runs code in a Docker container and streams its output.
+// If the configured client does not implement StreamRunner, the container is
+// run to completion and its output is emitted afterwards.
+func (b *Backend) ExecuteStream(ctx context.Context, req toolruntime.ExecuteRequest) (<-chan toolruntime.StreamEvent, error) {
+	if err := req.Validate(); err != nil {
+		return nil, runtimeError(opValidate, err)
+	}
+	if b.client == nil {
+		return nil, errClientNotConfigured()
+	}
+
+	streamer, ok := b.client.(StreamRunner)
+	if !ok {
+		result, err := b.Execute(ctx, req)
+		return toolruntime.StreamResult(result, err), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout(req))
+
+	start := time.Now()
+
+	capture := toolruntime.NewOutputCapture(req)
+	spec, profile, err := b.prepare(ctx, req, capture.Nonce())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	runCtx, span := toolruntime.StartSpan(ctx, "docker.container_run", toolruntime.Attr("docker.image", spec.Image))
+	events, err := streamer.RunStream(runCtx, spec)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		cancel()
+		return nil, runtimeError(opContainerRun, err)
+	}
+
+	out := make(chan toolruntime.StreamEvent, 16)
+	go func() {
+		defer cancel()
+		defer close(out)
+		defer span.End()
+
+		capture.WithStream(
+			toolruntime.StreamWriter(ctx, out, toolruntime.StreamEventStdout),
+			toolruntime.StreamWriter(ctx, out, toolruntime.StreamEventStderr),
+		)
+		send := func(ev toolruntime.StreamEvent) {
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+			}
+		}
+		partial := func() *toolruntime.ExecuteResult {
+			result := &toolruntime.ExecuteResult{
+				Duration: time.Since(start),
+				Backend:  b.backendInfo(profile, spec.Image),
+			}
+			_ = capture.Finish(result)
+			return result
+		}
+
+		for ev := range events {
+			switch ev.Type {
+			case StreamEventStdout:
+				_, _ = capture.Stdout.Write(ev.Data)
+			case StreamEventStderr:
+				_, _ = capture.Stderr.Write(ev.Data)
+			case StreamEventExit:
+				span.SetAttributes(toolruntime.Attr("docker.exit_code", ev.ExitCode))
+				result, err := b.toResult(req, spec, profile, ContainerResult{
+					ExitCode: ev.ExitCode,
+					Duration: time.Since(start),
+					Outputs:  ev.Outputs,
+				}, capture)
+				if err != nil {
+					span.RecordError(err)
+					send(toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: &result, Error: err})
+					return
+				}
+				send(toolruntime.StreamEvent{Type: toolruntime.StreamEventExit, Result: &result})
+				return
+			case StreamEventError:
+				err := runtimeError(opContainerRun, ev.Error)
+				span.RecordError(err)
+				send(toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: partial(), Error: err})
+				return
+			}
+		}
+
+		// The runner closed the stream without an exit event.
+		err := ctx.Err()
+		if err == nil {
+			err = runtimeError(opContainerRun, fmt.Errorf("%w: stream closed without exit event", ErrContainerFailed))
+		}
+		span.RecordError(err)
+		send(toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: partial(), Error: err})
+	}()
 
+	return out, nil
+}
+
+// prepare performs the pre-execution steps shared by Execute and ExecuteStream:
+// language resolution, health check, image resolution and spec construction.
+// nonce frames the result envelope.
+func (b *Backend) prepare(ctx context.Context, req toolruntime.ExecuteRequest, nonce string) (ContainerSpec, toolruntime.SecurityProfile, error) {
 	select {
 	case <-ctx.Done():
-		return toolruntime.ExecuteResult{}, ctx.Err()
+		return ContainerSpec{}, "", ctx.Err()
 	default:
 	}
 
-	start := time.Now()
-
 	// Get container options based on profile and limits
 	profile := req.Profile
 	if profile == "" {
 		profile = toolruntime.ProfileStandard
 	}
 
+	lang, err := b.languages.Resolve(req.Language)
+	if err != nil {
+		return ContainerSpec{}, profile, runtimeError(opValidate, err)
+	}
+
 	// Optional health check
-	if b.healthChecker != nil {
-		if err := b.healthChecker.Ping(ctx); err != nil {
-			return toolruntime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrDaemonUnavailable, err)
-		}
+	if err := b.ping(ctx); err != nil {
+		return ContainerSpec{}, profile, err
 	}
 
 	// Optional image resolution
-	image := b.imageName
+	image := b.image(req, lang)
 	if b.imageResolver != nil {
+		_, span := toolruntime.StartSpan(ctx, "docker.image_resolve", toolruntime.Attr("docker.image", image))
 		resolved, err := b.imageResolver.Resolve(ctx, image)
+		span.SetAttributes(toolruntime.Attr("docker.resolved_image", resolved))
+		span.RecordError(err)
+		span.End()
 		if err != nil {
-			return toolruntime.ExecuteResult{}, err
+			return ContainerSpec{}, profile, runtimeError(opImageResolve, err)
 		}
 		image = resolved
 	}
 
 	// Build container spec from request
-	spec, err := b.buildSpec(image, req, profile)
+	spec, err := b.buildSpec(image, lang, req, profile, nonce)
 	if err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return ContainerSpec{}, profile, runtimeError(opValidate, err)
 	}
 
 	// Log execution
 	if b.logger != nil {
 		b.logger.Info("executing in Docker container",
+			"executionID", req.ExecutionID,
 			"profile", profile,
 			"image", image,
+			"language", lang.Name,
 			"networkDisabled", spec.Security.NetworkMode == "none",
 			"readOnlyRootfs", spec.Security.ReadOnlyRootfs)
 	}
 
-	// Execute via client
-	containerResult, err := b.client.Run(ctx, spec)
-	if err != nil {
-		return toolruntime.ExecuteResult{
-			Duration: time.Since(start),
-			Backend:  b.backendInfo(profile),
-		}, err
-	}
+	return spec, profile, nil
+}
 
-	// Convert to ExecuteResult
-	return toolruntime.ExecuteResult{
-		Value:    extractOutValue(containerResult.Stdout),
-		Stdout:   containerResult.Stdout,
-		Stderr:   containerResult.Stderr,
-		Duration: containerResult.Duration,
-		Backend:  b.backendInfo(profile),
+// toResult converts a ContainerResult, whose output was written to capture,
+// to an ExecuteResult, collecting the copied-out outputs as artifacts if the
+// request asked for them. An error reported in the result envelope is
+// returned as a container_run error.
+func (b *Backend) toResult(req toolruntime.ExecuteRequest, spec ContainerSpec, profile toolruntime.SecurityProfile, containerResult ContainerResult, capture *toolruntime.OutputCapture) (toolruntime.ExecuteResult, error) {
+	result := toolruntime.ExecuteResult{
+		ExecutionID: req.ExecutionID,
+		Duration:    containerResult.Duration,
+		Backend:     b.backendInfo(profile, spec.Image),
 		LimitsEnforced: toolruntime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     req.Limits.MemoryBytes > 0,
 			CPU:        req.Limits.CPUQuotaMillis > 0,
 			Pids:       req.Limits.PidsMax > 0,
+			Disk:       req.Limits.DiskBytes > 0,
 			ToolCalls:  true, // Enforced by gateway
 			ChainSteps: true, // Enforced by gateway
 		},
-	}, nil
+	}
+	if err := capture.Finish(&result); err != nil {
+		return result, runtimeError(opCaptureOutput, err)
+	}
+	if err := capture.Err(); err != nil {
+		return result, runtimeError(opContainerRun, err)
+	}
+	if req.Artifacts == nil {
+		return result, nil
+	}
+
+	prefix := strings.TrimSuffix(b.outputDir, "/") + "/"
+	for _, f := range containerResult.Outputs {
+		name, ok := strings.CutPrefix(f.Path, prefix)
+		if !ok {
+			continue
+		}
+		result.Artifacts = append(result.Artifacts, toolruntime.NewArtifact(name, f.Content))
+	}
+	slices.SortFunc(result.Artifacts, func(a, b toolruntime.Artifact) int { return strings.Compare(a.Path, b.Path) })
+	if err := toolruntime.CheckArtifacts(result.Artifacts, *req.Artifacts); err != nil {
+		result.Artifacts = nil
+		return result, runtimeError(opCollectArtifacts, err)
+	}
+	return result, nil
+}
+
+// errClientNotConfigured reports a missing client as an unavailable runtime
+// so that the runtime can fall back to another backend.
+func errClientNotConfigured() error {
+	return runtimeError(opConfigure, fmt.Errorf("%w: %w", ErrClientNotConfigured, toolruntime.ErrRuntimeUnavailable))
 }
 
-// buildSpec creates a ContainerSpec from an ExecuteRequest.
-func (b *Backend) buildSpec(image string, req toolruntime.ExecuteRequest, profile toolruntime.SecurityProfile) (ContainerSpec, error) {
+// Operations reported in toolruntime.RuntimeError.Op.
+const (
+	opConfigure        = "configure"
+	opValidate         = "validate"
+	opHealthCheck      = "health_check"
+	opImageResolve     = "image_resolve"
+	opContainerRun     = "container_run"
+	opCollectArtifacts = "collect_artifacts"
+	opCaptureOutput    = "capture_output"
+)
+
+// runtimeError wraps err in a toolruntime.RuntimeError for op. Errors
+// reporting that Docker is unreachable are marked as an unavailable runtime.
+func runtimeError(op string, err error) error {
+	if (errors.Is(err, ErrDaemonUnavailable) || errors.Is(err, ErrDockerNotAvailable)) && !errors.Is(err, toolruntime.ErrRuntimeUnavailable) {
+		err = fmt.Errorf("%w: %w", err, toolruntime.ErrRuntimeUnavailable)
+	}
+	return toolruntime.NewRuntimeError(toolruntime.BackendDocker, op, err, retryable(op, err))
+}
+
+// retryable reports whether err is a transient failure that happened before
+// user code started, so the execution can be retried safely.
+func retryable(op string, err error) bool {
+	if errors.Is(err, ErrSecurityViolation) || errors.Is(err, ErrResourceLimit) || errors.Is(err, ErrImageNotFound) {
+		return false
+	}
+	switch op {
+	case opHealthCheck, opImageResolve:
+		return true
+	case opContainerRun:
+		var ce *ClientError
+		if errors.As(err, &ce) {
+			return ce.Op == "create" || ce.Op == "start" || ce.Op == "pull"
+		}
+		return errors.Is(err, ErrDaemonUnavailable) || errors.Is(err, ErrDockerNotAvailable) ||
+			errors.Is(err, ErrImagePull) || errors.Is(err, ErrContainerCreate) || errors.Is(err, ErrContainerStart)
+	}
+	return false
+}
+
+// executionTimeout returns the request timeout or the default.
+func executionTimeout(req toolruntime.ExecuteRequest) time.Duration {
+	if req.Timeout == 0 {
+		return 30 * time.Second
+	}
+	return req.Timeout
+}
+
+// image returns the image that runs req in lang.
+func (b *Backend) image(req toolruntime.ExecuteRequest, lang toolruntime.Language) string {
+	switch {
+	case b.imageName != "":
+		return b.imageName
+	case req.Language != "" && lang.Image != "":
+		return lang.Image
+	}
+	return defaultImage
+}
+
+// defaultImage is the sandbox image used when neither the config nor the
+// request's language names one.
+const defaultImage = "toolruntime-sandbox:latest"
+
+// buildSpec creates a ContainerSpec that runs an ExecuteRequest in lang.
+func (b *Backend) buildSpec(image string, lang toolruntime.Language, req toolruntime.ExecuteRequest, profile toolruntime.SecurityProfile, nonce string) (ContainerSpec, error) {
 	opts := b.containerOptions(profile, req.Limits)
 
 	builder := NewSpecBuilder(image).
@@ -274,8 +649,61 @@ func (b *Backend) buildSpec(image string, req toolruntime.ExecuteRequest, profil
 			CPUQuota:    opts.CPUQuota,
 			PidsLimit:   opts.PidsLimit,
 		}).
+		WithCommand(lang.Command...).
 		WithLabel("toolruntime.profile", string(profile)).
-		WithLabel("toolruntime.backend", string(toolruntime.BackendDocker))
+		WithLabel("toolruntime.backend", string(toolruntime.BackendDocker)).
+		WithLabel("toolruntime.language", lang.Name)
+	if req.ExecutionID != "" {
+		builder = builder.WithLabel("toolruntime.execution_id", req.ExecutionID)
+	}
+
+	// Pass the environment, stdin, the JSON-encoded inputs and the nonce
+	// of the result envelope. The user has no home and the root
+	// filesystem may be read-only, so HOME and the Go toolchain's paths
+	// point into the workspace; the request environment can override them
+	inputs, err := toolruntime.EncodeInputs(req.Inputs)
+	if err != nil {
+		return ContainerSpec{}, err
+	}
+	builder = builder.WithEnv("HOME", b.workspaceDir).
+		WithEnv("GOPATH", path.Join(b.workspaceDir, ".go")).
+		WithEnv("GOCACHE", path.Join(b.workspaceDir, ".cache", "go-build")).
+		WithEnvs(toolruntime.EnvList(req.Env, req.Secrets)).
+		WithStdin(req.Stdin).
+		WithEnv(toolruntime.InputsEnv, string(inputs)).
+		WithEnv(toolruntime.ResultNonceEnv, nonce)
+
+	// Mount a tmpfs for artifacts, capped at their total size
+	if req.Artifacts != nil {
+		builder = builder.WithTmpfsSize(b.outputDir, req.Artifacts.TotalLimit()).
+			WithOutputDir(b.outputDir).
+			WithEnv(toolruntime.OutputDirEnv, b.outputDir)
+	}
+
+	// Mount a size-capped tmpfs workspace holding the request files, the
+	// language's support files and the wrapped code
+	if _, ok := req.Files[lang.SourceFile()]; ok {
+		return ContainerSpec{}, fmt.Errorf("%w: %s is reserved for the code", toolruntime.ErrInvalidFile, lang.SourceFile())
+	}
+	size := b.workspaceSize
+	if req.Limits.DiskBytes > 0 {
+		size = req.Limits.DiskBytes
+	}
+	builder = builder.WithTmpfsSize(b.workspaceDir, size).WithWorkingDir(b.workspaceDir)
+	for _, name := range slices.Sorted(maps.Keys(req.Files)) {
+		f := req.Files[name]
+		builder = builder.WithFile(File{Path: path.Join(b.workspaceDir, name), Content: f.Content, Mode: f.Perm()})
+	}
+	for _, name := range slices.Sorted(maps.Keys(lang.Files)) {
+		if _, ok := req.Files[name]; !ok {
+			builder = builder.WithFile(File{Path: path.Join(b.workspaceDir, name), Content: []byte(lang.Files[name]), Mode: toolruntime.DefaultFileMode})
+		}
+	}
+	builder = builder.WithFile(File{
+		Path:    path.Join(b.workspaceDir, lang.SourceFile()),
+		Content: []byte(lang.Wrap(req.Code)),
+		Mode:    toolruntime.DefaultFileMode,
+	})
 
 	return builder.Build()
 }
@@ -288,25 +716,17 @@ func (b *Backend) networkMode(opts ContainerOptions) string {
 	return "bridge"
 }
 
-// backendInfo returns BackendInfo for the given profile.
-func (b *Backend) backendInfo(profile toolruntime.SecurityProfile) toolruntime.BackendInfo {
+// backendInfo returns BackendInfo for the given profile and image.
+func (b *Backend) backendInfo(profile toolruntime.SecurityProfile, image string) toolruntime.BackendInfo {
 	return toolruntime.BackendInfo{
 		Kind: toolruntime.BackendDocker,
 		Details: map[string]any{
-			"image":   b.imageName,
+			"image":   image,
 			"profile": string(profile),
 		},
 	}
 }
 
-// extractOutValue extracts the __out value from stdout if present.
-// This follows the toolruntime convention for capturing return values.
-func extractOutValue(_ string) any {
-	// TODO: Implement __out extraction from stdout
-	// The gateway proxy will output JSON with __out key
-	return nil
-}
-
 // containerOptions returns ContainerOptions based on the security profile and limits.
 func (b *Backend) containerOptions(profile toolruntime.SecurityProfile, limits toolruntime.Limits) ContainerOptions {
 	opts := ContainerOptions{
@@ -347,3 +767,10 @@ func (b *Backend) containerOptions(profile toolruntime.SecurityProfile, limits t
 
 	return opts
 }
+
+var (
+	_ toolruntime.StreamingBackend   = (*Backend)(nil)
+	_ toolruntime.CapabilityReporter = (*Backend)(nil)
+	_ toolruntime.HealthProber       = (*Backend)(nil)
+	_ toolruntime.ClosableBackend    = (*Backend)(nil)
+)
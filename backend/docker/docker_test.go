@@ -3,6 +3,11 @@ package docker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/jonwraymond/tooldocs"
@@ -38,6 +43,9 @@ func (m *mockGateway) RunChain(_ context.Context, _ []toolrun.ChainStep) (toolru
 	return toolrun.RunResult{}, nil, nil
 }
 
+// python is the language passed to buildSpec in tests.
+var python, _ = toolruntime.DefaultLanguages().Lookup("python")
+
 // TestBackendImplementsInterface verifies Backend satisfies toolruntime.Backend
 func TestBackendImplementsInterface(t *testing.T) {
 	t.Helper()
@@ -52,6 +60,62 @@ func TestBackendKind(t *testing.T) {
 	}
 }
 
+func TestBackendCapabilities(t *testing.T) {
+	caps := New(Config{}).Capabilities()
+
+	if caps.Isolation != toolruntime.IsolationContainer {
+		t.Errorf("Isolation = %v, want %v", caps.Isolation, toolruntime.IsolationContainer)
+	}
+	if !caps.Limits.Memory || !caps.Limits.CPU || !caps.Limits.Pids || !caps.Limits.Disk {
+		t.Errorf("Limits = %+v, want memory, cpu, pids and disk", caps.Limits)
+	}
+	if caps.Streaming {
+		t.Error("Streaming = true without a StreamRunner")
+	}
+	if !caps.Files {
+		t.Error("Files = false, want true")
+	}
+	if !New(Config{Client: &MockStreamRunner{}}).Capabilities().Streaming {
+		t.Error("Streaming = false with a StreamRunner")
+	}
+	if !slices.Equal(caps.Languages, []string{"go", "javascript", "python", "shell"}) {
+		t.Errorf("Languages = %v, want the default languages", caps.Languages)
+	}
+}
+
+func TestBackendStrictDiskLimit(t *testing.T) {
+	var spec ContainerSpec
+	runner := &MockContainerRunner{
+		RunFunc: func(_ context.Context, s ContainerSpec) (ContainerResult, error) {
+			spec = s
+			return ContainerResult{}, nil
+		},
+	}
+	rt := toolruntime.NewDefaultRuntime(toolruntime.RuntimeConfig{
+		Backends:       map[toolruntime.SecurityProfile]toolruntime.Backend{toolruntime.ProfileStandard: New(Config{Client: runner})},
+		DefaultProfile: toolruntime.ProfileStandard,
+	})
+
+	// Strict requests with a disk limit run on docker, in a workspace
+	// capped at the limit
+	req := toolruntime.ExecuteRequest{
+		Code:         "echo hi",
+		Gateway:      &mockGateway{},
+		Limits:       toolruntime.Limits{DiskBytes: 1 << 20},
+		StrictLimits: true,
+	}
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.LimitsEnforced.Disk {
+		t.Errorf("LimitsEnforced = %+v, want disk", result.LimitsEnforced)
+	}
+	if !slices.ContainsFunc(spec.Mounts, func(m Mount) bool { return m.Target == "/workspace" && m.SizeBytes == 1<<20 }) {
+		t.Errorf("Mounts = %+v, want a 1 MiB workspace tmpfs", spec.Mounts)
+	}
+}
+
 func TestBackendRequiresGateway(t *testing.T) {
 	b := New(Config{})
 
@@ -249,7 +313,100 @@ func TestBackendWithHealthChecker(t *testing.T) {
 		if !errors.Is(err, ErrDaemonUnavailable) {
 			t.Errorf("Execute() error = %v, want %v", err, ErrDaemonUnavailable)
 		}
+		if !errors.Is(err, toolruntime.ErrRuntimeUnavailable) {
+			t.Errorf("Execute() error = %v, want %v", err, toolruntime.ErrRuntimeUnavailable)
+		}
 	})
+
+	t.Run("cached ping", func(t *testing.T) {
+		var pings int
+		mockHealth := &MockHealthChecker{
+			PingFunc: func(_ context.Context) error {
+				pings++
+				return nil
+			},
+		}
+
+		b := New(Config{
+			Client:        &MockContainerRunner{},
+			HealthChecker: mockHealth,
+		})
+
+		req := toolruntime.ExecuteRequest{
+			Code:    "print('hello')",
+			Gateway: &mockGateway{},
+		}
+		for range 3 {
+			if _, err := b.Execute(context.Background(), req); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+		}
+		if pings != 1 {
+			t.Errorf("pings = %d, want 1 within the health check interval", pings)
+		}
+	})
+}
+
+func TestBackendHealthCheck(t *testing.T) {
+	pings := 0
+	mockHealth := &MockHealthChecker{
+		PingFunc: func(_ context.Context) error {
+			pings++
+			return errors.New("connection refused")
+		},
+	}
+	b := New(Config{
+		Client: &MockContainerRunner{
+			RunFunc: func(_ context.Context, _ ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{ExitCode: 0}, nil
+			},
+		},
+		HealthChecker:          mockHealth,
+		SkipExecuteHealthCheck: true,
+	})
+
+	err := b.HealthCheck(context.Background())
+	if !errors.Is(err, ErrDaemonUnavailable) || !toolruntime.IsRetryable(err) {
+		t.Errorf("HealthCheck() error = %v, want retryable %v", err, ErrDaemonUnavailable)
+	}
+
+	_, err = b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "print('hello')", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if pings != 1 {
+		t.Errorf("pings = %d, want 1 (Execute should skip the health check)", pings)
+	}
+
+	if err := New(Config{}).HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck() without HealthChecker error = %v, want nil", err)
+	}
+}
+
+// closingRunner is a ContainerRunner that records Close calls
+type closingRunner struct {
+	MockContainerRunner
+	closed int
+}
+
+func (c *closingRunner) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestBackendClose(t *testing.T) {
+	client := &closingRunner{}
+	b := New(Config{Client: client})
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if client.closed != 1 {
+		t.Errorf("client closed %d times, want 1", client.closed)
+	}
+
+	if err := New(Config{Client: &MockContainerRunner{}}).Close(context.Background()); err != nil {
+		t.Errorf("Close() with non-closer client error = %v", err)
+	}
 }
 
 func TestBackendWithImageResolver(t *testing.T) {
@@ -301,9 +458,10 @@ func TestBackendBuildSpec(t *testing.T) {
 			CPUQuotaMillis: 1000,
 			PidsMax:        100,
 		},
+		ExecutionID: "exec-123",
 	}
 
-	spec, err := b.buildSpec("test-image:latest", req, toolruntime.ProfileHardened)
+	spec, err := b.buildSpec("test-image:latest", python, req, toolruntime.ProfileHardened, "nonce")
 	if err != nil {
 		t.Fatalf("buildSpec() error = %v", err)
 	}
@@ -342,6 +500,461 @@ func TestBackendBuildSpec(t *testing.T) {
 	if spec.Labels["toolruntime.backend"] != "docker" {
 		t.Errorf("Labels[toolruntime.backend] = %q, want %q", spec.Labels["toolruntime.backend"], "docker")
 	}
+	if spec.Labels["toolruntime.execution_id"] != "exec-123" {
+		t.Errorf("Labels[toolruntime.execution_id] = %q, want %q", spec.Labels["toolruntime.execution_id"], "exec-123")
+	}
+}
+
+func TestBackendBuildSpecFiles(t *testing.T) {
+	b := New(Config{})
+	req := toolruntime.ExecuteRequest{
+		Code:    "import helper",
+		Gateway: &mockGateway{},
+		Files: map[string]toolruntime.File{
+			"helper.py":    {Content: []byte("x = 1")},
+			"data/in.json": {Content: []byte("{}"), Mode: 0o600},
+		},
+	}
+
+	spec, err := b.buildSpec("test-image", python, req, toolruntime.ProfileStandard, "nonce")
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.WorkingDir != "/workspace" {
+		t.Errorf("WorkingDir = %q, want /workspace", spec.WorkingDir)
+	}
+	want := []Mount{{Type: MountTypeTmpfs, Target: "/workspace", SizeBytes: 64 << 20}}
+	if !reflect.DeepEqual(spec.Mounts, want) {
+		t.Errorf("Mounts = %+v, want %+v", spec.Mounts, want)
+	}
+	wantFiles := []File{
+		{Path: "/workspace/data/in.json", Content: []byte("{}"), Mode: 0o600},
+		{Path: "/workspace/helper.py", Content: []byte("x = 1"), Mode: toolruntime.DefaultFileMode},
+		{Path: "/workspace/main.py", Content: []byte(python.Wrap("import helper")), Mode: toolruntime.DefaultFileMode},
+	}
+	if !reflect.DeepEqual(spec.Files, wantFiles) {
+		t.Errorf("Files = %+v, want %+v", spec.Files, wantFiles)
+	}
+
+	// Limits.DiskBytes caps the workspace
+	req.Limits.DiskBytes = 4
+	_, err = b.buildSpec("test-image", python, req, toolruntime.ProfileStandard, "nonce")
+	if !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("buildSpec() with small disk limit error = %v, want ErrResourceLimit", err)
+	}
+
+	// Requests without files still get a workspace holding the program
+	req.Files = nil
+	req.Limits.DiskBytes = 0
+	spec, err = b.buildSpec("test-image", python, req, toolruntime.ProfileStandard, "nonce")
+	if err != nil || len(spec.Files) != 1 || spec.WorkingDir != "/workspace" {
+		t.Errorf("buildSpec() without files = %+v, %v; want workspace with the program", spec, err)
+	}
+
+	// The program's file name is reserved
+	req.Files = map[string]toolruntime.File{"main.py": {Content: []byte("x = 1")}}
+	_, err = b.buildSpec("test-image", python, req, toolruntime.ProfileStandard, "nonce")
+	if !errors.Is(err, toolruntime.ErrInvalidFile) {
+		t.Errorf("buildSpec() with main.py error = %v, want ErrInvalidFile", err)
+	}
+}
+
+func TestBackendArtifacts(t *testing.T) {
+	var spec ContainerSpec
+	runner := &MockContainerRunner{
+		RunFunc: func(_ context.Context, s ContainerSpec) (ContainerResult, error) {
+			spec = s
+			return ContainerResult{Outputs: []File{
+				{Path: "/output/report.csv", Content: []byte("a,b")},
+				{Path: "/output/charts/plot.png", Content: []byte("\x89PNG\r\n\x1a\n")},
+			}}, nil
+		},
+	}
+	b := New(Config{Client: runner})
+	req := toolruntime.ExecuteRequest{
+		Code:      "export()",
+		Gateway:   &mockGateway{},
+		Artifacts: &toolruntime.ArtifactSpec{MaxTotalBytes: 8},
+	}
+
+	_, err := b.Execute(context.Background(), req)
+	if !errors.Is(err, toolruntime.ErrResourceLimit) {
+		t.Fatalf("Execute() over total cap error = %v, want ErrResourceLimit", err)
+	}
+	if spec.OutputDir != "/output" || !slices.Contains(spec.Env, toolruntime.OutputDirEnv+"=/output") {
+		t.Errorf("spec = %+v, want /output as output dir and env", spec)
+	}
+	want := Mount{Type: MountTypeTmpfs, Target: "/output", SizeBytes: 8}
+	if !slices.Contains(spec.Mounts, want) {
+		t.Errorf("Mounts = %+v, want %+v", spec.Mounts, want)
+	}
+
+	req.Artifacts.MaxTotalBytes = 1024
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(result.Artifacts) != 2 {
+		t.Fatalf("Artifacts = %+v, want 2", result.Artifacts)
+	}
+	if a := result.Artifacts[0]; a.Path != "charts/plot.png" || a.MIMEType != "image/png" {
+		t.Errorf("Artifacts[0] = %+v, want charts/plot.png as image/png", a)
+	}
+	if a := result.Artifacts[1]; a.Path != "report.csv" || string(a.Content) != "a,b" || a.SHA256 == "" {
+		t.Errorf("Artifacts[1] = %+v, want report.csv with hash", a)
+	}
+
+	// Outputs are ignored unless requested
+	req.Artifacts = nil
+	if result, _ := b.Execute(context.Background(), req); len(result.Artifacts) != 0 || spec.OutputDir != "" {
+		t.Errorf("Artifacts = %+v, OutputDir = %q; want none", result.Artifacts, spec.OutputDir)
+	}
+}
+
+func TestBackendOutputLimits(t *testing.T) {
+	runner := &MockContainerRunner{
+		RunFunc: func(_ context.Context, _ ContainerSpec) (ContainerResult, error) {
+			return ContainerResult{Stdout: strings.Repeat("x", 100), Stderr: "warn"}, nil
+		},
+	}
+	b := New(Config{Client: runner})
+	req := toolruntime.ExecuteRequest{
+		Code:    "spam()",
+		Gateway: &mockGateway{},
+		Limits:  toolruntime.Limits{MaxStdoutBytes: 10, MaxStderrBytes: 10},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != strings.Repeat("x", 10)+"\n[truncated: 90 bytes omitted]\n" || result.Stderr != "warn" {
+		t.Errorf("Stdout = %q, Stderr = %q", result.Stdout, result.Stderr)
+	}
+	if !result.LimitsEnforced.Output || !b.Capabilities().Limits.Output {
+		t.Error("output limits should be reported as enforced")
+	}
+}
+
+// envelopeStdout returns stdout followed by a result envelope framed with
+// the nonce passed in spec.
+func envelopeStdout(t *testing.T, spec ContainerSpec, stdout string, env toolruntime.ResultEnvelope) string {
+	t.Helper()
+	var nonce string
+	for _, e := range spec.Env {
+		if v, ok := strings.CutPrefix(e, toolruntime.ResultNonceEnv+"="); ok {
+			nonce = v
+		}
+	}
+	line, err := toolruntime.EncodeResultEnvelope(nonce, env)
+	if err != nil {
+		t.Fatalf("EncodeResultEnvelope() error = %v", err)
+	}
+	return stdout + string(line)
+}
+
+func TestBackendResultEnvelope(t *testing.T) {
+	env := toolruntime.ResultEnvelope{Value: 42.0, Metadata: map[string]any{"rows": 3.0}}
+	runner := &MockContainerRunner{
+		RunFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			spoof := "\n" + toolruntime.ResultMarker("guess") + `{"value":"spoofed"}` + "\n"
+			return ContainerResult{Stdout: envelopeStdout(t, spec, "hello"+spoof, env)}, nil
+		},
+	}
+	b := New(Config{Client: runner})
+	req := toolruntime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != 42.0 || result.Metadata["rows"] != 3.0 {
+		t.Errorf("Value = %v, Metadata = %v; want values from the envelope", result.Value, result.Metadata)
+	}
+	if !strings.HasPrefix(result.Stdout, "hello\n__TOOLRUNTIME_RESULT_guess:") || strings.Contains(result.Stdout, "42") {
+		t.Errorf("Stdout = %q, want the envelope stripped and the spoofed line kept", result.Stdout)
+	}
+
+	env = toolruntime.ResultEnvelope{Error: &toolruntime.ResultError{Type: "ValueError", Message: "bad row"}}
+	result, err = b.Execute(context.Background(), req)
+	var resultErr *toolruntime.ResultError
+	if !errors.Is(err, toolruntime.ErrCodeFailed) || !errors.As(err, &resultErr) || resultErr.Type != "ValueError" {
+		t.Errorf("Execute() error = %v, want ResultError", err)
+	}
+	if result.Stdout != "hello"+"\n"+toolruntime.ResultMarker("guess")+`{"value":"spoofed"}`+"\n" {
+		t.Errorf("Stdout = %q, want the envelope stripped", result.Stdout)
+	}
+}
+
+func TestBackendExecuteStreamResultEnvelope(t *testing.T) {
+	runner := &MockStreamRunner{
+		RunStreamFunc: func(_ context.Context, spec ContainerSpec) (<-chan StreamEvent, error) {
+			stdout := envelopeStdout(t, spec, "hi", toolruntime.ResultEnvelope{Value: "done"})
+			ch := make(chan StreamEvent, 4)
+			ch <- StreamEvent{Type: StreamEventStdout, Data: []byte(stdout[:5])}
+			ch <- StreamEvent{Type: StreamEventStdout, Data: []byte(stdout[5:])}
+			ch <- StreamEvent{Type: StreamEventExit}
+			close(ch)
+			return ch, nil
+		},
+	}
+	b := New(Config{Client: runner})
+
+	events, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var stdout string
+	var last toolruntime.StreamEvent
+	for ev := range events {
+		if ev.Type == toolruntime.StreamEventStdout {
+			stdout += string(ev.Data)
+		}
+		last = ev
+	}
+	if stdout != "hi" {
+		t.Errorf("streamed stdout = %q, want the envelope stripped", stdout)
+	}
+	if last.Type != toolruntime.StreamEventExit || last.Result.Value != "done" || last.Result.Stdout != "hi" {
+		t.Errorf("last event = %+v, want exit with the envelope value", last)
+	}
+}
+
+func TestBackendStdinInputsAndEnv(t *testing.T) {
+	b := New(Config{Client: &MockContainerRunner{}})
+	req := toolruntime.ExecuteRequest{
+		Code:    "print(input())",
+		Gateway: &mockGateway{},
+		Stdin:   []byte("line\n"),
+		Inputs:  map[string]any{"n": 1},
+		Env:     map[string]string{"MODE": "fast"},
+		Secrets: map[string]string{"API_KEY": "s3cret"},
+	}
+
+	spec, err := b.buildSpec("python:3.12-slim", python, req, toolruntime.ProfileStandard, "nonce")
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if string(spec.Stdin) != "line\n" {
+		t.Errorf("Stdin = %q, want %q", spec.Stdin, "line\n")
+	}
+	if !slices.Contains(spec.Env, toolruntime.InputsEnv+`={"n":1}`) {
+		t.Errorf("Env = %v, want encoded inputs", spec.Env)
+	}
+	if !slices.Contains(spec.Env, "MODE=fast") || !slices.Contains(spec.Env, "API_KEY=s3cret") {
+		t.Errorf("Env = %v, want request env and secrets", spec.Env)
+	}
+}
+
+func TestBackendLanguages(t *testing.T) {
+	var spec ContainerSpec
+	runner := &MockContainerRunner{
+		RunFunc: func(_ context.Context, s ContainerSpec) (ContainerResult, error) {
+			spec = s
+			return ContainerResult{}, nil
+		},
+	}
+
+	tests := []struct {
+		name      string
+		imageName string
+		language  string
+		wantImage string
+		wantCmd   []string
+		wantFile  string
+	}{
+		{"named language", "", "py", "python:3.12-slim", []string{"python3", "main.py"}, "/workspace/main.py"},
+		{"configured image", "custom:1", "javascript", "custom:1", []string{"node", "main.js"}, "/workspace/main.js"},
+		{"default language", "", "", "toolruntime-sandbox:latest", []string{"go", "run", "."}, "/workspace/main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(Config{Client: runner, ImageName: tt.imageName})
+			req := toolruntime.ExecuteRequest{Code: "echo hi", Language: tt.language, Gateway: &mockGateway{}}
+			result, err := b.Execute(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if spec.Image != tt.wantImage || result.Backend.Details["image"] != tt.wantImage {
+				t.Errorf("image = %q (reported %v), want %q", spec.Image, result.Backend.Details["image"], tt.wantImage)
+			}
+			if !slices.Equal(spec.Command, tt.wantCmd) {
+				t.Errorf("Command = %v, want %v", spec.Command, tt.wantCmd)
+			}
+			if len(spec.Files) == 0 || spec.Files[len(spec.Files)-1].Path != tt.wantFile {
+				t.Errorf("Files = %+v, want program at %s", spec.Files, tt.wantFile)
+			}
+		})
+	}
+
+	// Go programs get a go.mod unless the request provides one
+	b := New(Config{Client: runner})
+	req := toolruntime.ExecuteRequest{Code: `fmt.Println("hi")`, Language: "go", Gateway: &mockGateway{}}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !slices.ContainsFunc(spec.Files, func(f File) bool { return f.Path == "/workspace/go.mod" }) {
+		t.Errorf("Files = %+v, want go.mod", spec.Files)
+	}
+
+	// The toolchain writes its caches to the workspace, as the user has no
+	// home and the root filesystem is read-only
+	for _, want := range []string{"HOME=/workspace", "GOPATH=/workspace/.go", "GOCACHE=/workspace/.cache/go-build"} {
+		if !slices.Contains(spec.Env, want) {
+			t.Errorf("Env = %v, want %s", spec.Env, want)
+		}
+	}
+
+	// Requests without a language run as Go, even when strings in the code
+	// look like another language
+	for _, code := range []string{`__out = "let me know"`, `__out = "None"`, `var s = "echo hi"`} {
+		req := toolruntime.ExecuteRequest{Code: code, Gateway: &mockGateway{}}
+		if _, err := b.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute(%q) error = %v", code, err)
+		}
+		if spec.Labels["toolruntime.language"] != "go" {
+			t.Errorf("Execute(%q) language = %q, want go", code, spec.Labels["toolruntime.language"])
+		}
+	}
+
+	// Unknown languages are rejected before a container is started
+	spec = ContainerSpec{}
+	req.Language = "cobol"
+	_, err := b.Execute(context.Background(), req)
+	if !errors.Is(err, toolruntime.ErrInvalidRequest) || spec.Image != "" {
+		t.Errorf("Execute() with unknown language error = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestBackendExecuteStream(t *testing.T) {
+	t.Run("streams events from StreamRunner", func(t *testing.T) {
+		runner := &MockStreamRunner{
+			RunStreamFunc: func(_ context.Context, _ ContainerSpec) (<-chan StreamEvent, error) {
+				ch := make(chan StreamEvent, 3)
+				ch <- StreamEvent{Type: StreamEventStdout, Data: []byte("hello ")}
+				ch <- StreamEvent{Type: StreamEventStdout, Data: []byte("world")}
+				ch <- StreamEvent{Type: StreamEventExit, ExitCode: 0}
+				close(ch)
+				return ch, nil
+			},
+		}
+		b := New(Config{Client: runner})
+
+		events, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{
+			Code:    "print('hello')",
+			Gateway: &mockGateway{},
+		})
+		if err != nil {
+			t.Fatalf("ExecuteStream() error = %v", err)
+		}
+
+		var got []toolruntime.StreamEvent
+		for ev := range events {
+			got = append(got, ev)
+		}
+		if len(got) != 3 {
+			t.Fatalf("got %d events, want 3", len(got))
+		}
+		last := got[2]
+		if last.Type != toolruntime.StreamEventExit {
+			t.Fatalf("last event Type = %v, want %v", last.Type, toolruntime.StreamEventExit)
+		}
+		if last.Result.Stdout != "hello world" {
+			t.Errorf("Result.Stdout = %q, want %q", last.Result.Stdout, "hello world")
+		}
+		if last.Result.Backend.Kind != toolruntime.BackendDocker {
+			t.Errorf("Result.Backend.Kind = %v, want %v", last.Result.Backend.Kind, toolruntime.BackendDocker)
+		}
+	})
+
+	t.Run("buffers non-streaming client", func(t *testing.T) {
+		runner := &MockContainerRunner{
+			RunFunc: func(_ context.Context, _ ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{Stdout: "done"}, nil
+			},
+		}
+		b := New(Config{Client: runner})
+
+		events, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{
+			Code:    "print('hello')",
+			Gateway: &mockGateway{},
+		})
+		if err != nil {
+			t.Fatalf("ExecuteStream() error = %v", err)
+		}
+
+		var got []toolruntime.StreamEvent
+		for ev := range events {
+			got = append(got, ev)
+		}
+		if len(got) != 2 || string(got[0].Data) != "done" || got[1].Type != toolruntime.StreamEventExit {
+			t.Errorf("events = %+v, want stdout then exit", got)
+		}
+	})
+
+	t.Run("stream error", func(t *testing.T) {
+		streamErr := errors.New("container died")
+		runner := &MockStreamRunner{
+			RunStreamFunc: func(_ context.Context, _ ContainerSpec) (<-chan StreamEvent, error) {
+				ch := make(chan StreamEvent, 1)
+				ch <- StreamEvent{Type: StreamEventError, Error: streamErr}
+				close(ch)
+				return ch, nil
+			},
+		}
+		b := New(Config{Client: runner})
+
+		events, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{
+			Code:    "print('hello')",
+			Gateway: &mockGateway{},
+		})
+		if err != nil {
+			t.Fatalf("ExecuteStream() error = %v", err)
+		}
+
+		var last toolruntime.StreamEvent
+		for ev := range events {
+			last = ev
+		}
+		if last.Type != toolruntime.StreamEventError || !errors.Is(last.Error, streamErr) {
+			t.Errorf("last event = %+v, want error wrapping %v", last, streamErr)
+		}
+	})
+}
+
+// spanRecorder records the names of started spans
+type spanRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, _ ...toolruntime.Attribute) (context.Context, toolruntime.Span) {
+	r.mu.Lock()
+	r.names = append(r.names, name)
+	r.mu.Unlock()
+	_, span := toolruntime.StartSpan(context.Background(), name)
+	return ctx, span
+}
+
+func TestBackendTracing(t *testing.T) {
+	recorder := &spanRecorder{}
+	b := New(Config{
+		Client:        &MockContainerRunner{},
+		HealthChecker: &MockHealthChecker{},
+		ImageResolver: &MockImageResolver{},
+	})
+
+	ctx := toolruntime.ContextWithTracer(context.Background(), recorder)
+	_, err := b.Execute(ctx, toolruntime.ExecuteRequest{Code: "print('hello')", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []string{"docker.health_check", "docker.image_resolve", "docker.container_run"}
+	if !reflect.DeepEqual(recorder.names, want) {
+		t.Errorf("spans = %v, want %v", recorder.names, want)
+	}
 }
 
 func TestClientError(t *testing.T) {
@@ -382,3 +995,132 @@ func TestClientError(t *testing.T) {
 		}
 	})
 }
+
+func TestBackendRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             Config
+		req             toolruntime.ExecuteRequest
+		wantOp          string
+		wantRetryable   bool
+		wantUnavailable bool
+	}{
+		{
+			name:   "invalid request",
+			cfg:    Config{Client: &MockContainerRunner{}},
+			req:    toolruntime.ExecuteRequest{Code: "x"},
+			wantOp: "validate",
+		},
+		{
+			name: "daemon unavailable",
+			cfg: Config{
+				Client:        &MockContainerRunner{},
+				HealthChecker: &MockHealthChecker{PingFunc: func(context.Context) error { return errors.New("refused") }},
+			},
+			wantOp:          "health_check",
+			wantRetryable:   true,
+			wantUnavailable: true,
+		},
+		{
+			name: "image pull",
+			cfg: Config{
+				Client: &MockContainerRunner{},
+				ImageResolver: &MockImageResolver{ResolveFunc: func(context.Context, string) (string, error) {
+					return "", ErrImagePull
+				}},
+			},
+			wantOp:        "image_resolve",
+			wantRetryable: true,
+		},
+		{
+			name: "container create",
+			cfg: Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{}, &ClientError{Op: "create", Err: errors.New("no space")}
+			}}},
+			wantOp:        "container_run",
+			wantRetryable: true,
+		},
+		{
+			name: "container wait",
+			cfg: Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{}, &ClientError{Op: "wait", Err: errors.New("lost")}
+			}}},
+			wantOp: "container_run",
+		},
+		{
+			name: "sandbox violation",
+			cfg: Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{}, &ClientError{Op: "create", Err: ErrSecurityViolation}
+			}}},
+			wantOp: "container_run",
+		},
+		{
+			name: "daemon unavailable on run",
+			cfg: Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{}, &ClientError{Op: "create", Err: ErrDaemonUnavailable}
+			}}},
+			wantOp:          "container_run",
+			wantRetryable:   true,
+			wantUnavailable: true,
+		},
+		{
+			name: "docker not available",
+			cfg: Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+				return ContainerResult{}, ErrDockerNotAvailable
+			}}},
+			wantOp:          "container_run",
+			wantRetryable:   true,
+			wantUnavailable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req.Code == "" {
+				req = toolruntime.ExecuteRequest{Code: "print('hello')", Gateway: &mockGateway{}}
+			}
+
+			_, err := New(tt.cfg).Execute(context.Background(), req)
+			var re *toolruntime.RuntimeError
+			if !errors.As(err, &re) {
+				t.Fatalf("Execute() error = %v, want *toolruntime.RuntimeError", err)
+			}
+			if re.Backend != toolruntime.BackendDocker || re.Op != tt.wantOp || re.Retryable != tt.wantRetryable {
+				t.Errorf("RuntimeError = {Backend: %s, Op: %s, Retryable: %v}, want {docker, %s, %v}",
+					re.Backend, re.Op, re.Retryable, tt.wantOp, tt.wantRetryable)
+			}
+			if got := errors.Is(err, toolruntime.ErrRuntimeUnavailable); got != tt.wantUnavailable {
+				t.Errorf("errors.Is(err, ErrRuntimeUnavailable) = %v, want %v", got, tt.wantUnavailable)
+			}
+		})
+	}
+}
+
+func TestBackendDaemonUnavailableFallsBack(t *testing.T) {
+	var primaryCalls, fallbackCalls int
+	primary := New(Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+		primaryCalls++
+		return ContainerResult{}, &ClientError{Op: "create", Err: fmt.Errorf("%w: connection refused", ErrDaemonUnavailable)}
+	}}})
+	fallback := New(Config{Client: &MockContainerRunner{RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+		fallbackCalls++
+		return ContainerResult{Stdout: "ok"}, nil
+	}}})
+	rt := toolruntime.NewDefaultRuntime(toolruntime.RuntimeConfig{
+		Backends:       map[toolruntime.SecurityProfile]toolruntime.Backend{toolruntime.ProfileStandard: primary},
+		Fallbacks:      map[toolruntime.SecurityProfile][]toolruntime.Backend{toolruntime.ProfileStandard: {fallback}},
+		DefaultProfile: toolruntime.ProfileStandard,
+	})
+
+	result, err := rt.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "echo hi", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v, want fallback to succeed", err)
+	}
+	if primaryCalls == 0 || fallbackCalls != 1 {
+		t.Errorf("calls = %d primary, %d fallback; want the fallback to run once", primaryCalls, fallbackCalls)
+	}
+	if attempted, _ := result.Backend.Details["attempted"].([]string); len(attempted) != 2 {
+		t.Errorf("attempted = %v, want primary and fallback", result.Backend.Details["attempted"])
+	}
+}
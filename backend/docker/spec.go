@@ -1,6 +1,9 @@
 package docker
 
-import "time"
+import (
+	"io/fs"
+	"time"
+)
 
 // MountType defines the type of volume mount.
 type MountType string
@@ -34,6 +37,23 @@ type Mount struct {
 	// Consistency is the mount consistency mode: "consistent", "cached", "delegated".
 	// Only relevant for bind mounts on macOS.
 	Consistency string
+
+	// SizeBytes caps the size of a tmpfs mount.
+	// Zero uses the runtime default. Ignored for other mount types.
+	SizeBytes int64
+}
+
+// File is a file written into the container before the command starts.
+type File struct {
+	// Path is the absolute path inside the container.
+	// It must lie inside a tmpfs mount.
+	Path string
+
+	// Content is the file content.
+	Content []byte
+
+	// Mode holds the file's permission bits.
+	Mode fs.FileMode
 }
 
 // ResourceSpec defines container resource limits.
@@ -90,9 +110,21 @@ type ContainerSpec struct {
 	// Env contains environment variables in KEY=value format.
 	Env []string
 
+	// Stdin is written to the command's standard input.
+	Stdin []byte
+
 	// Mounts defines volume mounts for the container.
 	Mounts []Mount
 
+	// Files are written into the container's tmpfs mounts, creating parent
+	// directories, before Command runs.
+	Files []File
+
+	// OutputDir, if set, is the directory whose regular files are copied
+	// out of the container after the command exits and before the container
+	// is removed.
+	OutputDir string
+
 	// Resources defines resource limits.
 	Resources ResourceSpec
 
@@ -119,6 +151,10 @@ type ContainerResult struct {
 
 	// Duration is the execution time.
 	Duration time.Duration
+
+	// Outputs are the regular files under ContainerSpec.OutputDir, with
+	// absolute paths, copied out before the container was removed.
+	Outputs []File
 }
 
 // StreamEventType identifies the type of streaming event.
@@ -149,6 +185,9 @@ type StreamEvent struct {
 	// ExitCode is set when Type is StreamEventExit.
 	ExitCode int
 
+	// Outputs is set when Type is StreamEventExit, as in ContainerResult.
+	Outputs []File
+
 	// Error is set when Type is StreamEventError.
 	Error error
 }
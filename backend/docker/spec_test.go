@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"errors"
+	"io/fs"
 	"testing"
 )
 
@@ -293,6 +295,16 @@ func TestMountValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "tmpfs mount with negative size",
+			mount: Mount{
+				Type:      MountTypeTmpfs,
+				Target:    "/tmp",
+				SizeBytes: -1,
+			},
+			wantErr: true,
+			errMsg:  "tmpfs size cannot be negative",
+		},
 		{
 			name: "unknown mount type",
 			mount: Mount{
@@ -320,6 +332,45 @@ func TestMountValidate(t *testing.T) {
 	}
 }
 
+func TestContainerSpecValidateFiles(t *testing.T) {
+	workspace := Mount{Type: MountTypeTmpfs, Target: "/workspace", SizeBytes: 4}
+	tests := []struct {
+		name    string
+		mounts  []Mount
+		files   []File
+		wantErr error
+		errMsg  string
+	}{
+		{"inside tmpfs", []Mount{workspace}, []File{{Path: "/workspace/a/b.txt", Content: []byte("abcd")}}, nil, ""},
+		{"relative path", []Mount{workspace}, []File{{Path: "workspace/a"}}, nil, "must be clean and absolute"},
+		{"unclean path", []Mount{workspace}, []File{{Path: "/workspace/../etc/passwd"}}, nil, "must be clean and absolute"},
+		{"outside tmpfs", []Mount{workspace}, []File{{Path: "/etc/passwd"}}, nil, "not inside a tmpfs mount"},
+		{"prefix is not parent", []Mount{workspace}, []File{{Path: "/workspace2/a"}}, nil, "not inside a tmpfs mount"},
+		{"bind mount", []Mount{{Type: MountTypeBind, Source: "/host", Target: "/workspace"}}, []File{{Path: "/workspace/a"}}, nil, "not inside a tmpfs mount"},
+		{"non-permission mode", []Mount{workspace}, []File{{Path: "/workspace/a", Mode: fs.ModeSymlink}}, nil, "not a permission mode"},
+		{"exceeds size", []Mount{workspace}, []File{{Path: "/workspace/a", Content: []byte("abc")}, {Path: "/workspace/b", Content: []byte("de")}}, ErrResourceLimit, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := ContainerSpec{Image: "alpine", Mounts: tt.mounts, Files: tt.files}
+			err := spec.Validate()
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.errMsg != "":
+				if err == nil || !contains(err.Error(), tt.errMsg) {
+					t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+				}
+			case err != nil:
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestMountTypes(t *testing.T) {
 	// Verify mount type constants
 	if MountTypeBind != "bind" {
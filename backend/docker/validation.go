@@ -3,6 +3,9 @@ package docker
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"path"
+	"strings"
 )
 
 // Validate checks ContainerSpec for errors before execution.
@@ -21,6 +24,53 @@ func (s ContainerSpec) Validate() error {
 			return fmt.Errorf("mount[%d]: %w", i, err)
 		}
 	}
+	if s.OutputDir != "" && (!path.IsAbs(s.OutputDir) || path.Clean(s.OutputDir) != s.OutputDir) {
+		return fmt.Errorf("output dir %q must be clean and absolute", s.OutputDir)
+	}
+	return s.validateFiles()
+}
+
+// validateFiles checks that every file lies inside a tmpfs mount and that
+// the files fit in their mount.
+func (s ContainerSpec) validateFiles() error {
+	used := make(map[int]int64)
+	for i, f := range s.Files {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("file[%d]: %w", i, err)
+		}
+		mount := -1
+		for j, m := range s.Mounts {
+			if m.Type == MountTypeTmpfs && within(f.Path, m.Target) &&
+				(mount < 0 || len(m.Target) > len(s.Mounts[mount].Target)) {
+				mount = j
+			}
+		}
+		if mount < 0 {
+			return fmt.Errorf("file[%d]: %s is not inside a tmpfs mount", i, f.Path)
+		}
+		used[mount] += int64(len(f.Content))
+	}
+	for j, size := range used {
+		if limit := s.Mounts[j].SizeBytes; limit > 0 && size > limit {
+			return fmt.Errorf("%w: files need %d bytes, tmpfs %s holds %d", ErrResourceLimit, size, s.Mounts[j].Target, limit)
+		}
+	}
+	return nil
+}
+
+// within reports whether p is inside the directory dir.
+func within(p, dir string) bool {
+	return dir == "/" || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// Validate checks File for a clean absolute path and a permission mode.
+func (f File) Validate() error {
+	if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || f.Path == "/" {
+		return fmt.Errorf("path %q must be clean and absolute", f.Path)
+	}
+	if f.Mode&^fs.ModePerm != 0 {
+		return fmt.Errorf("mode %v is not a permission mode", f.Mode)
+	}
 	return nil
 }
 
@@ -68,6 +118,9 @@ func (m Mount) Validate() error {
 		}
 	case MountTypeTmpfs:
 		// tmpfs doesn't require source
+		if m.SizeBytes < 0 {
+			return errors.New("tmpfs size cannot be negative")
+		}
 	case "":
 		return errors.New("mount type is required")
 	default:
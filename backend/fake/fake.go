@@ -0,0 +1,370 @@
+// Package fake provides a scriptable backend for tests. Requests are matched
+// against rules that return canned results or errors, simulate latency and
+// timeouts, and drive scripted tool calls through the request's gateway.
+// Every request is recorded for assertions.
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolrun"
+	"github.com/jonwraymond/toolruntime"
+)
+
+// Kind is the default backend kind of a fake backend.
+const Kind toolruntime.BackendKind = "fake"
+
+// ErrNoMatch is returned when no rule matches a request and the backend
+// rejects unmatched requests.
+var ErrNoMatch = errors.New("no fake rule matches request")
+
+// ToolCall is a scripted gateway call. If Chain is set, the steps are run
+// with RunChain and each executed step is recorded; otherwise ToolID is run
+// with RunTool.
+type ToolCall struct {
+	// ToolID is the tool to run.
+	ToolID string
+
+	// Args are the tool arguments.
+	Args map[string]any
+
+	// Chain optionally runs a chain instead of a single tool.
+	Chain []toolrun.ChainStep
+
+	// ContinueOnError records a failed call and continues with the script
+	// instead of failing the execution.
+	ContinueOnError bool
+}
+
+// Rule scripts the response to matching requests. All set match conditions
+// must hold; a rule without conditions matches every request.
+type Rule struct {
+	// Code matches requests with exactly this code.
+	Code string
+
+	// CodeContains matches requests whose code contains this substring.
+	CodeContains string
+
+	// Language matches requests with this language.
+	Language string
+
+	// Metadata matches requests whose metadata has all these entries.
+	Metadata map[string]any
+
+	// Match optionally matches requests with a custom predicate.
+	Match func(req toolruntime.ExecuteRequest) bool
+
+	// Times limits how many requests the rule answers.
+	// Zero means unlimited.
+	Times int
+
+	// ToolCalls are made through the request's gateway, in order, before
+	// responding.
+	ToolCalls []ToolCall
+
+	// Latency is how long the execution takes. It honors context
+	// cancellation and the request timeout: if Latency exceeds
+	// ExecuteRequest.Timeout, the execution fails with ErrTimeout once the
+	// timeout elapses.
+	Latency time.Duration
+
+	// Result is returned on success, and alongside Err. Backend.Kind,
+	// ExecutionID and Duration are filled in when empty, and the records
+	// of scripted tool calls are prepended to ToolCalls.
+	Result toolruntime.ExecuteResult
+
+	// Err is returned after the tool calls and latency.
+	Err error
+}
+
+// matches reports whether the rule's conditions hold for req.
+func (r *Rule) matches(req toolruntime.ExecuteRequest) bool {
+	if r.Code != "" && req.Code != r.Code {
+		return false
+	}
+	if r.CodeContains != "" && !strings.Contains(req.Code, r.CodeContains) {
+		return false
+	}
+	if r.Language != "" && req.Language != r.Language {
+		return false
+	}
+	for k, v := range r.Metadata {
+		got, ok := req.Metadata[k]
+		if !ok || !reflect.DeepEqual(got, v) {
+			return false
+		}
+	}
+	return r.Match == nil || r.Match(req)
+}
+
+// Config configures a fake backend.
+type Config struct {
+	// Kind is the backend kind reported by the backend.
+	// Default: "fake"
+	Kind toolruntime.BackendKind
+
+	// Rules are tried in order; the first matching rule with uses left
+	// answers the request.
+	Rules []Rule
+
+	// Default answers requests that match no rule. The zero value returns
+	// an empty successful result.
+	Default Rule
+
+	// RejectUnmatched fails requests that match no rule with ErrNoMatch
+	// instead of using Default.
+	RejectUnmatched bool
+}
+
+// Backend is a scriptable toolruntime.Backend for tests.
+type Backend struct {
+	kind            toolruntime.BackendKind
+	rejectUnmatched bool
+
+	mu       sync.Mutex
+	rules    []*rule
+	fallback Rule
+	requests []toolruntime.ExecuteRequest
+}
+
+// rule is a Rule with its use count.
+type rule struct {
+	Rule
+	uses int
+}
+
+// New creates a new fake backend with the given configuration.
+func New(cfg Config) *Backend {
+	kind := cfg.Kind
+	if kind == "" {
+		kind = Kind
+	}
+	b := &Backend{
+		kind:            kind,
+		rejectUnmatched: cfg.RejectUnmatched,
+		fallback:        cfg.Default,
+	}
+	for _, r := range cfg.Rules {
+		b.AddRule(r)
+	}
+	return b
+}
+
+// AddRule appends a rule. It is safe to call while the backend is in use.
+func (b *Backend) AddRule(r Rule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rules = append(b.rules, &rule{Rule: r})
+}
+
+// Kind returns the configured backend kind.
+func (b *Backend) Kind() toolruntime.BackendKind {
+	return b.kind
+}
+
+// Requests returns the requests received so far, in order, including
+// invalid ones.
+func (b *Backend) Requests() []toolruntime.ExecuteRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.requests)
+}
+
+// Calls returns the number of requests received so far.
+func (b *Backend) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.requests)
+}
+
+// Reset clears recorded requests and rule use counts.
+func (b *Backend) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests = nil
+	for _, r := range b.rules {
+		r.uses = 0
+	}
+}
+
+// Execute answers req with the first matching rule.
+func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
+	b.record(req)
+
+	if err := req.Validate(); err != nil {
+		return toolruntime.ExecuteResult{}, err
+	}
+	if ctx.Err() != nil {
+		return toolruntime.ExecuteResult{}, ctx.Err()
+	}
+
+	r, ok := b.match(req)
+	if !ok {
+		return toolruntime.ExecuteResult{}, fmt.Errorf("%w: code %q", ErrNoMatch, req.Code)
+	}
+
+	start := time.Now()
+	toolCalls, err := b.runToolCalls(ctx, req, r.ToolCalls)
+	if err == nil {
+		err = b.wait(ctx, req, r.Latency)
+	}
+
+	result := r.Result
+	if result.Backend.Kind == "" {
+		result.Backend.Kind = b.kind
+	}
+	if result.ExecutionID == "" {
+		result.ExecutionID = req.ExecutionID
+	}
+	if result.Duration == 0 {
+		result.Duration = time.Since(start)
+	}
+	result.ToolCalls = append(toolCalls, r.Result.ToolCalls...)
+	if result.Backend.Details != nil {
+		result.Backend.Details = maps.Clone(result.Backend.Details)
+	}
+
+	// Hold scripted output to the request's output limits, like real backends
+	capture := toolruntime.NewOutputCapture(req)
+	_, _ = io.WriteString(capture.Stdout, result.Stdout)
+	_, _ = io.WriteString(capture.Stderr, result.Stderr)
+	if cerr := capture.Finish(&result); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return result, err
+	}
+	return result, r.Err
+}
+
+// record stores a copy of req.
+func (b *Backend) record(req toolruntime.ExecuteRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests = append(b.requests, req)
+}
+
+// match returns a copy of the rule that answers req and counts its use.
+func (b *Backend) match(req toolruntime.ExecuteRequest) (Rule, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, r := range b.rules {
+		if (r.Times == 0 || r.uses < r.Times) && r.matches(req) {
+			r.uses++
+			return r.Rule, true
+		}
+	}
+	if b.rejectUnmatched {
+		return Rule{}, false
+	}
+	return b.fallback, true
+}
+
+// runToolCalls makes the scripted calls through req.Gateway and returns
+// their records, one per tool run and one per executed chain step.
+func (b *Backend) runToolCalls(ctx context.Context, req toolruntime.ExecuteRequest, calls []ToolCall) ([]toolruntime.ToolCallRecord, error) {
+	var records []toolruntime.ToolCallRecord
+	for _, call := range calls {
+		if len(call.Chain) > 0 {
+			chain, err := chainRecords(ctx, req, call.Chain)
+			records = append(records, chain...)
+			if err != nil && (!call.ContinueOnError || ctx.Err() != nil) {
+				toolID := call.Chain[0].ToolID
+				if len(chain) > 0 {
+					toolID = chain[len(chain)-1].ToolID
+				}
+				return records, fmt.Errorf("tool call %s: %w", toolID, err)
+			}
+			continue
+		}
+
+		start := time.Now()
+		result, err := req.Gateway.RunTool(ctx, call.ToolID, call.Args)
+		record := toolruntime.ToolCallRecord{
+			ToolID:      call.ToolID,
+			ExecutionID: req.ExecutionID,
+			BackendKind: string(result.Backend.Kind),
+			Duration:    time.Since(start),
+		}
+		if err != nil {
+			record.ErrorOp = "run"
+		}
+		records = append(records, record)
+
+		if err != nil && (!call.ContinueOnError || ctx.Err() != nil) {
+			return records, fmt.Errorf("tool call %s: %w", record.ToolID, err)
+		}
+	}
+	return records, nil
+}
+
+// chainRecords runs steps with RunChain and returns a record per executed
+// step. When the gateway reports no step results, every step is assumed to
+// have run unless the chain failed.
+func chainRecords(ctx context.Context, req toolruntime.ExecuteRequest, steps []toolrun.ChainStep) ([]toolruntime.ToolCallRecord, error) {
+	start := time.Now()
+	_, stepResults, err := req.Gateway.RunChain(ctx, steps)
+	duration := time.Since(start)
+
+	executed := min(len(stepResults), len(steps))
+	if len(stepResults) == 0 && err == nil {
+		executed = len(steps)
+	}
+	if executed == 0 {
+		return nil, err
+	}
+
+	records := make([]toolruntime.ToolCallRecord, executed)
+	for i, step := range steps[:executed] {
+		records[i] = toolruntime.ToolCallRecord{
+			ToolID:      step.ToolID,
+			ExecutionID: req.ExecutionID,
+			Duration:    duration / time.Duration(executed),
+		}
+		if i < len(stepResults) {
+			records[i].BackendKind = string(stepResults[i].Backend.Kind)
+			if stepResults[i].Err != nil {
+				records[i].ErrorOp = "chain"
+			}
+		}
+	}
+	if err != nil && records[executed-1].ErrorOp == "" {
+		records[executed-1].ErrorOp = "chain"
+	}
+	return records, err
+}
+
+// wait simulates latency, honoring cancellation and the request timeout.
+func (b *Backend) wait(ctx context.Context, req toolruntime.ExecuteRequest, latency time.Duration) error {
+	if latency <= 0 {
+		return nil
+	}
+	timedOut := req.Timeout > 0 && latency > req.Timeout
+	if timedOut {
+		latency = req.Timeout
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+	if timedOut {
+		return fmt.Errorf("%w: after %v", toolruntime.ErrTimeout, req.Timeout)
+	}
+	return nil
+}
+
+var _ toolruntime.Backend = (*Backend)(nil)
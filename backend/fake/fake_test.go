@@ -0,0 +1,262 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldocs"
+	"github.com/jonwraymond/toolindex"
+	"github.com/jonwraymond/toolmodel"
+	"github.com/jonwraymond/toolrun"
+	"github.com/jonwraymond/toolruntime"
+)
+
+// mockGateway records tool runs and fails the tools listed in fail
+type mockGateway struct {
+	mu   sync.Mutex
+	runs []string
+	fail map[string]bool
+}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]toolindex.Summary, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldocs.DetailLevel) (tooldocs.ToolDoc, error) {
+	return tooldocs.ToolDoc{}, errors.New("tool not found")
+}
+
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldocs.ToolExample, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) RunTool(_ context.Context, id string, _ map[string]any) (toolrun.RunResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs = append(m.runs, id)
+	if m.fail[id] {
+		return toolrun.RunResult{}, errors.New("tool failed")
+	}
+	return toolrun.RunResult{Backend: toolmodel.ToolBackend{Kind: "mcp"}}, nil
+}
+
+func (m *mockGateway) RunChain(_ context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, step := range steps {
+		m.runs = append(m.runs, step.ToolID)
+	}
+	return toolrun.RunResult{}, nil, nil
+}
+
+func TestBackendContract(t *testing.T) {
+	toolruntime.RunBackendContractTests(t, toolruntime.BackendContract{
+		NewBackend: func() toolruntime.Backend {
+			return New(Config{Default: Rule{Result: toolruntime.ExecuteResult{Value: "hello"}}})
+		},
+		NewGateway: func() toolruntime.ToolGateway {
+			return &mockGateway{}
+		},
+		ExpectedKind: Kind,
+	})
+}
+
+func TestBackendMatching(t *testing.T) {
+	failure := errors.New("boom")
+	b := New(Config{
+		Kind: toolruntime.BackendDocker,
+		Rules: []Rule{
+			{Code: "exact", Result: toolruntime.ExecuteResult{Value: "exact"}},
+			{CodeContains: "once", Times: 1, Result: toolruntime.ExecuteResult{Value: "once"}},
+			{Language: "python", Result: toolruntime.ExecuteResult{Value: "python"}},
+			{Metadata: map[string]any{"tenant": "acme"}, Err: failure},
+			{Match: func(req toolruntime.ExecuteRequest) bool { return req.Limits.MaxToolCalls > 0 }, Result: toolruntime.ExecuteResult{Value: "custom"}},
+		},
+		Default: Rule{Result: toolruntime.ExecuteResult{Value: "default"}},
+	})
+
+	tests := []struct {
+		name    string
+		req     toolruntime.ExecuteRequest
+		want    any
+		wantErr error
+	}{
+		{"exact code", toolruntime.ExecuteRequest{Code: "exact"}, "exact", nil},
+		{"contains", toolruntime.ExecuteRequest{Code: "run once"}, "once", nil},
+		{"times exhausted", toolruntime.ExecuteRequest{Code: "run once"}, "default", nil},
+		{"language", toolruntime.ExecuteRequest{Code: "x", Language: "python"}, "python", nil},
+		{"metadata", toolruntime.ExecuteRequest{Code: "x", Metadata: map[string]any{"tenant": "acme", "user": "u"}}, nil, failure},
+		{"metadata mismatch", toolruntime.ExecuteRequest{Code: "x", Metadata: map[string]any{"tenant": "other"}}, "default", nil},
+		{"custom", toolruntime.ExecuteRequest{Code: "x", Limits: toolruntime.Limits{MaxToolCalls: 1}}, "custom", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Gateway = &mockGateway{}
+			tt.req.ExecutionID = "exec-1"
+			result, err := b.Execute(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			if result.Value != tt.want {
+				t.Errorf("Value = %v, want %v", result.Value, tt.want)
+			}
+			if result.Backend.Kind != toolruntime.BackendDocker || result.ExecutionID != "exec-1" {
+				t.Errorf("result = %+v, want docker backend and execution ID", result)
+			}
+		})
+	}
+
+	if got := b.Calls(); got != len(tests) {
+		t.Errorf("Calls() = %d, want %d", got, len(tests))
+	}
+	if got := b.Requests()[1].Code; got != "run once" {
+		t.Errorf("Requests()[1].Code = %q, want %q", got, "run once")
+	}
+
+	b.Reset()
+	if b.Calls() != 0 {
+		t.Errorf("Calls() after Reset = %d, want 0", b.Calls())
+	}
+	if result, _ := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "once", Gateway: &mockGateway{}}); result.Value != "once" {
+		t.Errorf("Value after Reset = %v, want once", result.Value)
+	}
+}
+
+func TestBackendRejectUnmatched(t *testing.T) {
+	b := New(Config{RejectUnmatched: true})
+	_, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("Execute() error = %v, want ErrNoMatch", err)
+	}
+
+	b.AddRule(Rule{Code: "x"})
+	if _, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}}); err != nil {
+		t.Errorf("Execute() after AddRule error = %v", err)
+	}
+}
+
+func TestBackendOutputLimits(t *testing.T) {
+	b := New(Config{Default: Rule{Result: toolruntime.ExecuteResult{Stdout: "0123456789", Value: "abcdef"}}})
+	req := toolruntime.ExecuteRequest{
+		Code:    "x",
+		Gateway: &mockGateway{},
+		Limits:  toolruntime.Limits{MaxStdoutBytes: 4, MaxValueBytes: 3},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "0123\n[truncated: 6 bytes omitted]\n" {
+		t.Errorf("Stdout = %q", result.Stdout)
+	}
+	if result.Value != "\"ab\n[truncated: 5 bytes omitted]\n" {
+		t.Errorf("Value = %q", result.Value)
+	}
+}
+
+func TestBackendLatency(t *testing.T) {
+	b := New(Config{Default: Rule{Latency: 50 * time.Millisecond}})
+	gw := &mockGateway{}
+
+	t.Run("timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "x", Gateway: gw, Timeout: 5 * time.Millisecond})
+		if !errors.Is(err, toolruntime.ErrTimeout) {
+			t.Fatalf("Execute() error = %v, want ErrTimeout", err)
+		}
+		if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+			t.Errorf("Execute() took %v, want about the timeout", elapsed)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		_, err := b.Execute(ctx, toolruntime.ExecuteRequest{Code: "x", Gateway: gw})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Execute() error = %v, want DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("completes", func(t *testing.T) {
+		result, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "x", Gateway: gw, Timeout: time.Second})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.Duration < 50*time.Millisecond {
+			t.Errorf("Duration = %v, want at least the latency", result.Duration)
+		}
+	})
+}
+
+func TestBackendToolCalls(t *testing.T) {
+	gw := &mockGateway{fail: map[string]bool{"ns:bad": true}}
+	b := New(Config{Rules: []Rule{
+		{Code: "ok", ToolCalls: []ToolCall{
+			{ToolID: "ns:a", Args: map[string]any{"x": 1}},
+			{ToolID: "ns:bad", ContinueOnError: true},
+			{Chain: []toolrun.ChainStep{{ToolID: "ns:b"}, {ToolID: "ns:c"}}},
+		}},
+		{Code: "fail", ToolCalls: []ToolCall{{ToolID: "ns:bad"}, {ToolID: "ns:never"}}},
+	}})
+
+	result, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "ok", Gateway: gw})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(result.ToolCalls) != 4 {
+		t.Fatalf("ToolCalls = %+v, want a record per tool and chain step", result.ToolCalls)
+	}
+	if rec := result.ToolCalls[0]; rec.ToolID != "ns:a" || rec.BackendKind != "mcp" || rec.ErrorOp != "" {
+		t.Errorf("ToolCalls[0] = %+v", rec)
+	}
+	if result.ToolCalls[1].ErrorOp != "run" || result.ToolCalls[2].ToolID != "ns:b" || result.ToolCalls[3].ToolID != "ns:c" {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+
+	_, err = b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "fail", Gateway: gw})
+	if err == nil {
+		t.Fatal("Execute() with failing tool call should fail")
+	}
+	want := []string{"ns:a", "ns:bad", "ns:b", "ns:c", "ns:bad"}
+	if len(gw.runs) != len(want) {
+		t.Fatalf("gateway runs = %v, want %v", gw.runs, want)
+	}
+	for i := range want {
+		if gw.runs[i] != want[i] {
+			t.Errorf("gateway runs = %v, want %v", gw.runs, want)
+			break
+		}
+	}
+}
+
+func TestBackendWithRuntime(t *testing.T) {
+	unavailable := fmt.Errorf("%w: fake outage", toolruntime.ErrRuntimeUnavailable)
+	primary := New(Config{Kind: toolruntime.BackendDocker, Default: Rule{Err: unavailable}})
+	fallback := New(Config{Kind: toolruntime.BackendGVisor})
+	rt := toolruntime.NewDefaultRuntime(toolruntime.RuntimeConfig{
+		Backends:       map[toolruntime.SecurityProfile]toolruntime.Backend{toolruntime.ProfileStandard: primary},
+		Fallbacks:      map[toolruntime.SecurityProfile][]toolruntime.Backend{toolruntime.ProfileStandard: {fallback}},
+		DefaultProfile: toolruntime.ProfileStandard,
+	})
+
+	result, err := rt.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Backend.Kind != toolruntime.BackendGVisor || primary.Calls() != 1 || fallback.Calls() != 1 {
+		t.Errorf("backend = %q, calls = %d/%d; want gvisor, 1/1", result.Backend.Kind, primary.Calls(), fallback.Calls())
+	}
+	if id := fallback.Requests()[0].ExecutionID; id == "" || id != result.ExecutionID {
+		t.Errorf("recorded ExecutionID = %q, want %q", id, result.ExecutionID)
+	}
+}
@@ -115,7 +115,7 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		return toolruntime.ExecuteResult{}, err
 	}
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendFirecracker, "validate", err, false)
 	}
 
 	start := time.Now()
@@ -131,7 +131,8 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		},
 	}
 
-	return result, fmt.Errorf("%w: firecracker backend not fully implemented", ErrFirecrackerNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendFirecracker, "execute",
+		fmt.Errorf("%w: %w: firecracker backend not fully implemented", ErrFirecrackerNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
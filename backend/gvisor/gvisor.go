@@ -106,7 +106,7 @@ func (b *Backend) Kind() toolruntime.BackendKind {
 // Execute runs code with gVisor isolation.
 func (b *Backend) Execute(_ context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendGVisor, "validate", err, false)
 	}
 
 	start := time.Now()
@@ -122,7 +122,8 @@ func (b *Backend) Execute(_ context.Context, req toolruntime.ExecuteRequest) (to
 		},
 	}
 
-	return result, fmt.Errorf("%w: gvisor backend not fully implemented", ErrGVisorNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendGVisor, "execute",
+		fmt.Errorf("%w: %w: gvisor backend not fully implemented", ErrGVisorNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
@@ -96,7 +96,7 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		return toolruntime.ExecuteResult{}, err
 	}
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendKata, "validate", err, false)
 	}
 
 	start := time.Now()
@@ -111,7 +111,8 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		},
 	}
 
-	return result, fmt.Errorf("%w: kata backend not fully implemented", ErrKataNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendKata, "execute",
+		fmt.Errorf("%w: %w: kata backend not fully implemented", ErrKataNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
@@ -96,7 +96,7 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		return toolruntime.ExecuteResult{}, err
 	}
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendKubernetes, "validate", err, false)
 	}
 
 	start := time.Now()
@@ -113,7 +113,8 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 		},
 	}
 
-	return result, fmt.Errorf("%w: kubernetes backend not fully implemented", ErrKubernetesNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendKubernetes, "execute",
+		fmt.Errorf("%w: %w: kubernetes backend not fully implemented", ErrKubernetesNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
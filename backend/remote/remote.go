@@ -99,11 +99,12 @@ func (b *Backend) Kind() toolruntime.BackendKind {
 // Execute runs code on the remote runtime service.
 func (b *Backend) Execute(_ context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendRemote, "validate", err, false)
 	}
 
 	if b.endpoint == "" {
-		return toolruntime.ExecuteResult{}, fmt.Errorf("%w: endpoint not configured", ErrRemoteNotAvailable)
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendRemote, "configure",
+			fmt.Errorf("%w: %w: endpoint not configured", ErrRemoteNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 	}
 
 	start := time.Now()
@@ -118,7 +119,8 @@ func (b *Backend) Execute(_ context.Context, req toolruntime.ExecuteRequest) (to
 		},
 	}
 
-	return result, fmt.Errorf("%w: remote backend not fully implemented", ErrRemoteNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendRemote, "execute",
+		fmt.Errorf("%w: %w: remote backend not fully implemented", ErrRemoteNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
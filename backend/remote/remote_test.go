@@ -61,6 +61,9 @@ func TestBackendRequiresEndpoint(t *testing.T) {
 	if !errors.Is(err, ErrRemoteNotAvailable) {
 		t.Errorf("Execute() without endpoint error = %v, want %v", err, ErrRemoteNotAvailable)
 	}
+	if !errors.Is(err, toolruntime.ErrRuntimeUnavailable) {
+		t.Errorf("Execute() without endpoint error = %v, want %v", err, toolruntime.ErrRuntimeUnavailable)
+	}
 }
 
 // mockGateway implements toolruntime.ToolGateway for testing
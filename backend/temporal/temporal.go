@@ -116,12 +116,12 @@ func (b *Backend) Kind() toolruntime.BackendKind {
 // The actual code execution is delegated to the configured sandbox backend.
 func (b *Backend) Execute(_ context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendTemporal, "validate", err, false)
 	}
 
 	// Temporal is orchestration, not isolation - must have a sandbox backend
 	if b.sandboxBackend == nil {
-		return toolruntime.ExecuteResult{}, ErrMissingSandboxBackend
+		return toolruntime.ExecuteResult{}, toolruntime.NewRuntimeError(toolruntime.BackendTemporal, "configure", ErrMissingSandboxBackend, false)
 	}
 
 	start := time.Now()
@@ -138,7 +138,8 @@ func (b *Backend) Execute(_ context.Context, req toolruntime.ExecuteRequest) (to
 		},
 	}
 
-	return result, fmt.Errorf("%w: temporal backend not fully implemented", ErrTemporalNotAvailable)
+	return result, toolruntime.NewRuntimeError(toolruntime.BackendTemporal, "execute",
+		fmt.Errorf("%w: %w: temporal backend not fully implemented", ErrTemporalNotAvailable, toolruntime.ErrRuntimeUnavailable), false)
 }
 
 var _ toolruntime.Backend = (*Backend)(nil)
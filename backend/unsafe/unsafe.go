@@ -5,13 +5,12 @@ package unsafe
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/jonwraymond/toolruntime"
@@ -25,8 +24,8 @@ const (
 	// Faster startup but limited Go compatibility.
 	ModeInterpreter ExecutionMode = "interpreter"
 
-	// ModeSubprocess uses `go run` to execute code.
-	// Full Go compatibility but requires Go toolchain.
+	// ModeSubprocess runs code with the command of its language, such as
+	// `go run` or `python3`. It requires the toolchain on the host.
 	ModeSubprocess ExecutionMode = "subprocess"
 )
 
@@ -65,6 +64,26 @@ type Config struct {
 	// RequireOptIn requires explicit opt-in via request metadata.
 	// When true, requests must include metadata["unsafeOptIn"] = true.
 	RequireOptIn bool
+
+	// PassEnv lists the host environment variables passed to executed code,
+	// in addition to ExecuteRequest.Env and Secrets. Other host variables
+	// are not inherited.
+	// Default: PATH, HOME, TMPDIR and the variables configuring the Go
+	// toolchain
+	PassEnv []string
+
+	// Languages resolves ExecuteRequest.Language. Code runs with the
+	// command of its language, which must be installed on the host.
+	// Default: toolruntime.DefaultLanguages()
+	Languages *toolruntime.LanguageRegistry
+}
+
+// defaultPassEnv are the host variables the language toolchains, notably
+// `go run`, need to run the program.
+var defaultPassEnv = []string{
+	"PATH", "HOME", "TMPDIR",
+	"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE", "GOENV", "GOFLAGS",
+	"GOPROXY", "GOSUMDB", "GONOSUMDB", "GOPRIVATE", "GONOPROXY", "GOTOOLCHAIN",
 }
 
 // Backend executes code directly on the host without isolation.
@@ -73,6 +92,8 @@ type Backend struct {
 	mode         ExecutionMode
 	logger       Logger
 	requireOptIn bool
+	passEnv      []string
+	languages    *toolruntime.LanguageRegistry
 }
 
 // New creates a new unsafe backend with the given configuration.
@@ -82,10 +103,22 @@ func New(cfg Config) *Backend {
 		mode = ModeInterpreter
 	}
 
+	passEnv := cfg.PassEnv
+	if passEnv == nil {
+		passEnv = defaultPassEnv
+	}
+
+	languages := cfg.Languages
+	if languages == nil {
+		languages = toolruntime.DefaultLanguages()
+	}
+
 	return &Backend{
 		mode:         mode,
 		logger:       cfg.Logger,
 		requireOptIn: cfg.RequireOptIn,
+		passEnv:      passEnv,
+		languages:    languages,
 	}
 }
 
@@ -94,25 +127,108 @@ func (b *Backend) Kind() toolruntime.BackendKind {
 	return toolruntime.BackendUnsafeHost
 }
 
+// Capabilities describes the unsafe backend. Only the timeout and output
+// limits are enforced; code runs with the host's network and filesystem.
+func (b *Backend) Capabilities() toolruntime.Capabilities {
+	return toolruntime.Capabilities{
+		Languages:          b.languages.Names(),
+		Limits:             toolruntime.LimitsEnforced{Timeout: true, Output: true},
+		Isolation:          toolruntime.IsolationNone,
+		Network:            true,
+		WritableFilesystem: true,
+		Streaming:          true,
+		Files:              true,
+		Artifacts:          true,
+	}
+}
+
 // Execute runs code on the host without isolation.
 func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, runtimeError(opValidate, err)
 	}
 
 	// Check opt-in requirement
+	if err := b.checkOptIn(req); err != nil {
+		return toolruntime.ExecuteResult{}, runtimeError(opValidate, err)
+	}
+	lang, err := b.resolve(req)
+	if err != nil {
+		return toolruntime.ExecuteResult{}, runtimeError(opValidate, err)
+	}
+
+	return b.run(ctx, req, lang, nil, nil)
+}
+
+// ExecuteStream runs code on the host without isolation and streams stdout
+// and stderr as they are written by the subprocess.
+func (b *Backend) ExecuteStream(ctx context.Context, req toolruntime.ExecuteRequest) (<-chan toolruntime.StreamEvent, error) {
+	if err := req.Validate(); err != nil {
+		return nil, runtimeError(opValidate, err)
+	}
+	if err := b.checkOptIn(req); err != nil {
+		return nil, runtimeError(opValidate, err)
+	}
+	lang, err := b.resolve(req)
+	if err != nil {
+		return nil, runtimeError(opValidate, err)
+	}
+
+	out := make(chan toolruntime.StreamEvent, 16)
+	go func() {
+		defer close(out)
+
+		stdout := toolruntime.StreamWriter(ctx, out, toolruntime.StreamEventStdout)
+		stderr := toolruntime.StreamWriter(ctx, out, toolruntime.StreamEventStderr)
+		result, err := b.run(ctx, req, lang, stdout, stderr)
+
+		ev := toolruntime.StreamEvent{Type: toolruntime.StreamEventExit, Result: &result}
+		if err != nil {
+			ev = toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: &result, Error: err}
+		}
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out, nil
+}
+
+// checkOptIn enforces the RequireOptIn setting.
+func (b *Backend) checkOptIn(req toolruntime.ExecuteRequest) error {
 	if b.requireOptIn {
 		optIn, ok := req.Metadata["unsafeOptIn"].(bool)
 		if !ok || !optIn {
-			return toolruntime.ExecuteResult{}, ErrOptInRequired
+			return ErrOptInRequired
 		}
 	}
+	return nil
+}
 
+// resolve returns the language of req, rejecting request files that would
+// replace the program.
+func (b *Backend) resolve(req toolruntime.ExecuteRequest) (toolruntime.Language, error) {
+	lang, err := b.languages.Resolve(req.Language)
+	if err != nil {
+		return toolruntime.Language{}, err
+	}
+	if _, ok := req.Files[lang.SourceFile()]; ok {
+		return toolruntime.Language{}, fmt.Errorf("%w: %s is reserved for the code", toolruntime.ErrInvalidFile, lang.SourceFile())
+	}
+	return lang, nil
+}
+
+// run executes a validated request. Output is always captured in the result;
+// stdoutW and stderrW, when non-nil, additionally receive output as it is written.
+func (b *Backend) run(ctx context.Context, req toolruntime.ExecuteRequest, lang toolruntime.Language, stdoutW, stderrW io.Writer) (toolruntime.ExecuteResult, error) {
 	// Log UNSAFE warning
 	if b.logger != nil {
 		b.logger.Warn("UNSAFE: executing code without isolation",
+			"executionID", req.ExecutionID,
 			"mode", b.mode,
+			"language", lang.Name,
 			"codeLen", len(req.Code))
 	}
 
@@ -132,18 +248,20 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 
 	switch b.mode {
 	case ModeInterpreter:
-		result, err = b.executeInterpreter(ctx, req)
+		result, err = b.executeInterpreter(ctx, req, lang, stdoutW, stderrW)
 	case ModeSubprocess:
-		result, err = b.executeSubprocess(ctx, req)
+		result, err = b.executeSubprocess(ctx, req, lang, stdoutW, stderrW)
 	default:
-		result, err = b.executeInterpreter(ctx, req)
+		result, err = b.executeInterpreter(ctx, req, lang, stdoutW, stderrW)
 	}
 
+	result.ExecutionID = req.ExecutionID
 	result.Duration = time.Since(start)
 	result.Backend = toolruntime.BackendInfo{
 		Kind: toolruntime.BackendUnsafeHost,
 		Details: map[string]any{
-			"mode": string(b.mode),
+			"mode":     string(b.mode),
+			"language": lang.Name,
 		},
 	}
 
@@ -152,7 +270,7 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 
 // executeInterpreter executes code using an in-process interpreter.
 // Note: This is a simplified implementation. A full implementation would use yaegi.
-func (b *Backend) executeInterpreter(ctx context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
+func (b *Backend) executeInterpreter(ctx context.Context, req toolruntime.ExecuteRequest, lang toolruntime.Language, stdoutW, stderrW io.Writer) (toolruntime.ExecuteResult, error) {
 	// For now, fall back to subprocess since yaegi integration is complex
 	// A full implementation would:
 	// 1. Create a yaegi interpreter
@@ -160,114 +278,149 @@ func (b *Backend) executeInterpreter(ctx context.Context, req toolruntime.Execut
 	// 3. Execute the code
 	// 4. Extract __out value
 
-	return b.executeSubprocess(ctx, req)
+	return b.executeSubprocess(ctx, req, lang, stdoutW, stderrW)
 }
 
-// executeSubprocess executes code using `go run`.
-func (b *Backend) executeSubprocess(ctx context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
+// executeSubprocess executes code with the command of its language.
+func (b *Backend) executeSubprocess(ctx context.Context, req toolruntime.ExecuteRequest, lang toolruntime.Language, stdoutW, stderrW io.Writer) (toolruntime.ExecuteResult, error) {
 	// Create a temporary directory for the code
 	tmpDir, err := os.MkdirTemp("", "toolruntime-unsafe-*")
 	if err != nil {
-		return toolruntime.ExecuteResult{}, fmt.Errorf("%w: failed to create temp dir: %v", ErrSubprocessFailed, err)
+		return toolruntime.ExecuteResult{}, runtimeError(opWriteSource, fmt.Errorf("%w: failed to create temp dir: %v", ErrSubprocessFailed, err))
 	}
 	defer func() {
 		_ = os.RemoveAll(tmpDir)
 	}()
 
-	// Wrap the code in a main function
-	wrappedCode := wrapCode(req.Code)
+	// Wrap the code for its language
+	wrappedCode := lang.Wrap(req.Code)
 
-	// Write the code to a file
-	mainFile := filepath.Join(tmpDir, "main.go")
-	if err := os.WriteFile(mainFile, []byte(wrappedCode), 0600); err != nil {
-		return toolruntime.ExecuteResult{}, fmt.Errorf("%w: failed to write code: %v", ErrSubprocessFailed, err)
+	// Write the request files, then the code and the language's support files
+	_, span := toolruntime.StartSpan(ctx, "unsafe.write_source", toolruntime.Attr("unsafe.dir", tmpDir))
+	err = writeSource(tmpDir, lang, wrappedCode, req.Files)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return toolruntime.ExecuteResult{}, runtimeError(opWriteSource, err)
 	}
 
-	// Create go.mod
-	goMod := `module toolruntime_exec
+	inputs, err := toolruntime.EncodeInputs(req.Inputs)
+	if err != nil {
+		return toolruntime.ExecuteResult{}, runtimeError(opValidate, err)
+	}
 
-go 1.21
-`
-	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte(goMod), 0600); err != nil {
-		return toolruntime.ExecuteResult{}, fmt.Errorf("%w: failed to write go.mod: %v", ErrSubprocessFailed, err)
+	// Create the artifact output directory next to the source
+	var outDir string
+	if req.Artifacts != nil {
+		outDir, err = os.MkdirTemp("", "toolruntime-unsafe-out-*")
+		if err != nil {
+			return toolruntime.ExecuteResult{}, runtimeError(opWriteSource, fmt.Errorf("%w: failed to create output dir: %v", ErrSubprocessFailed, err))
+		}
+		defer func() {
+			_ = os.RemoveAll(outDir)
+		}()
 	}
 
 	// Run the code
-	cmd := exec.CommandContext(ctx, "go", "run", ".")
+	runCtx, span := toolruntime.StartSpan(ctx, "unsafe.run", toolruntime.Attr("unsafe.language", lang.Name))
+	defer span.End()
+	cmd := exec.CommandContext(runCtx, lang.Command[0], lang.Command[1:]...)
 	cmd.Dir = tmpDir
+	cmd.Env = append(b.environ(req), toolruntime.InputsEnv+"="+string(inputs))
+	if outDir != "" {
+		cmd.Env = append(cmd.Env, toolruntime.OutputDirEnv+"="+outDir)
+	}
+	cmd.Stdin = bytes.NewReader(req.Stdin)
+	// Children of a canceled process may keep its output pipes open
+	cmd.WaitDelay = time.Second
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Output is kept within the request's limits; the wrapped code reports
+	// __out in the result envelope, which is stripped from stdout
+	capture := toolruntime.NewOutputCapture(req).WithStream(stdoutW, stderrW)
+	cmd.Env = append(cmd.Env, toolruntime.ResultNonceEnv+"="+capture.Nonce())
+	cmd.Stdout = capture.Stdout
+	cmd.Stderr = capture.Stderr
 
 	err = cmd.Run()
+	span.RecordError(err)
 
-	result := toolruntime.ExecuteResult{
-		Stdout: stdout.String(),
-		Stderr: stderr.String(),
+	var result toolruntime.ExecuteResult
+	if cerr := capture.Finish(&result); cerr != nil && err == nil {
+		return result, runtimeError(opCaptureOutput, cerr)
 	}
 
+	if err != nil && ctx.Err() != nil {
+		return result, runtimeError(opRun, fmt.Errorf("%w: %w", toolruntime.ErrTimeout, ctx.Err()))
+	}
+	if cerr := capture.Err(); cerr != nil {
+		return result, runtimeError(opRun, cerr)
+	}
 	if err != nil {
-		if ctx.Err() != nil {
-			return result, fmt.Errorf("%w: %v", toolruntime.ErrTimeout, ctx.Err())
-		}
-		return result, fmt.Errorf("%w: %v\nstderr: %s", ErrSubprocessFailed, err, stderr.String())
+		return result, runtimeError(opRun, fmt.Errorf("%w: %v\nstderr: %s", ErrSubprocessFailed, err, result.Stderr))
 	}
 
-	// Extract __out value from stdout
-	// The wrapped code prints "__OUT__:<value>" at the end
-	result.Value = extractOutValue(stdout.String())
+	// Collect artifacts
+	if outDir != "" {
+		result.Artifacts, err = toolruntime.CollectArtifacts(outDir, *req.Artifacts)
+		if err != nil {
+			return result, runtimeError(opCollectArtifacts, err)
+		}
+	}
 
 	return result, nil
 }
 
-// wrapCode wraps user code in a main function with output capture.
-func wrapCode(code string) string {
-	// Check if code already has package/imports
-	hasPackage := strings.Contains(code, "package ")
-	hasMain := strings.Contains(code, "func main()")
-
-	if hasPackage && hasMain {
-		// Code is already complete
-		return code
-	}
-
-	// Wrap in main function with __out capture
-	return fmt.Sprintf(`package main
-
-import (
-	"encoding/json"
-	"fmt"
+// Operations reported in toolruntime.RuntimeError.Op.
+const (
+	opValidate         = "validate"
+	opWriteSource      = "write_source"
+	opRun              = "run"
+	opCollectArtifacts = "collect_artifacts"
+	opCaptureOutput    = "capture_output"
 )
 
-func main() {
-	var __out any
-
-	// User code starts here
-	%s
-	// User code ends here
+// runtimeError wraps err in a toolruntime.RuntimeError for op. Only failures
+// to prepare the source directory happen before user code starts, so only
+// those are retryable.
+func runtimeError(op string, err error) error {
+	return toolruntime.NewRuntimeError(toolruntime.BackendUnsafeHost, op, err, op == opWriteSource)
+}
 
-	// Output __out value
-	if __out != nil {
-		data, _ := json.Marshal(__out)
-		fmt.Printf("__OUT__:%%s\n", string(data))
+// environ returns the environment of executed code: the passed-through host
+// variables that are set, followed by the request's Env and Secrets.
+func (b *Backend) environ(req toolruntime.ExecuteRequest) []string {
+	var env []string
+	for _, name := range b.passEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
 	}
-}
-`, code)
+	return append(env, toolruntime.EnvList(req.Env, req.Secrets)...)
 }
 
-// extractOutValue extracts the __out value from stdout.
-func extractOutValue(stdout string) any {
-	lines := strings.Split(stdout, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "__OUT__:") {
-			jsonStr := strings.TrimPrefix(line, "__OUT__:")
-			var value any
-			if err := json.Unmarshal([]byte(jsonStr), &value); err == nil {
-				return value
-			}
-			return jsonStr
+// writeSource writes the request files, the program and the support files
+// of lang that the request does not provide into dir.
+func writeSource(dir string, lang toolruntime.Language, code string, files map[string]toolruntime.File) error {
+	if err := toolruntime.WriteFiles(dir, files); err != nil {
+		return fmt.Errorf("%w: failed to write files: %w", ErrSubprocessFailed, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, lang.SourceFile()), []byte(code), 0600); err != nil {
+		return fmt.Errorf("%w: failed to write code: %v", ErrSubprocessFailed, err)
+	}
+
+	for name, content := range lang.Files {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			return fmt.Errorf("%w: failed to write %s: %v", ErrSubprocessFailed, name, err)
 		}
 	}
 	return nil
 }
+
+var (
+	_ toolruntime.StreamingBackend   = (*Backend)(nil)
+	_ toolruntime.CapabilityReporter = (*Backend)(nil)
+)
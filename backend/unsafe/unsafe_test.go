@@ -4,6 +4,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -80,6 +85,17 @@ func TestBackendKind(t *testing.T) {
 	}
 }
 
+func TestBackendCapabilities(t *testing.T) {
+	caps := New(Config{}).Capabilities()
+
+	if caps.Isolation != toolruntime.IsolationNone || !caps.Network || !caps.WritableFilesystem || !caps.Files {
+		t.Errorf("Capabilities() = %+v, want no isolation with host network, filesystem and files", caps)
+	}
+	if missing := caps.UnenforcedLimits(toolruntime.ExecuteRequest{Limits: toolruntime.Limits{MemoryBytes: 1}}); len(missing) != 1 {
+		t.Errorf("UnenforcedLimits(memory) = %v, want [memory]", missing)
+	}
+}
+
 func TestBackendRequiresGateway(t *testing.T) {
 	b := New(Config{})
 
@@ -140,6 +156,10 @@ func TestBackendRequiresOptIn(t *testing.T) {
 	if !errors.Is(err, ErrOptInRequired) {
 		t.Errorf("Execute() without opt-in error = %v, want %v", err, ErrOptInRequired)
 	}
+	var re *toolruntime.RuntimeError
+	if !errors.As(err, &re) || re.Op != "validate" || re.Retryable {
+		t.Errorf("Execute() without opt-in error = %#v, want non-retryable validate RuntimeError", err)
+	}
 }
 
 func TestBackendOptInAllows(t *testing.T) {
@@ -189,6 +209,34 @@ func TestBackendRespectsTimeout(t *testing.T) {
 	}
 }
 
+func TestBackendAsyncCancel(t *testing.T) {
+	rt := toolruntime.NewDefaultRuntime(toolruntime.RuntimeConfig{
+		Backends:       map[toolruntime.SecurityProfile]toolruntime.Backend{toolruntime.ProfileDev: New(Config{Mode: ModeSubprocess})},
+		DefaultProfile: toolruntime.ProfileDev,
+	})
+	a := toolruntime.NewAsyncRuntime(rt, toolruntime.AsyncConfig{})
+	defer a.Close()
+
+	h, err := a.Submit(toolruntime.ExecuteRequest{Language: "shell", Code: "sleep 30", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	for h.Status() == toolruntime.JobPending {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	h.Cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+	if got := h.Status(); got != toolruntime.JobCanceled {
+		t.Errorf("Status() = %q, want %q", got, toolruntime.JobCanceled)
+	}
+}
+
 func TestBackendReturnsBackendInfo(t *testing.T) {
 	b := New(Config{Mode: ModeInterpreter})
 
@@ -227,6 +275,331 @@ func TestBackendCapturesStdout(t *testing.T) {
 	}
 }
 
+func TestBackendExecuteStream(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+
+	ctx := context.Background()
+	req := toolruntime.ExecuteRequest{
+		Code:    `fmt.Println("streamed")`,
+		Gateway: &mockGateway{},
+	}
+
+	events, err := b.ExecuteStream(ctx, req)
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	var stdout strings.Builder
+	var last toolruntime.StreamEvent
+	for ev := range events {
+		if ev.Type == toolruntime.StreamEventStdout {
+			stdout.Write(ev.Data)
+		}
+		last = ev
+	}
+
+	if last.Type == toolruntime.StreamEventError {
+		t.Skipf("ExecuteStream() error = %v (go toolchain may not be available)", last.Error)
+	}
+	if !strings.Contains(stdout.String(), "streamed") {
+		t.Errorf("streamed stdout = %q, want to contain %q", stdout.String(), "streamed")
+	}
+	if last.Type != toolruntime.StreamEventExit || last.Result == nil {
+		t.Errorf("last event = %+v, want exit with result", last)
+	}
+}
+
+func TestWriteSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]toolruntime.File{
+		"go.mod":         {Content: []byte("module custom\n\ngo 1.21\n")},
+		"data/input.txt": {Content: []byte("42"), Mode: 0o600},
+	}
+	lang, _ := toolruntime.DefaultLanguages().Lookup("go")
+	if err := writeSource(dir, lang, "package main", files); err != nil {
+		t.Fatalf("writeSource() error = %v", err)
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil || !strings.HasPrefix(string(goMod), "module custom") {
+		t.Errorf("go.mod = %q, %v; want the request's go.mod", goMod, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "data", "input.txt"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("data/input.txt = %v, %v; want mode 0600", info, err)
+	}
+	if main, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(main) != "package main" {
+		t.Errorf("main.go = %q, want the code", main)
+	}
+}
+
+func TestBackendRejectsInvalidFiles(t *testing.T) {
+	b := New(Config{})
+	for _, name := range []string{"main.go", "../escape.go", "/etc/passwd"} {
+		_, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{
+			Code:    `fmt.Println("x")`,
+			Gateway: &mockGateway{},
+			Files:   map[string]toolruntime.File{name: {Content: []byte("x")}},
+		})
+		if !errors.Is(err, toolruntime.ErrInvalidFile) {
+			t.Errorf("Execute() with file %q error = %v, want ErrInvalidFile", name, err)
+		}
+	}
+}
+
+func TestBackendArtifacts(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{
+		Code: `package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+func main() {
+	_ = os.WriteFile(filepath.Join(os.Getenv("TOOLRUNTIME_OUTPUT_DIR"), "out.csv"), []byte("a,b"), 0o644)
+}
+`,
+		Gateway:   &mockGateway{},
+		Artifacts: &toolruntime.ArtifactSpec{},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if len(result.Artifacts) != 1 || result.Artifacts[0].Path != "out.csv" || string(result.Artifacts[0].Content) != "a,b" {
+		t.Errorf("Artifacts = %+v, want out.csv", result.Artifacts)
+	}
+}
+
+func TestBackendInputs(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{
+		Code:    `__out = __in["name"]`,
+		Gateway: &mockGateway{},
+		Inputs:  map[string]any{"name": "gopher"},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if result.Value != "gopher" {
+		t.Errorf("Value = %v, want gopher", result.Value)
+	}
+}
+
+func TestBackendStdin(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{
+		Code: `package main
+
+import (
+	"io"
+	"os"
+)
+
+func main() {
+	_, _ = io.Copy(os.Stdout, os.Stdin)
+}
+`,
+		Gateway: &mockGateway{},
+		Stdin:   []byte("from stdin"),
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if result.Stdout != "from stdin" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "from stdin")
+	}
+}
+
+func TestBackendEnv(t *testing.T) {
+	t.Setenv("TOOLRUNTIME_TEST_HOST_VAR", "leaked")
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{
+		Code: `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Print(os.Getenv("MODE"), ",", os.Getenv("API_KEY"), ",", os.Getenv("TOOLRUNTIME_TEST_HOST_VAR"))
+}
+`,
+		Gateway: &mockGateway{},
+		Env:     map[string]string{"MODE": "fast"},
+		Secrets: map[string]string{"API_KEY": "s3cret"},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if result.Stdout != "fast,s3cret," {
+		t.Errorf("Stdout = %q, want request env set and host env not inherited", result.Stdout)
+	}
+}
+
+func TestBackendOutputLimits(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{
+		Code: `for i := 0; i < 1000; i++ {
+		fmt.Println("line", i)
+	}
+	__out = "done"`,
+		Gateway:     &mockGateway{},
+		Limits:      toolruntime.Limits{MaxStdoutBytes: 64},
+		SpillOutput: true,
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	defer toolruntime.RemoveSpillFiles(result)
+
+	if !strings.HasPrefix(result.Stdout, "line 0\n") || !strings.Contains(result.Stdout, "[truncated: ") || len(result.Stdout) > 128 {
+		t.Errorf("Stdout = %q, want 64 bytes and a truncation marker", result.Stdout)
+	}
+	if result.Value != "done" {
+		t.Errorf("Value = %v, want done despite truncated stdout", result.Value)
+	}
+	data, err := os.ReadFile(result.StdoutFile)
+	if err != nil || !strings.Contains(string(data), "line 999\n") {
+		t.Errorf("spill file missing full output: %v", err)
+	}
+	if !result.LimitsEnforced.Output {
+		t.Error("LimitsEnforced.Output should be set")
+	}
+}
+
+func TestBackendResultEnvelope(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{
+		Code: `fmt.Println("__OUT__:\"spoofed\"")
+	__out = map[string]any{"n": 1}
+	__meta = map[string]any{"rows": 2}`,
+		Gateway: &mockGateway{},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if fmt.Sprint(result.Value) != "map[n:1]" || result.Metadata["rows"] != 2.0 {
+		t.Errorf("Value = %v, Metadata = %v; want values from the envelope", result.Value, result.Metadata)
+	}
+	if result.Stdout != "__OUT__:\"spoofed\"\n" {
+		t.Errorf("Stdout = %q, want the envelope stripped", result.Stdout)
+	}
+
+	for code, wantType := range map[string]string{
+		`__err = fmt.Errorf("bad row")`: "",
+		`panic("boom")`:                 "panic",
+	} {
+		req.Code = code
+		_, err = b.Execute(context.Background(), req)
+		var resultErr *toolruntime.ResultError
+		if !errors.As(err, &resultErr) || resultErr.Type != wantType {
+			t.Errorf("Execute(%q) error = %v, want ResultError of type %q", code, err, wantType)
+		}
+	}
+}
+
+func TestBackendLanguages(t *testing.T) {
+	tests := []struct {
+		language string
+		code     string
+	}{
+		{"python", `print("computing")
+__out = {"n": __in["n"] * 2}`},
+		{"javascript", `console.log("computing");
+__out = { n: __in.n * 2 };`},
+		{"shell", `echo computing
+__out='{"n":4}'`},
+	}
+
+	b := New(Config{Mode: ModeSubprocess})
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			lang, _ := b.languages.Lookup(tt.language)
+			if _, err := exec.LookPath(lang.Command[0]); err != nil {
+				t.Skipf("%s not available", lang.Command[0])
+			}
+			result, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{
+				Language: tt.language,
+				Code:     tt.code,
+				Gateway:  &mockGateway{},
+				Inputs:   map[string]any{"n": 2},
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if fmt.Sprint(result.Value) != "map[n:4]" || result.Stdout != "computing\n" {
+				t.Errorf("Value = %v, Stdout = %q; want map[n:4] and the printed line", result.Value, result.Stdout)
+			}
+		})
+	}
+}
+
+func TestBackendPythonSysExit(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+	b := New(Config{Mode: ModeSubprocess})
+	req := toolruntime.ExecuteRequest{Language: "python", Gateway: &mockGateway{}}
+
+	// The value set before sys.exit(0) is still reported
+	req.Code = "import sys\n__out = 42\nsys.exit(0)"
+	result, err := b.Execute(context.Background(), req)
+	if err != nil || fmt.Sprint(result.Value) != "42" {
+		t.Errorf("Execute() = %v, %v; want 42", result.Value, err)
+	}
+
+	// A non-zero exit status is reported as an error
+	req.Code = "import sys\nsys.exit(3)"
+	_, err = b.Execute(context.Background(), req)
+	var resultErr *toolruntime.ResultError
+	if !errors.As(err, &resultErr) || resultErr.Type != "exit" {
+		t.Errorf("Execute() error = %v, want ResultError of type exit", err)
+	}
+}
+
+func TestBackendRejectsUnknownLanguage(t *testing.T) {
+	b := New(Config{})
+	_, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{
+		Language: "cobol",
+		Code:     "DISPLAY 'x'.",
+		Gateway:  &mockGateway{},
+	})
+	if !errors.Is(err, toolruntime.ErrInvalidRequest) {
+		t.Errorf("Execute() error = %v, want ErrInvalidRequest", err)
+	}
+	if caps := b.Capabilities(); !slices.Contains(caps.Languages, "python") || slices.Contains(caps.Languages, "cobol") {
+		t.Errorf("Capabilities().Languages = %v, want the registered languages", caps.Languages)
+	}
+}
+
+func TestBackendExecuteStreamRequiresOptIn(t *testing.T) {
+	b := New(Config{RequireOptIn: true})
+
+	_, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{
+		Code:    `__out = "hello"`,
+		Gateway: &mockGateway{},
+	})
+	if !errors.Is(err, ErrOptInRequired) {
+		t.Errorf("ExecuteStream() without opt-in error = %v, want %v", err, ErrOptInRequired)
+	}
+}
+
 func TestBackendModeSelection(t *testing.T) {
 	tests := []struct {
 		mode ExecutionMode
@@ -12,7 +12,8 @@ import "context"
 //   - Compile/load the WASM module
 //   - Configure memory limits and WASI
 //   - Execute the module with provided input
-//   - Capture stdout/stderr
+//   - Capture stdout/stderr unmodified, including the result envelope
+//     framed with the nonce in spec.Env (see toolruntime.ResultEnvelope)
 //   - Respect context cancellation and spec timeout
 type Runner interface {
 	// Run executes code in a WASM sandbox and returns the result.
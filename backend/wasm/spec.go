@@ -8,6 +8,10 @@ type Spec struct {
 	// This can be raw .wasm bytes or a precompiled module reference.
 	Module []byte
 
+	// Source is the request's code wrapped for its language, for runners
+	// that compile it to a module when Module is empty.
+	Source []byte
+
 	// EntryPoint is the exported function to call (default: "_start" for WASI).
 	EntryPoint string
 
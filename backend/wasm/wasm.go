@@ -6,7 +6,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"time"
 
 	"github.com/jonwraymond/toolruntime"
@@ -68,8 +70,23 @@ type Config struct {
 	// AllowedHostFunctions lists host functions the WASM module can call.
 	AllowedHostFunctions []string
 
+	// Languages are the languages the Runner compiles to WASM. The code is
+	// wrapped for the resolved language and passed in Spec.Source, and the
+	// language name in the toolruntime.language label; requests for other
+	// languages fail with toolruntime.ErrInvalidRequest.
+	// Default: Go only
+	Languages *toolruntime.LanguageRegistry
+
+	// WorkspaceDir is the host directory in which a temporary workspace is
+	// created for each request with files, and a temporary output directory
+	// for each request with artifacts. They are preopened for WASI at
+	// /workspace and /output and removed after execution.
+	// Default: os.TempDir()
+	WorkspaceDir string
+
 	// Client is the WASM runner implementation.
 	// If nil, Execute() returns ErrClientNotConfigured.
+	// If it implements io.Closer, Close closes it.
 	Client Runner
 
 	// ModuleLoader optionally pre-compiles modules.
@@ -80,6 +97,11 @@ type Config struct {
 	// If nil, health checks are skipped.
 	HealthChecker HealthChecker
 
+	// SkipExecuteHealthCheck disables the HealthChecker ping before each
+	// execution. Set it when a toolruntime.HealthMonitor probes the backend
+	// through HealthCheck instead.
+	SkipExecuteHealthCheck bool
+
 	// Logger is an optional logger for backend events.
 	Logger Logger
 }
@@ -90,9 +112,12 @@ type Backend struct {
 	maxMemoryPages       int
 	enableWASI           bool
 	allowedHostFunctions []string
+	languages            *toolruntime.LanguageRegistry
+	workspaceDir         string
 	client               Runner
 	moduleLoader         ModuleLoader
 	healthChecker        HealthChecker
+	skipPing             bool
 	logger               Logger
 }
 
@@ -108,14 +133,25 @@ func New(cfg Config) *Backend {
 		maxMemoryPages = 256 // 16MB
 	}
 
+	languages := cfg.Languages
+	if languages == nil {
+		languages = toolruntime.NewLanguageRegistry()
+		if lang, ok := toolruntime.DefaultLanguages().Lookup("go"); ok {
+			_ = languages.Register(lang)
+		}
+	}
+
 	return &Backend{
 		runtime:              runtime,
 		maxMemoryPages:       maxMemoryPages,
 		enableWASI:           cfg.EnableWASI,
 		allowedHostFunctions: cfg.AllowedHostFunctions,
+		languages:            languages,
+		workspaceDir:         cfg.WorkspaceDir,
 		client:               cfg.Client,
 		moduleLoader:         cfg.ModuleLoader,
 		healthChecker:        cfg.HealthChecker,
+		skipPing:             cfg.SkipExecuteHealthCheck,
 		logger:               cfg.Logger,
 	}
 }
@@ -125,77 +161,349 @@ func (b *Backend) Kind() toolruntime.BackendKind {
 	return toolruntime.BackendWASM
 }
 
+// Capabilities describes the WASM backend. Modules never have network
+// access and only see the filesystem through explicit WASI mounts.
+func (b *Backend) Capabilities() toolruntime.Capabilities {
+	_, streaming := b.client.(StreamRunner)
+	return toolruntime.Capabilities{
+		Languages: b.languages.Names(),
+		Limits: toolruntime.LimitsEnforced{
+			Timeout:    true,
+			Memory:     true,
+			ToolCalls:  true, // Enforced by gateway
+			ChainSteps: true, // Enforced by gateway
+			Output:     true,
+		},
+		Isolation: toolruntime.BackendWASM.Isolation(),
+		Streaming: streaming,
+		Files:     true,
+		Artifacts: true,
+	}
+}
+
+// HealthCheck pings the WASM runtime through the configured HealthChecker.
+// It returns nil if no HealthChecker is configured. It implements
+// toolruntime.HealthProber.
+func (b *Backend) HealthCheck(ctx context.Context) error {
+	if b.healthChecker == nil {
+		return nil
+	}
+	_, span := toolruntime.StartSpan(ctx, "wasm.health_check")
+	err := b.healthChecker.Ping(ctx)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return runtimeError(opHealthCheck, fmt.Errorf("%w: %w: %v", ErrWASMRuntimeNotAvailable, toolruntime.ErrRuntimeUnavailable, err))
+	}
+	return nil
+}
+
+// Close releases the ModuleLoader's cached modules and closes the Client if
+// it implements io.Closer. It implements toolruntime.ClosableBackend.
+func (b *Backend) Close(ctx context.Context) error {
+	var errs []error
+	if b.moduleLoader != nil {
+		if err := b.moduleLoader.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("close module loader: %w", err))
+		}
+	}
+	if closer, ok := b.client.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close client: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Execute runs code compiled to WebAssembly.
 func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (toolruntime.ExecuteResult, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
-		return toolruntime.ExecuteResult{}, err
+		return toolruntime.ExecuteResult{}, runtimeError(opValidate, err)
 	}
 
 	// Check client is configured
 	if b.client == nil {
-		return toolruntime.ExecuteResult{}, ErrClientNotConfigured
+		return toolruntime.ExecuteResult{}, errClientNotConfigured()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout(req))
+	defer cancel()
+
+	start := time.Now()
+
+	capture := toolruntime.NewOutputCapture(req)
+	spec, profile, cleanup, err := b.prepare(ctx, req, capture.Nonce())
+	if err != nil {
+		return toolruntime.ExecuteResult{}, err
+	}
+	defer cleanup()
+
+	// Execute via client
+	runCtx, span := toolruntime.StartSpan(ctx, "wasm.module_run", toolruntime.Attr("wasm.runtime", b.runtime))
+	wasmResult, err := b.client.Run(runCtx, spec)
+	span.SetAttributes(toolruntime.Attr("wasm.exit_code", wasmResult.ExitCode))
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return toolruntime.ExecuteResult{
+			Duration: time.Since(start),
+			Backend:  b.backendInfo(profile),
+		}, runtimeError(opModuleRun, err)
+	}
+
+	_, _ = io.WriteString(capture.Stdout, wasmResult.Stdout)
+	_, _ = io.WriteString(capture.Stderr, wasmResult.Stderr)
+	result, err := b.toResult(spec, profile, wasmResult, capture)
+	if err != nil {
+		return result, err
+	}
+	if err := b.collectArtifacts(ctx, req, spec, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// ExecuteStream runs code compiled to WebAssembly and streams its output.
+// If the configured client does not implement StreamRunner, the module is
+// run to completion and its output is emitted afterwards.
+func (b *Backend) ExecuteStream(ctx context.Context, req toolruntime.ExecuteRequest) (<-chan toolruntime.StreamEvent, error) {
+	if err := req.Validate(); err != nil {
+		return nil, runtimeError(opValidate, err)
+	}
+	if b.client == nil {
+		return nil, errClientNotConfigured()
 	}
 
-	// Apply timeout
-	timeout := req.Timeout
-	if timeout == 0 {
-		timeout = 30 * time.Second
+	streamer, ok := b.client.(StreamRunner)
+	if !ok {
+		result, err := b.Execute(ctx, req)
+		return toolruntime.StreamResult(result, err), nil
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout(req))
+
+	start := time.Now()
+
+	capture := toolruntime.NewOutputCapture(req)
+	spec, profile, cleanup, err := b.prepare(ctx, req, capture.Nonce())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	runCtx, span := toolruntime.StartSpan(ctx, "wasm.module_run", toolruntime.Attr("wasm.runtime", b.runtime))
+	events, err := streamer.RunStream(runCtx, spec)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		cleanup()
+		cancel()
+		return nil, runtimeError(opModuleRun, err)
+	}
+
+	out := make(chan toolruntime.StreamEvent, 16)
+	go func() {
+		defer cancel()
+		defer cleanup()
+		defer close(out)
+		defer span.End()
+
+		capture.WithStream(
+			toolruntime.StreamWriter(ctx, out, toolruntime.StreamEventStdout),
+			toolruntime.StreamWriter(ctx, out, toolruntime.StreamEventStderr),
+		)
+		send := func(ev toolruntime.StreamEvent) {
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+			}
+		}
+		partial := func() *toolruntime.ExecuteResult {
+			result := &toolruntime.ExecuteResult{
+				Duration: time.Since(start),
+				Backend:  b.backendInfo(profile),
+			}
+			_ = capture.Finish(result)
+			return result
+		}
+
+		for ev := range events {
+			switch ev.Type {
+			case StreamEventStdout:
+				_, _ = capture.Stdout.Write(ev.Data)
+			case StreamEventStderr:
+				_, _ = capture.Stderr.Write(ev.Data)
+			case StreamEventExit:
+				span.SetAttributes(toolruntime.Attr("wasm.exit_code", ev.ExitCode))
+				result, err := b.toResult(spec, profile, Result{
+					ExitCode: ev.ExitCode,
+					Duration: time.Since(start),
+				}, capture)
+				if err == nil {
+					err = b.collectArtifacts(ctx, req, spec, &result)
+				}
+				if err != nil {
+					span.RecordError(err)
+					send(toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: &result, Error: err})
+					return
+				}
+				send(toolruntime.StreamEvent{Type: toolruntime.StreamEventExit, Result: &result})
+				return
+			case StreamEventError:
+				err := runtimeError(opModuleRun, ev.Error)
+				span.RecordError(err)
+				send(toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: partial(), Error: err})
+				return
+			}
+		}
+
+		// The runner closed the stream without an exit event.
+		err := ctx.Err()
+		if err == nil {
+			err = runtimeError(opModuleRun, fmt.Errorf("%w: stream closed without exit event", ErrModuleExecutionFailed))
+		}
+		span.RecordError(err)
+		send(toolruntime.StreamEvent{Type: toolruntime.StreamEventError, Result: partial(), Error: err})
+	}()
+
+	return out, nil
+}
 
+// prepare performs the pre-execution steps shared by Execute and ExecuteStream:
+// language resolution, health check, spec construction, and workspace and output directory setup.
+// The returned cleanup function removes both once the module has finished and
+// its artifacts are collected. nonce frames the result envelope.
+func (b *Backend) prepare(ctx context.Context, req toolruntime.ExecuteRequest, nonce string) (Spec, toolruntime.SecurityProfile, func(), error) {
 	// Check context before proceeding
 	select {
 	case <-ctx.Done():
-		return toolruntime.ExecuteResult{}, ctx.Err()
+		return Spec{}, "", nil, ctx.Err()
 	default:
 	}
 
-	start := time.Now()
-
 	// Get security profile
 	profile := req.Profile
 	if profile == "" {
 		profile = toolruntime.ProfileStandard
 	}
 
+	lang, err := b.languages.Resolve(req.Language)
+	if err != nil {
+		return Spec{}, profile, nil, runtimeError(opValidate, err)
+	}
+
 	// Optional health check
-	if b.healthChecker != nil {
-		if err := b.healthChecker.Ping(ctx); err != nil {
-			return toolruntime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrWASMRuntimeNotAvailable, err)
+	if !b.skipPing {
+		if err := b.HealthCheck(ctx); err != nil {
+			return Spec{}, profile, nil, err
 		}
 	}
 
 	// Build WASM spec from request
-	spec := b.buildSpec(req, profile)
+	spec := b.buildSpec(req, profile, nonce)
+	spec.Source = []byte(lang.Wrap(req.Code))
+	spec.Labels["toolruntime.language"] = lang.Name
+
+	var dirs []string
+	cleanup := func() {
+		for _, dir := range dirs {
+			_ = os.RemoveAll(dir)
+		}
+	}
+
+	// Write request files into a workspace preopened for the module
+	if len(req.Files) > 0 {
+		dir, err := b.writeWorkspace(ctx, req)
+		if err != nil {
+			return Spec{}, profile, nil, err
+		}
+		dirs = append(dirs, dir)
+		spec.WorkingDir = workspaceGuestPath
+		spec.Mounts = append(spec.Mounts, Mount{HostPath: dir, GuestPath: workspaceGuestPath})
+	}
+
+	// Preopen an empty output directory for artifacts
+	if req.Artifacts != nil {
+		dir, err := os.MkdirTemp(b.workspaceDir, "toolruntime-wasm-out-*")
+		if err != nil {
+			cleanup()
+			return Spec{}, profile, nil, runtimeError(opWriteWorkspace, fmt.Errorf("create output dir: %w", err))
+		}
+		dirs = append(dirs, dir)
+		spec.Env = append(spec.Env, toolruntime.OutputDirEnv+"="+outputGuestPath)
+		spec.Mounts = append(spec.Mounts, Mount{HostPath: dir, GuestPath: outputGuestPath})
+	}
 
 	// Log execution
 	if b.logger != nil {
 		b.logger.Info("executing in WASM sandbox",
+			"executionID", req.ExecutionID,
 			"profile", profile,
 			"runtime", b.runtime,
+			"language", lang.Name,
 			"enableWASI", b.enableWASI,
 			"memoryPages", b.maxMemoryPages)
 	}
 
-	// Execute via client
-	wasmResult, err := b.client.Run(ctx, spec)
+	return spec, profile, cleanup, nil
+}
+
+// Guest paths of the request workspace and the artifact output directory.
+const (
+	workspaceGuestPath = "/workspace"
+	outputGuestPath    = "/output"
+)
+
+// writeWorkspace creates a temporary host directory holding the request files.
+func (b *Backend) writeWorkspace(ctx context.Context, req toolruntime.ExecuteRequest) (string, error) {
+	_, span := toolruntime.StartSpan(ctx, "wasm.write_workspace", toolruntime.Attr("wasm.files", len(req.Files)))
+	defer span.End()
+
+	dir, err := os.MkdirTemp(b.workspaceDir, "toolruntime-wasm-*")
 	if err != nil {
-		return toolruntime.ExecuteResult{
-			Duration: time.Since(start),
-			Backend:  b.backendInfo(profile),
-		}, err
+		span.RecordError(err)
+		return "", runtimeError(opWriteWorkspace, fmt.Errorf("create workspace: %w", err))
+	}
+	if err := toolruntime.WriteFiles(dir, req.Files); err != nil {
+		span.RecordError(err)
+		_ = os.RemoveAll(dir)
+		return "", runtimeError(opWriteWorkspace, err)
 	}
+	return dir, nil
+}
+
+// collectArtifacts reads the artifacts the module wrote to the output
+// directory into result, if the request asked for them.
+func (b *Backend) collectArtifacts(ctx context.Context, req toolruntime.ExecuteRequest, spec Spec, result *toolruntime.ExecuteResult) error {
+	if req.Artifacts == nil {
+		return nil
+	}
+	for _, m := range spec.Mounts {
+		if m.GuestPath != outputGuestPath {
+			continue
+		}
+		_, span := toolruntime.StartSpan(ctx, "wasm.collect_artifacts")
+		artifacts, err := toolruntime.CollectArtifacts(m.HostPath, *req.Artifacts)
+		span.SetAttributes(toolruntime.Attr("wasm.artifacts", len(artifacts)))
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			return runtimeError(opCollectArtifacts, err)
+		}
+		result.Artifacts = artifacts
+	}
+	return nil
+}
 
-	// Convert to ExecuteResult
-	return toolruntime.ExecuteResult{
-		Value:    extractOutValue(wasmResult.Stdout),
-		Stdout:   wasmResult.Stdout,
-		Stderr:   wasmResult.Stderr,
-		Duration: wasmResult.Duration,
-		Backend:  b.backendInfo(profile),
+// toResult converts a WASM Result, whose output was written to capture, to
+// an ExecuteResult. An error reported in the result envelope is returned as
+// a module_run error.
+func (b *Backend) toResult(spec Spec, profile toolruntime.SecurityProfile, wasmResult Result, capture *toolruntime.OutputCapture) (toolruntime.ExecuteResult, error) {
+	result := toolruntime.ExecuteResult{
+		ExecutionID: spec.Labels["toolruntime.execution_id"],
+		Duration:    wasmResult.Duration,
+		Backend:     b.backendInfo(profile),
 		LimitsEnforced: toolruntime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     spec.Resources.MemoryPages > 0,
@@ -204,11 +512,60 @@ func (b *Backend) Execute(ctx context.Context, req toolruntime.ExecuteRequest) (
 			ToolCalls:  true,                         // Enforced by gateway
 			ChainSteps: true,                         // Enforced by gateway
 		},
-	}, nil
+	}
+	if err := capture.Finish(&result); err != nil {
+		return result, runtimeError(opCaptureOutput, err)
+	}
+	if err := capture.Err(); err != nil {
+		return result, runtimeError(opModuleRun, err)
+	}
+	return result, nil
+}
+
+// errClientNotConfigured reports a missing client as an unavailable runtime
+// so that the runtime can fall back to another backend.
+func errClientNotConfigured() error {
+	return runtimeError(opConfigure, fmt.Errorf("%w: %w", ErrClientNotConfigured, toolruntime.ErrRuntimeUnavailable))
+}
+
+// Operations reported in toolruntime.RuntimeError.Op.
+const (
+	opConfigure        = "configure"
+	opValidate         = "validate"
+	opHealthCheck      = "health_check"
+	opWriteWorkspace   = "write_workspace"
+	opModuleRun        = "module_run"
+	opCollectArtifacts = "collect_artifacts"
+	opCaptureOutput    = "capture_output"
+)
+
+// runtimeError wraps err in a toolruntime.RuntimeError for op.
+func runtimeError(op string, err error) error {
+	return toolruntime.NewRuntimeError(toolruntime.BackendWASM, op, err, retryable(op, err))
+}
+
+// retryable reports whether err is a transient failure that happened before
+// the module started, so the execution can be retried safely.
+func retryable(op string, err error) bool {
+	switch op {
+	case opHealthCheck, opWriteWorkspace:
+		return true
+	case opModuleRun:
+		return errors.Is(err, ErrWASMRuntimeNotAvailable)
+	}
+	return false
+}
+
+// executionTimeout returns the request timeout or the default.
+func executionTimeout(req toolruntime.ExecuteRequest) time.Duration {
+	if req.Timeout == 0 {
+		return 30 * time.Second
+	}
+	return req.Timeout
 }
 
 // buildSpec creates a Spec from an ExecuteRequest.
-func (b *Backend) buildSpec(req toolruntime.ExecuteRequest, profile toolruntime.SecurityProfile) Spec {
+func (b *Backend) buildSpec(req toolruntime.ExecuteRequest, profile toolruntime.SecurityProfile, nonce string) Spec {
 	memoryPages := uint32(0)
 	if b.maxMemoryPages > 0 {
 		// #nosec G115 -- b.maxMemoryPages is clamped to uint32 below.
@@ -234,6 +591,19 @@ func (b *Backend) buildSpec(req toolruntime.ExecuteRequest, profile toolruntime.
 			"toolruntime.backend": string(toolruntime.BackendWASM),
 		},
 	}
+	if req.ExecutionID != "" {
+		spec.Labels["toolruntime.execution_id"] = req.ExecutionID
+	}
+
+	// Pass the environment, stdin, the JSON-encoded inputs and the nonce of
+	// the result envelope; req.Validate has already checked that the inputs
+	// encode
+	spec.Env = toolruntime.EnvList(req.Env, req.Secrets)
+	spec.Stdin = req.Stdin
+	inputs, _ := toolruntime.EncodeInputs(req.Inputs)
+	spec.Env = append(spec.Env,
+		toolruntime.InputsEnv+"="+string(inputs),
+		toolruntime.ResultNonceEnv+"="+nonce)
 
 	// Apply profile-specific settings
 	switch profile {
@@ -280,14 +650,6 @@ func (b *Backend) backendInfo(profile toolruntime.SecurityProfile) toolruntime.B
 	}
 }
 
-// extractOutValue extracts the __out value from stdout if present.
-// This follows the toolruntime convention for capturing return values.
-func extractOutValue(_ string) any {
-	// TODO: Implement __out extraction from stdout
-	// The gateway proxy will output JSON with __out key
-	return nil
-}
-
 func clampUint32(value uint64) uint32 {
 	if value > math.MaxUint32 {
 		return math.MaxUint32
@@ -296,4 +658,10 @@ func clampUint32(value uint64) uint32 {
 	return uint32(value)
 }
 
-var _ toolruntime.Backend = (*Backend)(nil)
+var (
+	_ toolruntime.Backend            = (*Backend)(nil)
+	_ toolruntime.StreamingBackend   = (*Backend)(nil)
+	_ toolruntime.CapabilityReporter = (*Backend)(nil)
+	_ toolruntime.HealthProber       = (*Backend)(nil)
+	_ toolruntime.ClosableBackend    = (*Backend)(nil)
+)
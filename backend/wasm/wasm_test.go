@@ -3,6 +3,11 @@ package wasm
 import (
 	"context"
 	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,6 +29,20 @@ func TestBackendKind(t *testing.T) {
 	}
 }
 
+func TestBackendCapabilities(t *testing.T) {
+	caps := New(Config{}).Capabilities()
+
+	if caps.Isolation != toolruntime.IsolationSandbox || caps.Network {
+		t.Errorf("Capabilities() = %+v, want sandbox isolation without network", caps)
+	}
+	if !caps.Limits.Memory || caps.Limits.Pids {
+		t.Errorf("Limits = %+v, want memory but not pids", caps.Limits)
+	}
+	if !caps.Files {
+		t.Error("Files = false, want true")
+	}
+}
+
 func TestBackendDefaults(t *testing.T) {
 	b := New(Config{})
 	if b.runtime != "wazero" {
@@ -113,6 +132,47 @@ func TestBackendHealthCheckFailure(t *testing.T) {
 	if !errors.Is(err, ErrWASMRuntimeNotAvailable) {
 		t.Errorf("Execute() with health check failure error = %v, want %v", err, ErrWASMRuntimeNotAvailable)
 	}
+	if !toolruntime.IsRetryable(err) {
+		t.Errorf("Execute() with health check failure error = %v, want retryable", err)
+	}
+}
+
+func TestBackendHealthCheck(t *testing.T) {
+	mockClient := &mockWasmRunner{}
+	b := New(Config{
+		Client:                 mockClient,
+		HealthChecker:          &mockHealthChecker{pingErr: errors.New("runtime not available")},
+		SkipExecuteHealthCheck: true,
+	})
+
+	err := b.HealthCheck(context.Background())
+	if !errors.Is(err, ErrWASMRuntimeNotAvailable) || !toolruntime.IsRetryable(err) {
+		t.Errorf("HealthCheck() error = %v, want retryable %v", err, ErrWASMRuntimeNotAvailable)
+	}
+
+	_, err = b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Errorf("Execute() error = %v, want health check skipped", err)
+	}
+}
+
+func TestBackendClose(t *testing.T) {
+	loaderErr := errors.New("close failed")
+	loader := &mockModuleLoader{closeErr: loaderErr}
+	client := &closingWasmRunner{}
+	b := New(Config{Client: client, ModuleLoader: loader})
+
+	err := b.Close(context.Background())
+	if !errors.Is(err, loaderErr) {
+		t.Errorf("Close() error = %v, want %v", err, loaderErr)
+	}
+	if loader.closed != 1 || client.closed != 1 {
+		t.Errorf("loader closed %d, client closed %d; want 1 and 1", loader.closed, client.closed)
+	}
+
+	if err := New(Config{}).Close(context.Background()); err != nil {
+		t.Errorf("Close() without loader or client error = %v", err)
+	}
 }
 
 func TestBackendContextCancellation(t *testing.T) {
@@ -137,6 +197,59 @@ func TestBackendContextCancellation(t *testing.T) {
 	}
 }
 
+func TestBackendExecuteStream(t *testing.T) {
+	runner := &mockWasmStreamRunner{
+		events: []StreamEvent{
+			{Type: StreamEventStdout, Data: []byte("hi")},
+			{Type: StreamEventStderr, Data: []byte("warn")},
+			{Type: StreamEventExit},
+		},
+	}
+	b := New(Config{Client: runner})
+
+	events, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	var got []toolruntime.StreamEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d events, want 3", len(got))
+	}
+	if got[1].Type != toolruntime.StreamEventStderr {
+		t.Errorf("got[1].Type = %v, want %v", got[1].Type, toolruntime.StreamEventStderr)
+	}
+	if got[2].Result == nil || got[2].Result.Stderr != "warn" {
+		t.Errorf("exit Result = %+v, want Stderr %q", got[2].Result, "warn")
+	}
+}
+
+func TestBackendExecuteStreamWithoutStreamRunner(t *testing.T) {
+	b := New(Config{Client: &mockWasmRunner{result: Result{Stdout: "buffered"}}})
+
+	events, err := b.ExecuteStream(context.Background(), toolruntime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	var got []toolruntime.StreamEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != 2 || string(got[0].Data) != "buffered" {
+		t.Errorf("events = %+v, want buffered stdout then exit", got)
+	}
+}
+
 func TestBackendBuildSpecProfiles(t *testing.T) {
 	b := New(Config{
 		EnableWASI:           true,
@@ -161,7 +274,7 @@ func TestBackendBuildSpecProfiles(t *testing.T) {
 				Code:    "test",
 				Gateway: &mockGateway{},
 			}
-			spec := b.buildSpec(req, tt.profile)
+			spec := b.buildSpec(req, tt.profile, "nonce")
 
 			if spec.Security.EnableNetwork != tt.wantNetwork {
 				t.Errorf("EnableNetwork = %v, want %v", spec.Security.EnableNetwork, tt.wantNetwork)
@@ -190,7 +303,7 @@ func TestBackendBuildSpecMemoryLimit(t *testing.T) {
 		},
 	}
 
-	spec := b.buildSpec(req, toolruntime.ProfileStandard)
+	spec := b.buildSpec(req, toolruntime.ProfileStandard, "nonce")
 
 	// 32MB / 64KB per page = 512 pages
 	expectedPages := uint32(512)
@@ -199,6 +312,170 @@ func TestBackendBuildSpecMemoryLimit(t *testing.T) {
 	}
 }
 
+func TestBackendWorkspace(t *testing.T) {
+	runner := &workspaceRunner{}
+	b := New(Config{Client: runner, WorkspaceDir: t.TempDir()})
+
+	req := toolruntime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+		Files: map[string]toolruntime.File{
+			"lib/helper.py": {Content: []byte("x = 1")},
+			"data.csv":      {Content: []byte("a,b"), Mode: 0o600},
+		},
+	}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if runner.spec.WorkingDir != "/workspace" || len(runner.spec.Mounts) != 1 || runner.spec.Mounts[0].GuestPath != "/workspace" {
+		t.Fatalf("spec = %+v, want workspace preopened at /workspace", runner.spec)
+	}
+	if runner.files["lib/helper.py"] != "x = 1" || runner.files["data.csv"] != "a,b" {
+		t.Errorf("workspace files = %v", runner.files)
+	}
+	if _, err := os.Stat(runner.spec.Mounts[0].HostPath); !os.IsNotExist(err) {
+		t.Errorf("workspace not removed after execution: %v", err)
+	}
+
+	// Requests without files get no workspace
+	runner.spec = Spec{}
+	req.Files = nil
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(runner.spec.Mounts) != 0 {
+		t.Errorf("Mounts = %+v, want none", runner.spec.Mounts)
+	}
+}
+
+func TestBackendArtifacts(t *testing.T) {
+	runner := &outputRunner{files: map[string]string{"report.csv": "a,b", "logs/run.txt": "ok"}}
+	b := New(Config{Client: runner, WorkspaceDir: t.TempDir()})
+	req := toolruntime.ExecuteRequest{
+		Code:      "test",
+		Gateway:   &mockGateway{},
+		Artifacts: &toolruntime.ArtifactSpec{},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(result.Artifacts) != 2 || result.Artifacts[0].Path != "logs/run.txt" || result.Artifacts[1].MIMEType != "text/csv; charset=utf-8" {
+		t.Errorf("Artifacts = %+v, want logs/run.txt and a CSV report", result.Artifacts)
+	}
+	if !slices.Contains(runner.spec.Env, toolruntime.OutputDirEnv+"=/output") {
+		t.Errorf("Env = %v, want output dir", runner.spec.Env)
+	}
+	if _, err := os.Stat(runner.dir); !os.IsNotExist(err) {
+		t.Errorf("output dir not removed after execution: %v", err)
+	}
+
+	req.Artifacts = &toolruntime.ArtifactSpec{MaxFileBytes: 2}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, toolruntime.ErrResourceLimit) {
+		t.Errorf("Execute() over file cap error = %v, want ErrResourceLimit", err)
+	}
+}
+
+func TestBackendStdinInputsAndEnv(t *testing.T) {
+	runner := &workspaceRunner{}
+	b := New(Config{Client: runner})
+	req := toolruntime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+		Stdin:   []byte("line\n"),
+		Inputs:  map[string]any{"n": 1},
+		Env:     map[string]string{"MODE": "fast"},
+		Secrets: map[string]string{"API_KEY": "s3cret"},
+	}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if string(runner.spec.Stdin) != "line\n" {
+		t.Errorf("Stdin = %q, want %q", runner.spec.Stdin, "line\n")
+	}
+	if !slices.Contains(runner.spec.Env, toolruntime.InputsEnv+`={"n":1}`) {
+		t.Errorf("Env = %v, want encoded inputs", runner.spec.Env)
+	}
+	if !slices.Contains(runner.spec.Env, "MODE=fast") || !slices.Contains(runner.spec.Env, "API_KEY=s3cret") {
+		t.Errorf("Env = %v, want request env and secrets", runner.spec.Env)
+	}
+}
+
+func TestBackendOutputLimits(t *testing.T) {
+	runner := &mockWasmRunner{result: Result{Stdout: strings.Repeat("x", 100)}}
+	b := New(Config{Client: runner})
+	req := toolruntime.ExecuteRequest{
+		Code:        "test",
+		Gateway:     &mockGateway{},
+		Limits:      toolruntime.Limits{MaxStdoutBytes: 10},
+		SpillOutput: true,
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	defer toolruntime.RemoveSpillFiles(result)
+
+	if result.Stdout != strings.Repeat("x", 10)+"\n[truncated: 90 bytes omitted]\n" {
+		t.Errorf("Stdout = %q", result.Stdout)
+	}
+	if data, err := os.ReadFile(result.StdoutFile); err != nil || len(data) != 100 {
+		t.Errorf("spill file = %d bytes, %v; want 100", len(data), err)
+	}
+}
+
+func TestBackendResultEnvelope(t *testing.T) {
+	runner := &envelopeRunner{stdout: "hello\n", env: toolruntime.ResultEnvelope{Value: "ok", Metadata: map[string]any{"lang": "go"}}}
+	b := New(Config{Client: runner})
+
+	result, err := b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "hello\n" || result.Value != "ok" || result.Metadata["lang"] != "go" {
+		t.Errorf("result = %+v, want envelope decoded and stripped", result)
+	}
+
+	runner.env = toolruntime.ResultEnvelope{Error: &toolruntime.ResultError{Type: "panic", Message: "boom"}}
+	_, err = b.Execute(context.Background(), toolruntime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, toolruntime.ErrCodeFailed) {
+		t.Errorf("Execute() error = %v, want ErrCodeFailed", err)
+	}
+}
+
+func TestBackendLanguages(t *testing.T) {
+	runner := &workspaceRunner{}
+	b := New(Config{Client: runner})
+
+	req := toolruntime.ExecuteRequest{Code: "test", Language: "golang", Gateway: &mockGateway{}}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if runner.spec.Labels["toolruntime.language"] != "go" {
+		t.Errorf("Labels = %v, want toolruntime.language=go", runner.spec.Labels)
+	}
+	if want := toolruntime.ResultNonceEnv; !strings.Contains(string(runner.spec.Source), want) {
+		t.Errorf("Source = %q, want the wrapped program", runner.spec.Source)
+	}
+	if got := b.Capabilities().Languages; !slices.Equal(got, []string{"go"}) {
+		t.Errorf("Capabilities().Languages = %v, want [go]", got)
+	}
+
+	// Languages the runner does not compile are rejected
+	for _, language := range []string{"python", "cobol"} {
+		runner.spec = Spec{}
+		req.Language = language
+		_, err := b.Execute(context.Background(), req)
+		if !errors.Is(err, toolruntime.ErrInvalidRequest) || runner.spec.Labels != nil {
+			t.Errorf("Execute() with %s error = %v, want ErrInvalidRequest", language, err)
+		}
+	}
+}
+
 // Mock implementations
 
 type mockGateway struct{}
@@ -247,6 +524,115 @@ func (m *mockWasmRunner) Run(ctx context.Context, _ Spec) (Result, error) {
 	return m.result, m.err
 }
 
+// envelopeRunner writes stdout followed by a result envelope framed with the
+// nonce passed in the spec
+type envelopeRunner struct {
+	stdout string
+	env    toolruntime.ResultEnvelope
+}
+
+func (r *envelopeRunner) Run(_ context.Context, spec Spec) (Result, error) {
+	var nonce string
+	for _, e := range spec.Env {
+		if v, ok := strings.CutPrefix(e, toolruntime.ResultNonceEnv+"="); ok {
+			nonce = v
+		}
+	}
+	line, err := toolruntime.EncodeResultEnvelope(nonce, r.env)
+	return Result{Stdout: r.stdout + string(line)}, err
+}
+
+// workspaceRunner captures the spec and the workspace files it mounts
+type workspaceRunner struct {
+	spec  Spec
+	files map[string]string
+}
+
+func (w *workspaceRunner) Run(_ context.Context, spec Spec) (Result, error) {
+	w.spec = spec
+	w.files = make(map[string]string)
+	for _, m := range spec.Mounts {
+		err := filepath.WalkDir(m.HostPath, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			rel, _ := filepath.Rel(m.HostPath, p)
+			w.files[filepath.ToSlash(rel)] = string(data)
+			return err
+		})
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	return Result{}, nil
+}
+
+// outputRunner writes files into the preopened output directory
+type outputRunner struct {
+	files map[string]string
+	spec  Spec
+	dir   string
+}
+
+func (o *outputRunner) Run(_ context.Context, spec Spec) (Result, error) {
+	o.spec = spec
+	for _, m := range spec.Mounts {
+		if m.GuestPath != "/output" {
+			continue
+		}
+		o.dir = m.HostPath
+		for name, content := range o.files {
+			p := filepath.Join(m.HostPath, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return Result{}, err
+			}
+			if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+	return Result{}, nil
+}
+
+type mockWasmStreamRunner struct {
+	mockWasmRunner
+	events []StreamEvent
+}
+
+func (m *mockWasmStreamRunner) RunStream(_ context.Context, _ Spec) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent, len(m.events))
+	for _, ev := range m.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+type closingWasmRunner struct {
+	mockWasmRunner
+	closed int
+}
+
+func (c *closingWasmRunner) Close() error {
+	c.closed++
+	return nil
+}
+
+type mockModuleLoader struct {
+	closeErr error
+	closed   int
+}
+
+func (m *mockModuleLoader) Load(_ context.Context, _ []byte) (CompiledModule, error) {
+	return nil, nil
+}
+
+func (m *mockModuleLoader) Close(_ context.Context) error {
+	m.closed++
+	return m.closeErr
+}
+
 type mockHealthChecker struct {
 	pingErr error
 	info    RuntimeInfo
@@ -263,6 +649,7 @@ func (m *mockHealthChecker) Info(_ context.Context) (RuntimeInfo, error) {
 
 // Interface compliance checks
 var (
-	_ Runner    = (*mockWasmRunner)(nil)
+	_ Runner        = (*mockWasmRunner)(nil)
+	_ StreamRunner  = (*mockWasmStreamRunner)(nil)
 	_ HealthChecker = (*mockHealthChecker)(nil)
 )
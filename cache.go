@@ -0,0 +1,296 @@
+package toolruntime
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Defaults for CacheConfig.
+const (
+	defaultCacheTTL        = 5 * time.Minute
+	defaultCacheMaxEntries = 1024
+)
+
+// CacheConfig configures a ResultCache.
+type CacheConfig struct {
+	// TTL is how long a cached result is served.
+	// Default: 5 minutes
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached results. The least recently
+	// used entry is evicted when the cache is full.
+	// Default: 1024
+	MaxEntries int
+
+	// PureTools lists tool IDs known to have no side effects. Executions
+	// that called only these tools are cached; executions that called any
+	// other tool never are. Calls are observed through the request's
+	// gateway, so gateways need not record them.
+	PureTools []string
+
+	// GatewayKey optionally returns a stable identity for the gateway of a
+	// request, such as the name of the tool backend it serves, so that
+	// results obtained through one gateway are not served for another.
+	// Default: gateways are not part of the key
+	GatewayKey func(ToolGateway) string
+
+	// Now returns the current time. Default: time.Now
+	Now func() time.Time
+}
+
+// ResultCache memoizes successful executions of identical requests.
+// Requests are keyed by a hash of code, language, files, stdin, inputs,
+// environment, artifact caps, profile, timeout, limits, metadata (including
+// the tenant) and CacheConfig.GatewayKey, so results are never shared
+// between tenants. Requests whose inputs or metadata cannot be encoded are
+// never cached. Only executions without side-effecting tool calls are
+// cached. Install it with Interceptor; cache hits are marked with
+// BackendInfo.Details["cached"] = true.
+type ResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+	pureTools  map[string]bool
+	gatewayKey func(ToolGateway) string
+	now        func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+// cacheEntry is a cached execution result.
+type cacheEntry struct {
+	key     string
+	result  ExecuteResult
+	expires time.Time
+}
+
+// NewResultCache creates an empty ResultCache.
+func NewResultCache(cfg CacheConfig) *ResultCache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultCacheTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultCacheMaxEntries
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	pure := make(map[string]bool, len(cfg.PureTools))
+	for _, id := range cfg.PureTools {
+		pure[id] = true
+	}
+	return &ResultCache{
+		ttl:        cfg.TTL,
+		maxEntries: cfg.MaxEntries,
+		pureTools:  pure,
+		gatewayKey: cfg.GatewayKey,
+		now:        cfg.Now,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Interceptor returns an Interceptor that serves cached results and caches
+// eligible executions. When installed in a DefaultRuntime, a cache hit is
+// returned only after the request passes validation, the tenant's quota
+// check and the routing policy, but it does not reach the backend or
+// admission control and is not charged to the quota. With WrapRuntime, hits
+// skip all of these.
+func (c *ResultCache) Interceptor() Interceptor {
+	return func(ctx context.Context, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error) {
+		key, err := c.key(req)
+		if err != nil {
+			return next(ctx, req)
+		}
+		if result, ok := c.get(key); ok {
+			if err := preflight(ctx, req); err != nil {
+				return ExecuteResult{}, err
+			}
+			_, span := StartSpan(ctx, "toolruntime.cache_hit")
+			span.End()
+			result = cachedResult(result, req.ExecutionID)
+			replayStream(ctx, result)
+			return result, nil
+		}
+
+		gateway := &observingGateway{ToolGateway: req.Gateway}
+		if req.Gateway != nil {
+			req.Gateway = gateway
+		}
+		result, err := next(ctx, req)
+		if err == nil && c.cacheable(result, gateway.observed()) {
+			stored := cloneResult(result)
+			stampExecutionID(&stored, req.ExecutionID)
+			c.put(key, stored)
+		}
+		return result, err
+	}
+}
+
+// Len returns the number of cached results, including expired ones that
+// have not been evicted yet.
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes all cached results.
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// cacheable reports whether result made only side-effect-free tool calls,
+// judged by the calls observed through the gateway. A result reporting more
+// tool calls than were observed made calls that bypassed the gateway and is
+// not cached. Results with spill files are not cached either, since the
+// caller removes them.
+func (c *ResultCache) cacheable(result ExecuteResult, observed []string) bool {
+	if result.StdoutFile != "" || result.StderrFile != "" {
+		return false
+	}
+	if len(result.ToolCalls) > len(observed) {
+		return false
+	}
+	for _, id := range observed {
+		if !c.pureTools[id] {
+			return false
+		}
+	}
+	for _, call := range result.ToolCalls {
+		if !c.pureTools[call.ToolID] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ResultCache) get(key string) (ExecuteResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return ExecuteResult{}, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return ExecuteResult{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.result, true
+}
+
+func (c *ResultCache) put(key string, result ExecuteResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cacheEntry{key: key, result: result, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// key returns the cache key of req, identifying its gateway with GatewayKey.
+func (c *ResultCache) key(req ExecuteRequest) (string, error) {
+	var gateway string
+	if c.gatewayKey != nil && req.Gateway != nil {
+		gateway = c.gatewayKey(req.Gateway)
+	}
+	return cacheKey(req, gateway)
+}
+
+// cacheKey hashes the request fields that determine an execution's result.
+// It fails if the inputs or metadata cannot be encoded.
+func cacheKey(req ExecuteRequest, gateway string) (string, error) {
+	data, err := json.Marshal(struct {
+		Language  string
+		Code      string
+		Files     map[string]File
+		Stdin     []byte
+		Inputs    map[string]any
+		Env       map[string]string
+		Secrets   map[string]string
+		Artifacts *ArtifactSpec
+		Profile   SecurityProfile
+		Timeout   time.Duration
+		Limits    Limits
+		Spill     bool
+		Metadata  map[string]any
+		Gateway   string
+	}{req.Language, req.Code, req.Files, req.Stdin, req.Inputs, req.Env, req.Secrets, req.Artifacts, req.Profile, req.Timeout, req.Limits, req.SpillOutput,
+		req.Metadata, gateway})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cloneResult copies the tool calls, artifacts and details of result so the
+// cached entry is not shared with the caller.
+func cloneResult(result ExecuteResult) ExecuteResult {
+	result.ToolCalls = append([]ToolCallRecord(nil), result.ToolCalls...)
+	result.Artifacts = append([]Artifact(nil), result.Artifacts...)
+	for i := range result.Artifacts {
+		result.Artifacts[i].Content = bytes.Clone(result.Artifacts[i].Content)
+	}
+	details := make(map[string]any, len(result.Backend.Details))
+	for k, v := range result.Backend.Details {
+		details[k] = v
+	}
+	result.Backend.Details = details
+	result.Metadata = maps.Clone(result.Metadata)
+	return result
+}
+
+// cachedResult returns a copy of a cached result stamped with the current
+// execution ID and marked as cached.
+func cachedResult(result ExecuteResult, executionID string) ExecuteResult {
+	result = cloneResult(result)
+	result.Backend.Details["cached"] = true
+	result.Backend.Details["cachedExecutionID"] = result.ExecutionID
+
+	result.ExecutionID = executionID
+	for i := range result.ToolCalls {
+		result.ToolCalls[i].ExecutionID = executionID
+	}
+	result.QueueWait = 0
+	return result
+}
+
+// replayStream emits a cached result's tool calls and output when the
+// execution is streaming. The runtime emits the final event.
+func replayStream(ctx context.Context, result ExecuteResult) {
+	emitter := streamEmitterFrom(ctx)
+	if emitter == nil {
+		return
+	}
+	for i := range result.ToolCalls {
+		emitter.emit(StreamEvent{Type: StreamEventToolCall, ToolCall: &result.ToolCalls[i]})
+	}
+	for _, ev := range bufferedEvents(result, nil) {
+		if ev.Type != StreamEventExit {
+			emitter.emit(ev)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newCachedRuntime(backend Backend, cache *ResultCache) *DefaultRuntime {
+	return NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+		Interceptors:   []Interceptor{cache.Interceptor()},
+		DefaultProfile: ProfileDev,
+	})
+}
+
+func TestResultCacheHit(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: 42, Stdout: "out"}}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+	ctx := context.Background()
+	req := ExecuteRequest{Language: "go", Code: "test", Gateway: &mockToolGateway{}}
+
+	first, err := rt.Execute(ctx, req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if first.Backend.Details["cached"] != nil {
+		t.Errorf("first Execute() marked as cached: %v", first.Backend.Details)
+	}
+
+	second, err := rt.Execute(ctx, req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if backend.calls != 1 {
+		t.Errorf("backend calls = %d, want 1", backend.calls)
+	}
+	if second.Value != 42 || second.Stdout != "out" {
+		t.Errorf("cached result = %+v, want original value and stdout", second)
+	}
+	if second.Backend.Details["cached"] != true {
+		t.Errorf("Details[cached] = %v, want true", second.Backend.Details["cached"])
+	}
+	if second.Backend.Details["cachedExecutionID"] != first.ExecutionID {
+		t.Errorf("Details[cachedExecutionID] = %v, want %q", second.Backend.Details["cachedExecutionID"], first.ExecutionID)
+	}
+	if second.ExecutionID == first.ExecutionID {
+		t.Error("cached result should carry the new execution ID")
+	}
+}
+
+func TestResultCacheKey(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+	ctx := context.Background()
+
+	for _, req := range []ExecuteRequest{
+		{Code: "a", Gateway: &mockToolGateway{}},
+		{Code: "b", Gateway: &mockToolGateway{}},
+		{Code: "a", Language: "python", Gateway: &mockToolGateway{}},
+		{Code: "a", Profile: ProfileDev, Gateway: &mockToolGateway{}},
+		{Code: "a", Limits: Limits{MaxToolCalls: 1}, Gateway: &mockToolGateway{}},
+		{Code: "a", Files: map[string]File{"data.txt": {Content: []byte("1")}}, Gateway: &mockToolGateway{}},
+		{Code: "a", Stdin: []byte("1"), Gateway: &mockToolGateway{}},
+		{Code: "a", Inputs: map[string]any{"n": 1}, Gateway: &mockToolGateway{}},
+	} {
+		if _, err := rt.Execute(ctx, req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if backend.calls != 8 {
+		t.Errorf("backend calls = %d, want 8 distinct keys", backend.calls)
+	}
+}
+
+// toolBackend runs its tools through the request's gateway without
+// reporting them in the result, like a gateway that does not record calls.
+type toolBackend struct {
+	countingBackend
+	tools    []string
+	reported []ToolCallRecord
+}
+
+func (b *toolBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	for _, id := range b.tools {
+		_, _ = req.Gateway.RunTool(ctx, id, nil)
+	}
+	result, err := b.countingBackend.Execute(ctx, req)
+	result.ToolCalls = b.reported
+	return result, err
+}
+
+func TestResultCacheSideEffects(t *testing.T) {
+	tests := []struct {
+		name      string
+		tools     []string
+		reported  []ToolCallRecord
+		wantCalls int
+	}{
+		{"side effect", []string{"ns:read", "ns:write"}, nil, 2},
+		{"pure tool", []string{"ns:read"}, nil, 1},
+		{"unobserved call", nil, []ToolCallRecord{{ToolID: "ns:read"}}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &toolBackend{
+				countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}},
+				tools:           tt.tools,
+				reported:        tt.reported,
+			}
+			rt := newCachedRuntime(backend, NewResultCache(CacheConfig{PureTools: []string{"ns:read"}}))
+			req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+			_, _ = rt.Execute(context.Background(), req)
+			_, _ = rt.Execute(context.Background(), req)
+			if backend.calls != tt.wantCalls {
+				t.Errorf("backend calls = %d, want %d", backend.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// namedGateway is a gateway with its own identity.
+type namedGateway struct {
+	mockToolGateway
+	name string
+}
+
+func TestResultCacheUnencodableRequest(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+
+	// Requests whose metadata cannot be encoded are never cached, so they
+	// cannot collide with each other
+	for _, v := range []any{func() {}, make(chan int)} {
+		req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Metadata: map[string]any{"v": v}}
+		if _, err := rt.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if backend.calls != 2 {
+		t.Errorf("backend calls = %d, want 2", backend.calls)
+	}
+}
+
+func TestResultCacheCopiesArtifacts(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{
+		kind:   BackendUnsafeHost,
+		result: ExecuteResult{Artifacts: []Artifact{{Path: "out.txt", Content: []byte("data")}}},
+	}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	// Changing the artifacts of a result does not change the cached entry
+	for range 3 {
+		result, err := rt.Execute(context.Background(), req)
+		if err != nil || len(result.Artifacts) != 1 {
+			t.Fatalf("Execute() = %+v, %v; want one artifact", result.Artifacts, err)
+		}
+		if got := string(result.Artifacts[0].Content); got != "data" {
+			t.Errorf("artifact content = %q, want %q", got, "data")
+		}
+		copy(result.Artifacts[0].Content, "XXXX")
+	}
+	if backend.calls != 1 {
+		t.Errorf("backend calls = %d, want 1", backend.calls)
+	}
+}
+
+func TestResultCacheTenantIsolation(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendDocker}}
+	cache := NewResultCache(CacheConfig{GatewayKey: func(gw ToolGateway) string { return gw.(*namedGateway).name }})
+	quotas := NewQuotaManager(QuotaConfig{Tenants: map[string]Quota{"b": {MaxExecutions: 1}}})
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		Interceptors:   []Interceptor{cache.Interceptor()},
+		Quotas:         quotas,
+		DefaultProfile: ProfileStandard,
+	})
+	ctx := context.Background()
+	gw := &namedGateway{name: "a"}
+	reqA := ExecuteRequest{Code: "test", Gateway: gw, Metadata: map[string]any{"tenant": "a"}}
+
+	// Another tenant or gateway misses the cache
+	for _, req := range []ExecuteRequest{
+		reqA,
+		{Code: "test", Gateway: gw, Metadata: map[string]any{"tenant": "b"}},
+		{Code: "test", Gateway: &namedGateway{name: "other"}, Metadata: map[string]any{"tenant": "a"}},
+	} {
+		if _, err := rt.Execute(ctx, req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if backend.calls != 3 {
+		t.Errorf("backend calls = %d, want 3 distinct keys", backend.calls)
+	}
+
+	// A tenant that exhausted its quota gets no cache hit
+	_, err := rt.Execute(ctx, ExecuteRequest{Code: "test", Gateway: gw, Metadata: map[string]any{"tenant": "b"}})
+	if !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("Execute() for exhausted tenant error = %v, want ErrResourceLimit", err)
+	}
+
+	// A request the policy denies gets no cache hit
+	rt.UnregisterBackend(ProfileStandard)
+	_, err = rt.Execute(ctx, reqA)
+	if !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("Execute() without backend error = %v, want ErrRuntimeUnavailable", err)
+	}
+	if backend.calls != 3 {
+		t.Errorf("backend calls = %d, want hits served without the backend", backend.calls)
+	}
+}
+
+func TestResultCacheErrorsNotCached(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost, executeErr: ErrSandboxViolation}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	_, _ = rt.Execute(context.Background(), req)
+	_, _ = rt.Execute(context.Background(), req)
+	if backend.calls != 2 {
+		t.Errorf("backend calls = %d, want 2", backend.calls)
+	}
+}
+
+func TestResultCacheTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+	}))
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	_, _ = rt.Execute(context.Background(), req)
+	now = now.Add(30 * time.Second)
+	_, _ = rt.Execute(context.Background(), req)
+	if backend.calls != 1 {
+		t.Errorf("backend calls before TTL = %d, want 1", backend.calls)
+	}
+
+	now = now.Add(time.Minute)
+	_, _ = rt.Execute(context.Background(), req)
+	if backend.calls != 2 {
+		t.Errorf("backend calls after TTL = %d, want 2", backend.calls)
+	}
+}
+
+func TestResultCacheMaxEntries(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}}
+	cache := NewResultCache(CacheConfig{MaxEntries: 2})
+	rt := newCachedRuntime(backend, cache)
+	ctx := context.Background()
+
+	for _, code := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, _ = rt.Execute(ctx, ExecuteRequest{Code: code, Gateway: &mockToolGateway{}})
+	}
+	// a, b miss; a hit; c miss evicts b; a hit; b miss evicts c
+	if backend.calls != 4 {
+		t.Errorf("backend calls = %d, want 4", backend.calls)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Len() after Purge = %d, want 0", cache.Len())
+	}
+}
+
+func TestResultCacheStream(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Stdout: "hello"}}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	if _, err := rt.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	ch, err := rt.ExecuteStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	if len(events) != 2 || events[0].Type != StreamEventStdout || string(events[0].Data) != "hello" {
+		t.Fatalf("events = %+v, want stdout then exit", events)
+	}
+	if last := events[1]; last.Type != StreamEventExit || last.Result.Backend.Details["cached"] != true {
+		t.Errorf("final event = %+v, want cached exit", last)
+	}
+}
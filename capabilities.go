@@ -0,0 +1,89 @@
+package toolruntime
+
+// Capabilities describes what a backend supports before it runs anything.
+type Capabilities struct {
+	// Languages lists the supported languages.
+	// If empty, the supported languages are not known up-front.
+	Languages []string
+
+	// Limits reports which limits the backend can enforce when requested.
+	Limits LimitsEnforced
+
+	// Isolation is the isolation the backend provides.
+	Isolation IsolationLevel
+
+	// Network reports whether executed code has network access by default.
+	Network bool
+
+	// WritableFilesystem reports whether executed code can write to the
+	// filesystem by default.
+	WritableFilesystem bool
+
+	// Streaming reports whether output is streamed while code is running.
+	Streaming bool
+
+	// Files reports whether ExecuteRequest.Files are made available to
+	// executed code.
+	Files bool
+
+	// Artifacts reports whether ExecuteRequest.Artifacts are collected.
+	Artifacts bool
+}
+
+// UnsupportedFeatures returns the names of the request features used by req
+// that the backend does not support.
+func (c Capabilities) UnsupportedFeatures(req ExecuteRequest) []string {
+	var missing []string
+	if len(req.Files) > 0 && !c.Files {
+		missing = append(missing, "files")
+	}
+	if req.Artifacts != nil && !c.Artifacts {
+		missing = append(missing, "artifacts")
+	}
+	return missing
+}
+
+// UnenforcedLimits returns the names of the limits requested by req that
+// the backend cannot enforce.
+func (c Capabilities) UnenforcedLimits(req ExecuteRequest) []string {
+	var missing []string
+	check := func(requested, enforced bool, name string) {
+		if requested && !enforced {
+			missing = append(missing, name)
+		}
+	}
+	check(req.Timeout > 0, c.Limits.Timeout, "timeout")
+	check(req.Limits.MaxToolCalls > 0, c.Limits.ToolCalls, "tool calls")
+	check(req.Limits.MaxChainSteps > 0, c.Limits.ChainSteps, "chain steps")
+	check(req.Limits.MemoryBytes > 0, c.Limits.Memory, "memory")
+	check(req.Limits.CPUQuotaMillis > 0, c.Limits.CPU, "cpu")
+	check(req.Limits.PidsMax > 0, c.Limits.Pids, "pids")
+	check(req.Limits.DiskBytes > 0, c.Limits.Disk, "disk")
+	check(req.Limits.MaxStdoutBytes > 0 || req.Limits.MaxStderrBytes > 0 || req.Limits.MaxValueBytes > 0, c.Limits.Output, "output")
+	return missing
+}
+
+// CapabilityReporter is an optional extension to Backend for backends that
+// can describe their capabilities up-front.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Ownership: the returned value is a caller-owned snapshot.
+type CapabilityReporter interface {
+	Backend
+
+	// Capabilities describes the backend's capabilities.
+	Capabilities() Capabilities
+}
+
+// BackendCapabilities returns the capabilities of backend and true if it
+// implements CapabilityReporter. Otherwise it returns the isolation of the
+// backend kind and whether it implements StreamingBackend, with no
+// enforceable limits, and false.
+func BackendCapabilities(backend Backend) (Capabilities, bool) {
+	if reporter, ok := backend.(CapabilityReporter); ok {
+		return reporter.Capabilities(), true
+	}
+	_, streaming := backend.(StreamingBackend)
+	return Capabilities{Isolation: backend.Kind().Isolation(), Streaming: streaming}, false
+}
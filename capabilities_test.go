@@ -0,0 +1,117 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// capableBackend reports fixed capabilities
+type capableBackend struct {
+	countingBackend
+	caps Capabilities
+}
+
+func (b *capableBackend) Capabilities() Capabilities {
+	return b.caps
+}
+
+func TestCapabilitiesUnenforcedLimits(t *testing.T) {
+	caps := Capabilities{Limits: LimitsEnforced{Timeout: true, Memory: true}}
+	req := ExecuteRequest{
+		Timeout: time.Second,
+		Limits:  Limits{MemoryBytes: 1 << 20, PidsMax: 10, DiskBytes: 1 << 30, MaxStdoutBytes: 1024},
+	}
+
+	got := caps.UnenforcedLimits(req)
+	want := []string{"pids", "disk", "output"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnenforcedLimits() = %v, want %v", got, want)
+	}
+	if got := caps.UnenforcedLimits(ExecuteRequest{}); len(got) != 0 {
+		t.Errorf("UnenforcedLimits() without limits = %v, want none", got)
+	}
+}
+
+func TestCapabilitiesUnsupportedFeatures(t *testing.T) {
+	req := ExecuteRequest{Files: map[string]File{"a": {}}, Artifacts: &ArtifactSpec{}}
+	if got := (Capabilities{}).UnsupportedFeatures(req); !reflect.DeepEqual(got, []string{"files", "artifacts"}) {
+		t.Errorf("UnsupportedFeatures() = %v, want [files artifacts]", got)
+	}
+	if got := (Capabilities{Files: true, Artifacts: true}).UnsupportedFeatures(req); len(got) != 0 {
+		t.Errorf("UnsupportedFeatures() = %v, want none", got)
+	}
+}
+
+func TestBackendCapabilities(t *testing.T) {
+	reporter := &capableBackend{caps: Capabilities{Languages: []string{"go"}}}
+	if caps, ok := BackendCapabilities(reporter); !ok || caps.Languages[0] != "go" {
+		t.Errorf("BackendCapabilities(reporter) = %+v, %v", caps, ok)
+	}
+
+	caps, ok := BackendCapabilities(&mockBackend{kind: BackendGVisor})
+	if ok {
+		t.Error("BackendCapabilities(mockBackend) ok = true, want false")
+	}
+	if caps.Isolation != IsolationSandbox || caps.Limits != (LimitsEnforced{}) {
+		t.Errorf("BackendCapabilities(mockBackend) = %+v, want kind isolation and no limits", caps)
+	}
+}
+
+func TestDefaultRuntimeStrictLimits(t *testing.T) {
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Limits: Limits{MemoryBytes: 1 << 20}}
+
+	t.Run("lenient", func(t *testing.T) {
+		backend := &capableBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+			DefaultProfile: ProfileStandard,
+		})
+		if _, err := rt.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if backend.calls != 1 {
+			t.Errorf("backend calls = %d, want 1", backend.calls)
+		}
+	})
+
+	t.Run("strict denied", func(t *testing.T) {
+		backend := &capableBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+			DefaultProfile: ProfileStandard,
+		})
+		strict := req
+		strict.StrictLimits = true
+		_, err := rt.Execute(context.Background(), strict)
+		if !errors.Is(err, ErrBackendDenied) {
+			t.Fatalf("Execute() error = %v, want ErrBackendDenied", err)
+		}
+		if backend.calls != 0 {
+			t.Errorf("backend calls = %d, want 0", backend.calls)
+		}
+	})
+
+	t.Run("strict falls back", func(t *testing.T) {
+		primary := &capableBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+		fallback := &capableBackend{
+			countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendGVisor}},
+			caps:            Capabilities{Limits: LimitsEnforced{Memory: true}},
+		}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+			Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+			StrictLimits:   true,
+			DefaultProfile: ProfileStandard,
+		})
+		result, err := rt.Execute(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.Backend.Kind != BackendGVisor || primary.calls != 0 {
+			t.Errorf("backend = %q, primary calls = %d; want gvisor and 0", result.Backend.Kind, primary.calls)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package toolruntime
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ResultNonceEnv is the environment variable through which backends pass the
+// per-execution nonce of the result envelope to executed code.
+const ResultNonceEnv = "TOOLRUNTIME_RESULT_NONCE"
+
+// ResultMarkerPrefix starts the marker of a result envelope, which is
+// followed by the nonce and a colon.
+const ResultMarkerPrefix = "__TOOLRUNTIME_RESULT_"
+
+// ResultEnvelope is the framed result that executed code writes to stdout:
+// a newline, ResultMarker(nonce) and the JSON encoding of the envelope,
+// terminated by a newline. Because the nonce is random per execution, output
+// that merely looks like an envelope is never mistaken for one. Backends
+// strip the envelope from the Stdout returned to callers; if several are
+// written, the last one wins.
+type ResultEnvelope struct {
+	// Value becomes ExecuteResult.Value; wrappers set it from __out.
+	Value any `json:"value,omitempty"`
+
+	// Error reports that the code failed, e.g. with an uncaught exception.
+	Error *ResultError `json:"error,omitempty"`
+
+	// Metadata becomes ExecuteResult.Metadata.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// ResultError is an error reported by executed code in its result envelope.
+// It wraps ErrCodeFailed.
+type ResultError struct {
+	// Type classifies the error, such as "panic" or an exception class.
+	Type string `json:"type,omitempty"`
+
+	// Message describes the error.
+	Message string `json:"message"`
+}
+
+func (e *ResultError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("%v: %s", ErrCodeFailed, e.Message)
+	}
+	return fmt.Sprintf("%v: %s: %s", ErrCodeFailed, e.Type, e.Message)
+}
+
+func (e *ResultError) Unwrap() error { return ErrCodeFailed }
+
+// NewResultNonce returns a random nonce for a result envelope.
+func NewResultNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ResultMarker returns the marker that starts the result envelope for nonce.
+func ResultMarker(nonce string) string {
+	return ResultMarkerPrefix + nonce + ":"
+}
+
+// EncodeResultEnvelope returns the envelope line for nonce, including the
+// trailing newline. It is used by wrappers written in Go and by tests.
+func EncodeResultEnvelope(nonce string, env ResultEnvelope) ([]byte, error) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	line := append([]byte("\n"+ResultMarker(nonce)), data...)
+	return append(line, '\n'), nil
+}
+
+// envelopeWriter strips result envelopes from the output written to it,
+// passing the rest to w. The marker may start anywhere in a line and the
+// output may arrive in arbitrary chunks; the tail that could start a marker
+// is held back until more output or a flush arrives. A newline written
+// directly before a marker belongs to the envelope and is stripped with it.
+//
+// An envelope payload longer than limit is not kept: it is passed to w as
+// ordinary output, so it counts against the stdout limit, and result fails
+// with ErrResourceLimit.
+type envelopeWriter struct {
+	w      io.Writer
+	marker []byte
+	limit  int64
+
+	pending   []byte
+	capturing bool
+	payload   []byte
+	envelope  []byte // the last complete envelope payload
+	found     bool
+	overflow  bool
+}
+
+// newEnvelopeWriter returns an envelopeWriter for nonce writing to w that
+// keeps envelope payloads of up to limit bytes; zero means unlimited. With
+// an empty nonce, output is passed through unchanged.
+func newEnvelopeWriter(w io.Writer, nonce string, limit int64) *envelopeWriter {
+	e := &envelopeWriter{w: w, limit: limit}
+	if nonce != "" {
+		e.marker = []byte("\n" + ResultMarker(nonce))
+	}
+	return e
+}
+
+func (e *envelopeWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if e.marker == nil {
+		_, _ = e.w.Write(p)
+		return n, nil
+	}
+	for len(p) > 0 {
+		if e.capturing {
+			end := bytes.IndexByte(p, '\n')
+			chunk := p
+			if end >= 0 {
+				chunk = p[:end]
+			}
+			if e.limit > 0 && int64(len(e.payload)+len(chunk)) > e.limit {
+				e.overflowed()
+				continue
+			}
+			if end < 0 {
+				e.payload = append(e.payload, p...)
+				break
+			}
+			e.payload = append(e.payload, p[:end]...)
+			e.complete()
+			p = p[end+1:]
+			continue
+		}
+
+		data := append(e.pending, p...)
+		p = nil
+		if i := bytes.Index(data, e.marker); i >= 0 {
+			e.emit(data[:i])
+			e.pending, e.capturing = nil, true
+			p = data[i+len(e.marker):]
+			continue
+		}
+		keep := partialSuffix(data, e.marker)
+		e.emit(data[:len(data)-keep])
+		e.pending = append([]byte(nil), data[len(data)-keep:]...)
+	}
+	return n, nil
+}
+
+// flush emits the held back output and completes an envelope that is not
+// terminated by a newline.
+func (e *envelopeWriter) flush() {
+	if e.capturing {
+		e.complete()
+	}
+	e.emit(e.pending)
+	e.pending = nil
+}
+
+// overflowed gives up capturing an envelope that exceeds the limit and
+// passes what was captured to w as ordinary output.
+func (e *envelopeWriter) overflowed() {
+	e.emit(e.marker)
+	e.emit(e.payload)
+	e.payload, e.capturing, e.overflow = nil, false, true
+}
+
+// complete records the captured payload as the last envelope.
+func (e *envelopeWriter) complete() {
+	e.envelope, e.found = e.payload, true
+	e.payload, e.capturing = nil, false
+}
+
+func (e *envelopeWriter) emit(p []byte) {
+	if len(p) > 0 {
+		_, _ = e.w.Write(p)
+	}
+}
+
+// result decodes the last envelope, reporting whether one was written. It
+// fails with ErrResourceLimit if an envelope exceeded the limit.
+func (e *envelopeWriter) result() (ResultEnvelope, bool, error) {
+	var env ResultEnvelope
+	if e.overflow {
+		return env, true, fmt.Errorf("%w: result envelope exceeds %d bytes", ErrResourceLimit, e.limit)
+	}
+	if !e.found {
+		return env, false, nil
+	}
+	if err := json.Unmarshal(e.envelope, &env); err != nil {
+		return env, true, fmt.Errorf("invalid result envelope: %w", err)
+	}
+	return env, true, nil
+}
+
+// partialSuffix returns the length of the longest proper prefix of marker
+// that data ends with.
+func partialSuffix(data, marker []byte) int {
+	for k := min(len(data), len(marker)-1); k > 0; k-- {
+		if bytes.HasSuffix(data, marker[:k]) {
+			return k
+		}
+	}
+	return 0
+}
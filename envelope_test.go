@@ -0,0 +1,131 @@
+package toolruntime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeWriter(t *testing.T) {
+	line, err := EncodeResultEnvelope("n0nce", ResultEnvelope{Value: "v", Metadata: map[string]any{"k": 1.0}})
+	if err != nil {
+		t.Fatalf("EncodeResultEnvelope() error = %v", err)
+	}
+	spoof := "\n" + ResultMarker("other") + `{"value":"x"}` + "\n"
+	output := "out" + spoof + string(line) + "tail"
+
+	// Every split of the output must give the same result.
+	for size := 1; size <= len(output); size++ {
+		var out strings.Builder
+		w := newEnvelopeWriter(&out, "n0nce", 0)
+		for i := 0; i < len(output); i += size {
+			_, _ = w.Write([]byte(output[i:min(i+size, len(output))]))
+		}
+		w.flush()
+
+		env, found, err := w.result()
+		if err != nil || !found || env.Value != "v" || env.Metadata["k"] != 1.0 {
+			t.Fatalf("chunk size %d: result() = %+v, %v, %v", size, env, found, err)
+		}
+		if out.String() != "out"+spoof+"tail" {
+			t.Fatalf("chunk size %d: output = %q, want the envelope stripped", size, out.String())
+		}
+	}
+}
+
+func TestEnvelopeWriterLastWins(t *testing.T) {
+	first, _ := EncodeResultEnvelope("n", ResultEnvelope{Value: 1.0})
+	last, _ := EncodeResultEnvelope("n", ResultEnvelope{Value: 2.0})
+	var out strings.Builder
+	w := newEnvelopeWriter(&out, "n", 0)
+	_, _ = w.Write(append(first, last[:len(last)-1]...))
+	w.flush()
+
+	env, found, err := w.result()
+	if err != nil || !found || env.Value != 2.0 || out.Len() != 0 {
+		t.Errorf("result() = %+v, %v, %v, output %q; want the last unterminated envelope", env, found, err, out.String())
+	}
+}
+
+func TestEnvelopeWriterWithoutNonce(t *testing.T) {
+	var out strings.Builder
+	w := newEnvelopeWriter(&out, "", 0)
+	_, _ = w.Write([]byte("\n" + ResultMarker("") + "{}\n"))
+	w.flush()
+	if _, found, _ := w.result(); found || out.String() != "\n"+ResultMarker("")+"{}\n" {
+		t.Errorf("output = %q, found = %v; want output unchanged", out.String(), found)
+	}
+}
+
+func TestEnvelopeWriterLimit(t *testing.T) {
+	var out strings.Builder
+	w := newEnvelopeWriter(&out, "n", 8)
+	marker := "\n" + ResultMarker("n")
+	_, _ = w.Write([]byte(marker + `{"value":`))
+	_, _ = w.Write([]byte(`"0123456789"}`))
+	w.flush()
+
+	if _, _, err := w.result(); !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("result() error = %v, want ErrResourceLimit", err)
+	}
+	if out.String() != marker+`{"value":"0123456789"}` {
+		t.Errorf("output = %q, want the oversized envelope passed through", out.String())
+	}
+}
+
+func TestOutputCaptureEnvelopeLimit(t *testing.T) {
+	capture := NewOutputCapture(ExecuteRequest{Limits: Limits{MaxStdoutBytes: 16}})
+	_, _ = io.WriteString(capture.Stdout, "\n"+ResultMarker(capture.Nonce()))
+	for range 100 {
+		_, _ = io.WriteString(capture.Stdout, strings.Repeat("x", 1<<10))
+	}
+
+	var result ExecuteResult
+	if err := capture.Finish(&result); !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("Finish() error = %v, want ErrResourceLimit", err)
+	}
+	if !strings.Contains(result.Stdout, "[truncated:") || len(capture.envelope.payload) != 0 {
+		t.Errorf("Stdout = %q, want the envelope counted against stdout", result.Stdout)
+	}
+}
+
+func TestResultError(t *testing.T) {
+	err := fmt.Errorf("run: %w", &ResultError{Type: "panic", Message: "boom"})
+	if !errors.Is(err, ErrCodeFailed) || err.Error() != "run: executed code failed: panic: boom" {
+		t.Errorf("error = %v, want wrapping ErrCodeFailed", err)
+	}
+}
+
+func TestOutputCaptureEnvelope(t *testing.T) {
+	capture := NewOutputCapture(ExecuteRequest{Limits: Limits{MaxStdoutBytes: 5}})
+	var streamed strings.Builder
+	capture.WithStream(&streamed, nil)
+	line, _ := EncodeResultEnvelope(capture.Nonce(), ResultEnvelope{
+		Value:    "v",
+		Metadata: map[string]any{"k": "m"},
+		Error:    &ResultError{Message: "failed"},
+	})
+	_, _ = io.WriteString(capture.Stdout, "12345"+string(line))
+
+	var result ExecuteResult
+	if err := capture.Finish(&result); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if result.Stdout != "12345" || streamed.String() != "12345" {
+		t.Errorf("Stdout = %q, streamed %q; want the envelope stripped and not counted", result.Stdout, streamed.String())
+	}
+	if result.Value != "v" || result.Metadata["k"] != "m" {
+		t.Errorf("Value = %v, Metadata = %v", result.Value, result.Metadata)
+	}
+	if !errors.Is(capture.Err(), ErrCodeFailed) {
+		t.Errorf("Err() = %v, want ErrCodeFailed", capture.Err())
+	}
+
+	bad := NewOutputCapture(ExecuteRequest{})
+	_, _ = io.WriteString(bad.Stdout, "\n"+ResultMarker(bad.Nonce())+"{not json\n")
+	if err := bad.Finish(&result); err == nil {
+		t.Error("Finish() with an invalid envelope should fail")
+	}
+}
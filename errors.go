@@ -1,6 +1,7 @@
 package toolruntime
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -28,8 +29,43 @@ var (
 	// ErrMissingCode is returned when ExecuteRequest has no Code.
 	ErrMissingCode = errors.New("code is required")
 
+	// ErrInvalidRequest is returned when a backend cannot run a request, such
+	// as one naming an unknown language.
+	ErrInvalidRequest = errors.New("invalid request")
+
 	// ErrInvalidLimits is returned when Limits validation fails.
 	ErrInvalidLimits = errors.New("invalid limits")
+
+	// ErrInvalidFile is returned when an ExecuteRequest file has an invalid
+	// path or mode.
+	ErrInvalidFile = errors.New("invalid file")
+
+	// ErrInvalidInputs is returned when ExecuteRequest.Inputs cannot be
+	// encoded as JSON.
+	ErrInvalidInputs = errors.New("invalid inputs")
+
+	// ErrInvalidEnv is returned when ExecuteRequest.Env or Secrets has an
+	// invalid or reserved variable.
+	ErrInvalidEnv = errors.New("invalid environment")
+
+	// ErrCodeFailed is wrapped by a ResultError, reported when executed code
+	// fails and says so in its result envelope.
+	ErrCodeFailed = errors.New("executed code failed")
+
+	// ErrQueueFull is returned when an admission queue has no room for another waiter.
+	ErrQueueFull = errors.New("admission queue full")
+
+	// ErrQueueTimeout is returned when a request waits longer than the queue timeout for admission.
+	ErrQueueTimeout = errors.New("admission queue timeout")
+
+	// ErrJobNotFound is returned when an async job is unknown or has expired.
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobExists is returned when an async job with the same execution ID is already tracked.
+	ErrJobExists = errors.New("job already exists")
+
+	// ErrAuditLogTampered is returned when an audit log fails hash chain verification.
+	ErrAuditLogTampered = errors.New("audit log tampered")
 )
 
 // RuntimeError wraps an error with execution context information.
@@ -45,10 +81,32 @@ type RuntimeError struct {
 	Backend BackendKind
 
 	// Retryable indicates whether the operation can be retried.
-	// True for transient errors like timeouts; false for policy violations.
+	// True for transient failures that happened before user code started
+	// (daemon unavailable, image pull, container create); false for policy
+	// violations, invalid requests and failures after user code started.
 	Retryable bool
 }
 
+// NewRuntimeError wraps err in a RuntimeError for the given backend and
+// operation. It returns nil if err is nil, and returns context errors and
+// errors that already wrap a RuntimeError unchanged.
+func NewRuntimeError(backend BackendKind, op string, err error, retryable bool) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var re *RuntimeError
+	if errors.As(err, &re) {
+		return err
+	}
+	return &RuntimeError{Err: err, Op: op, Backend: backend, Retryable: retryable}
+}
+
+// IsRetryable reports whether err wraps a RuntimeError marked Retryable.
+func IsRetryable(err error) bool {
+	var re *RuntimeError
+	return errors.As(err, &re) && re.Retryable
+}
+
 // Error returns the error message with context.
 func (e *RuntimeError) Error() string {
 	if e.Err == nil {
@@ -1,6 +1,7 @@
 package toolruntime
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -180,3 +181,31 @@ func TestRuntimeErrorRetryable(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRuntimeError(t *testing.T) {
+	err := NewRuntimeError(BackendDocker, "health_check", ErrRuntimeUnavailable, true)
+	var re *RuntimeError
+	if !errors.As(err, &re) {
+		t.Fatalf("NewRuntimeError() = %T, want *RuntimeError", err)
+	}
+	if re.Op != "health_check" || re.Backend != BackendDocker || !re.Retryable {
+		t.Errorf("NewRuntimeError() = %+v", re)
+	}
+	if !errors.Is(err, ErrRuntimeUnavailable) || !IsRetryable(err) {
+		t.Error("NewRuntimeError() should wrap the error and be retryable")
+	}
+
+	// Existing RuntimeErrors and context errors are returned unchanged
+	if got := NewRuntimeError(BackendWASM, "other", err, false); got != err {
+		t.Errorf("NewRuntimeError(RuntimeError) = %v, want unchanged", got)
+	}
+	if got := NewRuntimeError(BackendDocker, "run", context.Canceled, true); got != context.Canceled {
+		t.Errorf("NewRuntimeError(context.Canceled) = %v, want unchanged", got)
+	}
+	if NewRuntimeError(BackendDocker, "run", nil, true) != nil {
+		t.Error("NewRuntimeError(nil) should be nil")
+	}
+	if IsRetryable(ErrRuntimeUnavailable) {
+		t.Error("IsRetryable(sentinel) = true, want false")
+	}
+}
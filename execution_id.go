@@ -0,0 +1,24 @@
+package toolruntime
+
+import "context"
+
+// executionIDKey is the context key for the current execution ID.
+type executionIDKey struct{}
+
+// NewExecutionID returns a new random execution ID (32 lowercase hex characters).
+func NewExecutionID() string {
+	return randomHex(16)
+}
+
+// ContextWithExecutionID returns a context that carries the execution ID.
+// DefaultRuntime sets it for every execution so backends and gateways can
+// correlate their work with the ExecuteResult.
+func ContextWithExecutionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, executionIDKey{}, id)
+}
+
+// ExecutionIDFromContext returns the execution ID carried by ctx, or "".
+func ExecutionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(executionIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,108 @@
+package toolruntime
+
+import (
+	"context"
+	"testing"
+)
+
+// idCapturingBackend records the execution IDs it observes
+type idCapturingBackend struct {
+	mockBackend
+	reqID string
+	ctxID string
+}
+
+func (b *idCapturingBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	b.reqID = req.ExecutionID
+	b.ctxID = ExecutionIDFromContext(ctx)
+	return b.mockBackend.Execute(ctx, req)
+}
+
+func TestNewExecutionID(t *testing.T) {
+	a, b := NewExecutionID(), NewExecutionID()
+	if len(a) != 32 {
+		t.Errorf("NewExecutionID() length = %d, want 32", len(a))
+	}
+	if a == b {
+		t.Errorf("NewExecutionID() returned duplicate %q", a)
+	}
+}
+
+func TestDefaultRuntimeExecutionID(t *testing.T) {
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		reqID string
+		want  string
+	}{
+		{"generated", context.Background(), "", ""},
+		{"from request", context.Background(), "req-id", "req-id"},
+		{"from context", ContextWithExecutionID(context.Background(), "ctx-id"), "", "ctx-id"},
+		{"request wins", ContextWithExecutionID(context.Background(), "ctx-id"), "req-id", "req-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &idCapturingBackend{mockBackend: mockBackend{
+				kind:   BackendUnsafeHost,
+				result: ExecuteResult{ToolCalls: []ToolCallRecord{{ToolID: "a"}, {ToolID: "b", ExecutionID: "other"}}},
+			}}
+			rt := NewDefaultRuntime(RuntimeConfig{
+				Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+				DefaultProfile: ProfileDev,
+			})
+
+			result, err := rt.Execute(tt.ctx, ExecuteRequest{
+				Code:        "test",
+				Gateway:     &mockToolGateway{},
+				ExecutionID: tt.reqID,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			id := result.ExecutionID
+			if id == "" {
+				t.Fatal("Execute().ExecutionID is empty")
+			}
+			if tt.want != "" && id != tt.want {
+				t.Errorf("Execute().ExecutionID = %q, want %q", id, tt.want)
+			}
+			if backend.reqID != id || backend.ctxID != id {
+				t.Errorf("backend saw req/ctx IDs %q/%q, want %q", backend.reqID, backend.ctxID, id)
+			}
+			if result.ToolCalls[0].ExecutionID != id {
+				t.Errorf("ToolCalls[0].ExecutionID = %q, want %q", result.ToolCalls[0].ExecutionID, id)
+			}
+			if result.ToolCalls[1].ExecutionID != "other" {
+				t.Errorf("ToolCalls[1].ExecutionID = %q, want existing ID kept", result.ToolCalls[1].ExecutionID)
+			}
+		})
+	}
+}
+
+func TestExecuteStreamToolCallExecutionID(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &toolCallingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}, toolID: "ns:tool"},
+		},
+		DefaultProfile: ProfileDev,
+	})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{
+		Code:        "test",
+		Gateway:     &mockToolGateway{},
+		ExecutionID: "stream-id",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	if events[0].ToolCall == nil || events[0].ToolCall.ExecutionID != "stream-id" {
+		t.Errorf("tool call event = %+v, want ExecutionID %q", events[0].ToolCall, "stream-id")
+	}
+	if last := events[len(events)-1]; last.Result == nil || last.Result.ExecutionID != "stream-id" {
+		t.Errorf("final event = %+v, want ExecutionID %q", last, "stream-id")
+	}
+}
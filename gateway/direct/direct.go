@@ -43,6 +43,9 @@ type Config struct {
 	// MaxChainSteps limits the number of steps in a chain.
 	// Zero means unlimited.
 	MaxChainSteps int
+
+	// Metrics optionally records failed operations and limit violations.
+	Metrics toolruntime.Metrics
 }
 
 // Gateway implements ToolGateway by directly delegating to
@@ -53,6 +56,7 @@ type Gateway struct {
 	runner        toolrun.Runner
 	maxToolCalls  int
 	maxChainSteps int
+	metrics       toolruntime.Metrics
 
 	mu        sync.Mutex
 	callCount int
@@ -67,6 +71,7 @@ func New(cfg Config) *Gateway {
 		runner:        cfg.Runner,
 		maxToolCalls:  cfg.MaxToolCalls,
 		maxChainSteps: cfg.MaxChainSteps,
+		metrics:       cfg.Metrics,
 	}
 }
 
@@ -75,7 +80,9 @@ func (g *Gateway) SearchTools(ctx context.Context, query string, limit int) ([]t
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	return g.index.Search(query, limit)
+	results, err := g.index.Search(query, limit)
+	g.recordError("search_tools", err)
+	return results, err
 }
 
 // ListNamespaces delegates to the index.
@@ -83,7 +90,9 @@ func (g *Gateway) ListNamespaces(ctx context.Context) ([]string, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	return g.index.ListNamespaces()
+	namespaces, err := g.index.ListNamespaces()
+	g.recordError("list_namespaces", err)
+	return namespaces, err
 }
 
 // DescribeTool delegates to the docs store.
@@ -91,7 +100,9 @@ func (g *Gateway) DescribeTool(ctx context.Context, id string, level tooldocs.De
 	if ctx.Err() != nil {
 		return tooldocs.ToolDoc{}, ctx.Err()
 	}
-	return g.docs.DescribeTool(id, level)
+	doc, err := g.docs.DescribeTool(id, level)
+	g.recordError("describe_tool", err)
+	return doc, err
 }
 
 // ListToolExamples delegates to the docs store.
@@ -99,7 +110,9 @@ func (g *Gateway) ListToolExamples(ctx context.Context, id string, maxExamples i
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	return g.docs.ListExamples(id, maxExamples)
+	examples, err := g.docs.ListExamples(id, maxExamples)
+	g.recordError("list_tool_examples", err)
+	return examples, err
 }
 
 // RunTool delegates to the runner and records the call.
@@ -112,20 +125,28 @@ func (g *Gateway) RunTool(ctx context.Context, id string, args map[string]any) (
 	g.mu.Lock()
 	if g.maxToolCalls > 0 && g.callCount >= g.maxToolCalls {
 		g.mu.Unlock()
-		return toolrun.RunResult{}, fmt.Errorf("%w: max %d calls exceeded", ErrToolCallLimitExceeded, g.maxToolCalls)
+		err := fmt.Errorf("%w: max %d calls exceeded", ErrToolCallLimitExceeded, g.maxToolCalls)
+		g.recordError("run_tool", err)
+		return toolrun.RunResult{}, err
 	}
 	g.callCount++
 	g.mu.Unlock()
 
 	// Execute
+	runCtx, span := toolruntime.StartSpan(ctx, "gateway.run_tool", toolruntime.Attr("tool.id", id))
 	start := time.Now()
-	result, err := g.runner.Run(ctx, id, args)
+	result, err := g.runner.Run(runCtx, id, args)
 	duration := time.Since(start)
+	span.SetAttributes(toolruntime.Attr("tool.backend", string(result.Backend.Kind)))
+	span.RecordError(err)
+	span.End()
+	g.recordError("run_tool", err)
 
 	// Record the call
 	record := toolruntime.ToolCallRecord{
-		ToolID:   id,
-		Duration: duration,
+		ToolID:      id,
+		ExecutionID: toolruntime.ExecutionIDFromContext(ctx),
+		Duration:    duration,
 	}
 	if err != nil {
 		record.ErrorOp = "run"
@@ -154,8 +175,10 @@ func (g *Gateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (tool
 
 	// Check chain step limit
 	if g.maxChainSteps > 0 && len(steps) > g.maxChainSteps {
-		return toolrun.RunResult{}, nil, fmt.Errorf("%w: max %d steps exceeded (got %d)",
+		err := fmt.Errorf("%w: max %d steps exceeded (got %d)",
 			ErrChainStepLimitExceeded, g.maxChainSteps, len(steps))
+		g.recordError("run_chain", err)
+		return toolrun.RunResult{}, nil, err
 	}
 
 	// Check if we have enough room for all steps
@@ -163,16 +186,33 @@ func (g *Gateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (tool
 	g.mu.Lock()
 	if g.maxToolCalls > 0 && g.callCount+reserved > g.maxToolCalls {
 		g.mu.Unlock()
-		return toolrun.RunResult{}, nil, fmt.Errorf("%w: would exceed max %d calls",
+		err := fmt.Errorf("%w: would exceed max %d calls",
 			ErrToolCallLimitExceeded, g.maxToolCalls)
+		g.recordError("run_chain", err)
+		return toolrun.RunResult{}, nil, err
 	}
 	g.callCount += reserved
 	g.mu.Unlock()
 
 	// Execute
+	runCtx, span := toolruntime.StartSpan(ctx, "gateway.run_chain", toolruntime.Attr("chain.steps", len(steps)))
 	start := time.Now()
-	result, stepResults, err := g.runner.RunChain(ctx, steps)
+	result, stepResults, err := g.runner.RunChain(runCtx, steps)
 	duration := time.Since(start)
+	for i, sr := range stepResults {
+		attrs := []toolruntime.Attribute{
+			toolruntime.Attr("chain.step", i),
+			toolruntime.Attr("tool.id", sr.ToolID),
+			toolruntime.Attr("tool.backend", string(sr.Backend.Kind)),
+		}
+		if sr.Err != nil {
+			attrs = append(attrs, toolruntime.Attr("error", sr.Err.Error()))
+		}
+		span.AddEvent("chain.step", attrs...)
+	}
+	span.RecordError(err)
+	span.End()
+	g.recordError("run_chain", err)
 
 	executed := len(stepResults)
 	if executed == 0 && err == nil {
@@ -201,8 +241,9 @@ func (g *Gateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (tool
 	g.mu.Lock()
 	for i, step := range steps[:executed] {
 		record := toolruntime.ToolCallRecord{
-			ToolID:   step.ToolID,
-			Duration: stepDuration,
+			ToolID:      step.ToolID,
+			ExecutionID: toolruntime.ExecutionIDFromContext(ctx),
+			Duration:    stepDuration,
 		}
 		if i < len(stepResults) && stepResults[i].Err != nil {
 			record.ErrorOp = "chain"
@@ -217,6 +258,21 @@ func (g *Gateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (tool
 	return result, stepResults, err
 }
 
+// recordError reports a failed operation, and any limit it violated, to the
+// configured metrics.
+func (g *Gateway) recordError(op string, err error) {
+	if g.metrics == nil || err == nil {
+		return
+	}
+	g.metrics.RecordGatewayError("direct", op)
+	switch {
+	case errors.Is(err, ErrToolCallLimitExceeded):
+		g.metrics.RecordLimitViolation(toolruntime.LimitToolCalls)
+	case errors.Is(err, ErrChainStepLimitExceeded):
+		g.metrics.RecordLimitViolation(toolruntime.LimitChainSteps)
+	}
+}
+
 // GetToolCalls returns a copy of all recorded tool calls.
 func (g *Gateway) GetToolCalls() []toolruntime.ToolCallRecord {
 	g.mu.Lock()
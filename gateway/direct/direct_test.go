@@ -1,8 +1,10 @@
 package direct
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 
@@ -403,6 +405,60 @@ func TestGatewayRunChain(t *testing.T) {
 	})
 }
 
+func TestGatewayTracing(t *testing.T) {
+	runner := &mockRunner{
+		stepResults: []toolrun.StepResult{
+			{ToolID: "step1"},
+			{ToolID: "step2", Err: errors.New("step failed")},
+		},
+	}
+	gw := New(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockDocs{},
+		Runner: runner,
+	})
+
+	var buf bytes.Buffer
+	ctx := toolruntime.ContextWithTracer(context.Background(), toolruntime.NewOTLPJSONTracer(&buf, "test"))
+
+	if _, err := gw.RunTool(ctx, "ns:tool", nil); err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	_, _, _ = gw.RunChain(ctx, []toolrun.ChainStep{{ToolID: "step1"}, {ToolID: "step2"}})
+
+	out := buf.String()
+	for _, want := range []string{`"name":"gateway.run_tool"`, `"name":"gateway.run_chain"`, `"stringValue":"ns:tool"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace output missing %s:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, `"name":"chain.step"`); got != 2 {
+		t.Errorf("chain.step events = %d, want 2", got)
+	}
+}
+
+func TestGatewayRecordsExecutionID(t *testing.T) {
+	gw := New(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockDocs{},
+		Runner: &mockRunner{stepResults: []toolrun.StepResult{{ToolID: "step1"}}},
+	})
+	ctx := toolruntime.ContextWithExecutionID(context.Background(), "exec-123")
+
+	_, _ = gw.RunTool(ctx, "tool1", nil)
+	_, _, _ = gw.RunChain(ctx, []toolrun.ChainStep{{ToolID: "step1"}})
+
+	calls := gw.GetToolCalls()
+	if len(calls) != 2 {
+		t.Fatalf("GetToolCalls() returned %d records, want 2", len(calls))
+	}
+	for i, c := range calls {
+		if c.ExecutionID != "exec-123" {
+			t.Errorf("calls[%d].ExecutionID = %q, want %q", i, c.ExecutionID, "exec-123")
+		}
+	}
+}
+
 func TestGatewayToolCallLimits(t *testing.T) {
 	runner := &mockRunner{}
 	gw := New(Config{
@@ -434,6 +490,53 @@ func TestGatewayToolCallLimits(t *testing.T) {
 	}
 }
 
+// mockMetrics records gateway errors and limit violations
+type mockMetrics struct {
+	mu              sync.Mutex
+	gatewayErrors   []string
+	limitViolations []string
+}
+
+func (m *mockMetrics) RecordExecution(toolruntime.ExecutionMetrics) {}
+
+func (m *mockMetrics) RecordLimitViolation(limit string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limitViolations = append(m.limitViolations, limit)
+}
+
+func (m *mockMetrics) RecordGatewayError(gateway, op string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gatewayErrors = append(m.gatewayErrors, gateway+"/"+op)
+}
+
+func TestGatewayMetrics(t *testing.T) {
+	metrics := &mockMetrics{}
+	gw := New(Config{
+		Index:         &mockIndex{},
+		Docs:          &mockDocs{},
+		Runner:        &mockRunner{runErr: errors.New("tool failed")},
+		MaxToolCalls:  1,
+		MaxChainSteps: 1,
+		Metrics:       metrics,
+	})
+	ctx := context.Background()
+
+	_, _ = gw.RunTool(ctx, "tool1", nil)
+	_, _ = gw.RunTool(ctx, "tool2", nil)
+	_, _, _ = gw.RunChain(ctx, []toolrun.ChainStep{{ToolID: "a"}, {ToolID: "b"}})
+
+	wantErrors := []string{"direct/run_tool", "direct/run_tool", "direct/run_chain"}
+	if strings.Join(metrics.gatewayErrors, ",") != strings.Join(wantErrors, ",") {
+		t.Errorf("gateway errors = %v, want %v", metrics.gatewayErrors, wantErrors)
+	}
+	wantLimits := []string{toolruntime.LimitToolCalls, toolruntime.LimitChainSteps}
+	if strings.Join(metrics.limitViolations, ",") != strings.Join(wantLimits, ",") {
+		t.Errorf("limit violations = %v, want %v", metrics.limitViolations, wantLimits)
+	}
+}
+
 func TestGatewayChainStepLimits(t *testing.T) {
 	runner := &mockRunner{
 		stepResults: []toolrun.StepResult{{}, {}},
@@ -22,10 +22,13 @@ const (
 )
 
 // Message is the wire protocol envelope for gateway operations.
+// Requests carry the execution ID of their context so the remote side can
+// correlate tool calls with the execution that made them.
 type Message struct {
-	Type    MessageType    `json:"type"`
-	ID      string         `json:"id"`
-	Payload map[string]any `json:"payload,omitempty"`
+	Type        MessageType    `json:"type"`
+	ID          string         `json:"id"`
+	ExecutionID string         `json:"executionId,omitempty"`
+	Payload     map[string]any `json:"payload,omitempty"`
 }
 
 // Connection defines the interface for sending and receiving messages.
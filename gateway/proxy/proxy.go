@@ -11,6 +11,7 @@ import (
 	"github.com/jonwraymond/tooldocs"
 	"github.com/jonwraymond/toolindex"
 	"github.com/jonwraymond/toolrun"
+	"github.com/jonwraymond/toolruntime"
 )
 
 // Errors for proxy gateway operations.
@@ -27,6 +28,9 @@ type Config struct {
 
 	// Codec is the message codec to use. If nil, JSON is used.
 	Codec Codec
+
+	// Metrics optionally records failed requests.
+	Metrics toolruntime.Metrics
 }
 
 // Gateway implements ToolGateway by serializing requests over a connection.
@@ -35,6 +39,7 @@ type Config struct {
 type Gateway struct {
 	conn      Connection
 	codec     Codec
+	metrics   toolruntime.Metrics
 	requestID atomic.Uint64
 	pending   sync.Map // map[string]chan Message
 	closed    atomic.Bool
@@ -49,8 +54,9 @@ func New(cfg Config) *Gateway {
 	}
 
 	return &Gateway{
-		conn:  cfg.Connection,
-		codec: codec,
+		conn:    cfg.Connection,
+		codec:   codec,
+		metrics: cfg.Metrics,
 	}
 }
 
@@ -272,14 +278,25 @@ func (g *Gateway) Close() error {
 	return g.conn.Close()
 }
 
-// request sends a request and waits for the response.
+// request sends a request and waits for the response, recording failures
+// to the configured metrics.
 func (g *Gateway) request(ctx context.Context, msgType MessageType, payload map[string]any) (Message, error) {
+	resp, err := g.roundTrip(ctx, msgType, payload)
+	if err != nil && g.metrics != nil {
+		g.metrics.RecordGatewayError("proxy", string(msgType))
+	}
+	return resp, err
+}
+
+// roundTrip sends a request and waits for the response.
+func (g *Gateway) roundTrip(ctx context.Context, msgType MessageType, payload map[string]any) (Message, error) {
 	id := fmt.Sprintf("%d", g.requestID.Add(1))
 
 	msg := Message{
-		Type:    msgType,
-		ID:      id,
-		Payload: payload,
+		Type:        msgType,
+		ID:          id,
+		ExecutionID: toolruntime.ExecutionIDFromContext(ctx),
+		Payload:     payload,
 	}
 
 	// Create response channel
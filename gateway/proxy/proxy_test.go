@@ -247,6 +247,45 @@ func TestGatewayRunTool(t *testing.T) {
 	}
 }
 
+func TestGatewayForwardsExecutionID(t *testing.T) {
+	conn := newAutoRespondConnection(func(msg Message) Message {
+		return Message{Type: MsgResponse, ID: msg.ID}
+	})
+	gw := New(Config{Connection: conn})
+	conn.SetGateway(gw)
+
+	ctx := toolruntime.ContextWithExecutionID(context.Background(), "exec-1")
+	if _, err := gw.RunTool(ctx, "test:tool", nil); err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if _, err := gw.ListNamespaces(context.Background()); err != nil {
+		t.Fatalf("ListNamespaces() error = %v", err)
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if len(conn.messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(conn.messages))
+	}
+	if got := conn.messages[0].ExecutionID; got != "exec-1" {
+		t.Errorf("RunTool ExecutionID = %q, want exec-1", got)
+	}
+	if got := conn.messages[1].ExecutionID; got != "" {
+		t.Errorf("ListNamespaces ExecutionID = %q, want none", got)
+	}
+
+	// The execution ID survives the wire encoding
+	codec := &jsonCodec{}
+	data, err := codec.Encode(conn.messages[0])
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	decoded, err := codec.Decode(data)
+	if err != nil || decoded.ExecutionID != "exec-1" {
+		t.Errorf("Decode() = %+v, %v; want execution ID exec-1", decoded, err)
+	}
+}
+
 func TestGatewayRunChain(t *testing.T) {
 	conn := newAutoRespondConnection(func(msg Message) Message {
 		return Message{
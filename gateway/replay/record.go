@@ -0,0 +1,140 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jonwraymond/tooldocs"
+	"github.com/jonwraymond/toolindex"
+	"github.com/jonwraymond/toolrun"
+	"github.com/jonwraymond/toolruntime"
+)
+
+// RecorderConfig configures a Recorder.
+type RecorderConfig struct {
+	// Gateway is the gateway whose calls are recorded. Required.
+	Gateway toolruntime.ToolGateway
+
+	// Writer receives one JSON-encoded Interaction per line. Required.
+	Writer io.Writer
+}
+
+// Recorder implements ToolGateway by delegating to another gateway and
+// appending every call, with its arguments, results and error, to a fixture.
+// Calls are written as they complete.
+type Recorder struct {
+	gateway toolruntime.ToolGateway
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder creates a Recorder with the given configuration.
+func NewRecorder(cfg RecorderConfig) *Recorder {
+	return &Recorder{
+		gateway: cfg.Gateway,
+		enc:     json.NewEncoder(cfg.Writer),
+	}
+}
+
+// Err returns the first error encountered while writing the fixture, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// SearchTools records a search.
+func (r *Recorder) SearchTools(ctx context.Context, query string, limit int) ([]toolindex.Summary, error) {
+	results, err := r.gateway.SearchTools(ctx, query, limit)
+	r.record(ctx, OpSearchTools, searchToolsRequest{Query: query, Limit: limit}, results, err)
+	return results, err
+}
+
+// ListNamespaces records a namespace listing.
+func (r *Recorder) ListNamespaces(ctx context.Context) ([]string, error) {
+	namespaces, err := r.gateway.ListNamespaces(ctx)
+	r.record(ctx, OpListNamespaces, struct{}{}, namespaces, err)
+	return namespaces, err
+}
+
+// DescribeTool records a tool description.
+func (r *Recorder) DescribeTool(ctx context.Context, id string, level tooldocs.DetailLevel) (tooldocs.ToolDoc, error) {
+	doc, err := r.gateway.DescribeTool(ctx, id, level)
+	r.record(ctx, OpDescribeTool, describeToolRequest{ID: id, Level: level}, doc, err)
+	return doc, err
+}
+
+// ListToolExamples records tool examples.
+func (r *Recorder) ListToolExamples(ctx context.Context, id string, maxExamples int) ([]tooldocs.ToolExample, error) {
+	examples, err := r.gateway.ListToolExamples(ctx, id, maxExamples)
+	r.record(ctx, OpListToolExamples, listToolExamplesRequest{ID: id, Max: maxExamples}, examples, err)
+	return examples, err
+}
+
+// RunTool records a tool run.
+func (r *Recorder) RunTool(ctx context.Context, id string, args map[string]any) (toolrun.RunResult, error) {
+	result, err := r.gateway.RunTool(ctx, id, args)
+	r.record(ctx, OpRunTool, runToolRequest{ID: id, Args: args}, result, err)
+	return result, err
+}
+
+// RunChain records a chain run.
+func (r *Recorder) RunChain(ctx context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	result, stepResults, err := r.gateway.RunChain(ctx, steps)
+
+	resp := runChainResponse{Result: result, Steps: make([]toolrun.StepResult, len(stepResults))}
+	for i, sr := range stepResults {
+		if sr.Err != nil {
+			if resp.StepErrors == nil {
+				resp.StepErrors = make([]string, len(stepResults))
+			}
+			resp.StepErrors[i] = sr.Err.Error()
+			sr.Err = nil
+		}
+		resp.Steps[i] = sr
+	}
+	r.record(ctx, OpRunChain, runChainRequest{Steps: steps}, resp, err)
+	return result, stepResults, err
+}
+
+// GetToolCalls returns the tool calls recorded by the wrapped gateway, if it
+// records them.
+func (r *Recorder) GetToolCalls() []toolruntime.ToolCallRecord {
+	if recorder, ok := r.gateway.(interface {
+		GetToolCalls() []toolruntime.ToolCallRecord
+	}); ok {
+		return recorder.GetToolCalls()
+	}
+	return nil
+}
+
+// record appends an interaction to the fixture.
+func (r *Recorder) record(ctx context.Context, op Op, req, resp any, callErr error) {
+	in := Interaction{
+		Op:          op,
+		ExecutionID: toolruntime.ExecutionIDFromContext(ctx),
+	}
+	var err error
+	if in.Request, err = json.Marshal(req); err == nil {
+		in.Response, err = json.Marshal(resp)
+	}
+	if callErr != nil {
+		in.Error = callErr.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		err = r.enc.Encode(in)
+	}
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("record %s: %w", op, err)
+	}
+}
+
+var _ toolruntime.ToolGateway = (*Recorder)(nil)
@@ -0,0 +1,328 @@
+// Package replay provides a ToolGateway that records every gateway call to a
+// fixture, and a ToolGateway that replays a fixture without touching live
+// tools. Together they reproduce an execution's tool interactions against any
+// backend, e.g. to debug a production incident or to write regression tests
+// for snippets.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/jonwraymond/tooldocs"
+	"github.com/jonwraymond/toolindex"
+	"github.com/jonwraymond/toolrun"
+	"github.com/jonwraymond/toolruntime"
+)
+
+// Errors for replay gateway operations.
+var (
+	// ErrUnexpectedCall is returned when a call has no matching recorded
+	// interaction left.
+	ErrUnexpectedCall = errors.New("unexpected gateway call")
+
+	// ErrInvalidFixture is returned when a fixture cannot be decoded.
+	ErrInvalidFixture = errors.New("invalid fixture")
+)
+
+// Op identifies a gateway operation.
+type Op string
+
+const (
+	// OpSearchTools identifies a SearchTools call.
+	OpSearchTools Op = "search_tools"
+
+	// OpListNamespaces identifies a ListNamespaces call.
+	OpListNamespaces Op = "list_namespaces"
+
+	// OpDescribeTool identifies a DescribeTool call.
+	OpDescribeTool Op = "describe_tool"
+
+	// OpListToolExamples identifies a ListToolExamples call.
+	OpListToolExamples Op = "list_tool_examples"
+
+	// OpRunTool identifies a RunTool call.
+	OpRunTool Op = "run_tool"
+
+	// OpRunChain identifies a RunChain call.
+	OpRunChain Op = "run_chain"
+)
+
+// Interaction is one recorded gateway call. A fixture is a sequence of
+// interactions stored as JSON lines.
+type Interaction struct {
+	// Op is the gateway operation.
+	Op Op `json:"op"`
+
+	// ExecutionID identifies the execution that made the call, if known.
+	// It is informational and not used for matching.
+	ExecutionID string `json:"executionId,omitempty"`
+
+	// Request holds the JSON-encoded call arguments.
+	Request json.RawMessage `json:"request"`
+
+	// Response holds the JSON-encoded results.
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Error is the error message returned by the call, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Request and response payloads.
+type (
+	searchToolsRequest struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	describeToolRequest struct {
+		ID    string               `json:"id"`
+		Level tooldocs.DetailLevel `json:"level"`
+	}
+	listToolExamplesRequest struct {
+		ID  string `json:"id"`
+		Max int    `json:"max"`
+	}
+	runToolRequest struct {
+		ID   string         `json:"id"`
+		Args map[string]any `json:"args"`
+	}
+	runChainRequest struct {
+		Steps []toolrun.ChainStep `json:"steps"`
+	}
+	runChainResponse struct {
+		Result toolrun.RunResult    `json:"result"`
+		Steps  []toolrun.StepResult `json:"steps"`
+
+		// StepErrors holds StepResult.Err messages, which do not survive
+		// JSON encoding, by step index.
+		StepErrors []string `json:"stepErrors,omitempty"`
+	}
+)
+
+// Load reads a fixture written by a Recorder.
+func Load(r io.Reader) ([]Interaction, error) {
+	var interactions []Interaction
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var in Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFixture, line, err)
+		}
+		interactions = append(interactions, in)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return interactions, nil
+}
+
+// LoadFile reads the fixture file at path.
+func LoadFile(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Config configures a replay gateway.
+type Config struct {
+	// Interactions are the recorded interactions to serve.
+	Interactions []Interaction
+}
+
+// Gateway implements ToolGateway by serving recorded interactions. A call is
+// matched by operation and arguments; identical calls are served in recorded
+// order. Calls without a matching interaction fail with ErrUnexpectedCall.
+//
+// Responses are decoded from JSON, so values held in `any` fields (such as
+// RunResult.Structured) come back as JSON types, and recorded errors are
+// returned as plain errors carrying the recorded message.
+type Gateway struct {
+	mu        sync.Mutex
+	pending   map[string][]Interaction
+	unused    int
+	toolCalls []toolruntime.ToolCallRecord
+}
+
+// New creates a replay gateway with the given configuration.
+func New(cfg Config) *Gateway {
+	g := &Gateway{pending: make(map[string][]Interaction)}
+	for _, in := range cfg.Interactions {
+		key := matchKey(in.Op, in.Request)
+		g.pending[key] = append(g.pending[key], in)
+		g.unused++
+	}
+	return g
+}
+
+// SearchTools serves a recorded search.
+func (g *Gateway) SearchTools(ctx context.Context, query string, limit int) ([]toolindex.Summary, error) {
+	var results []toolindex.Summary
+	err := g.serve(ctx, OpSearchTools, searchToolsRequest{Query: query, Limit: limit}, &results)
+	return results, err
+}
+
+// ListNamespaces serves a recorded namespace listing.
+func (g *Gateway) ListNamespaces(ctx context.Context) ([]string, error) {
+	var namespaces []string
+	err := g.serve(ctx, OpListNamespaces, struct{}{}, &namespaces)
+	return namespaces, err
+}
+
+// DescribeTool serves a recorded tool description.
+func (g *Gateway) DescribeTool(ctx context.Context, id string, level tooldocs.DetailLevel) (tooldocs.ToolDoc, error) {
+	var doc tooldocs.ToolDoc
+	err := g.serve(ctx, OpDescribeTool, describeToolRequest{ID: id, Level: level}, &doc)
+	return doc, err
+}
+
+// ListToolExamples serves recorded tool examples.
+func (g *Gateway) ListToolExamples(ctx context.Context, id string, maxExamples int) ([]tooldocs.ToolExample, error) {
+	var examples []tooldocs.ToolExample
+	err := g.serve(ctx, OpListToolExamples, listToolExamplesRequest{ID: id, Max: maxExamples}, &examples)
+	return examples, err
+}
+
+// RunTool serves a recorded tool run and records the call.
+func (g *Gateway) RunTool(ctx context.Context, id string, args map[string]any) (toolrun.RunResult, error) {
+	var result toolrun.RunResult
+	err := g.serve(ctx, OpRunTool, runToolRequest{ID: id, Args: args}, &result)
+	if errors.Is(err, ErrUnexpectedCall) || ctx.Err() != nil {
+		return result, err
+	}
+
+	record := toolruntime.ToolCallRecord{
+		ToolID:      id,
+		ExecutionID: toolruntime.ExecutionIDFromContext(ctx),
+		BackendKind: string(result.Backend.Kind),
+	}
+	if err != nil {
+		record.ErrorOp = "run"
+	}
+	g.mu.Lock()
+	g.toolCalls = append(g.toolCalls, record)
+	g.mu.Unlock()
+	return result, err
+}
+
+// RunChain serves a recorded chain run and records the calls.
+func (g *Gateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	var resp runChainResponse
+	err := g.serve(ctx, OpRunChain, runChainRequest{Steps: steps}, &resp)
+	if errors.Is(err, ErrUnexpectedCall) || ctx.Err() != nil {
+		return toolrun.RunResult{}, nil, err
+	}
+	for i, msg := range resp.StepErrors {
+		if msg != "" && i < len(resp.Steps) {
+			resp.Steps[i].Err = errors.New(msg)
+		}
+	}
+
+	g.mu.Lock()
+	for i, sr := range resp.Steps {
+		record := toolruntime.ToolCallRecord{
+			ToolID:      sr.ToolID,
+			ExecutionID: toolruntime.ExecutionIDFromContext(ctx),
+			BackendKind: string(sr.Backend.Kind),
+		}
+		if i < len(steps) && record.ToolID == "" {
+			record.ToolID = steps[i].ToolID
+		}
+		if sr.Err != nil {
+			record.ErrorOp = "chain"
+		}
+		g.toolCalls = append(g.toolCalls, record)
+	}
+	g.mu.Unlock()
+	return resp.Result, resp.Steps, err
+}
+
+// Unused returns the recorded interactions that have not been served, in no
+// particular order. Tests can use it to check a snippet made every expected
+// call.
+func (g *Gateway) Unused() []Interaction {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	unused := make([]Interaction, 0, g.unused)
+	for _, queue := range g.pending {
+		unused = append(unused, queue...)
+	}
+	return unused
+}
+
+// GetToolCalls returns a copy of the tool calls served so far.
+func (g *Gateway) GetToolCalls() []toolruntime.ToolCallRecord {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	result := make([]toolruntime.ToolCallRecord, len(g.toolCalls))
+	copy(result, g.toolCalls)
+	return result
+}
+
+// serve pops the next interaction matching op and req and decodes its
+// response into resp.
+func (g *Gateway) serve(ctx context.Context, op Op, req any, resp any) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encode %s request: %w", op, err)
+	}
+
+	key := matchKey(op, data)
+	g.mu.Lock()
+	queue := g.pending[key]
+	if len(queue) == 0 {
+		g.mu.Unlock()
+		return fmt.Errorf("%w: %s %s", ErrUnexpectedCall, op, data)
+	}
+	in := queue[0]
+	if len(queue) == 1 {
+		delete(g.pending, key)
+	} else {
+		g.pending[key] = queue[1:]
+	}
+	g.unused--
+	g.mu.Unlock()
+
+	if len(in.Response) > 0 {
+		if err := json.Unmarshal(in.Response, resp); err != nil {
+			return fmt.Errorf("%w: %s response: %v", ErrInvalidFixture, op, err)
+		}
+	}
+	if in.Error != "" {
+		return errors.New(in.Error)
+	}
+	return nil
+}
+
+// matchKey identifies calls with the same operation and arguments. Requests
+// are compacted so fixtures edited by hand still match.
+func matchKey(op Op, request json.RawMessage) string {
+	var v any
+	if err := json.Unmarshal(request, &v); err != nil {
+		return string(op) + " " + string(request)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return string(op) + " " + string(request)
+	}
+	return string(op) + " " + string(canonical)
+}
+
+var _ toolruntime.ToolGateway = (*Gateway)(nil)
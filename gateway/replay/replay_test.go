@@ -0,0 +1,189 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/tooldocs"
+	"github.com/jonwraymond/toolindex"
+	"github.com/jonwraymond/toolmodel"
+	"github.com/jonwraymond/toolrun"
+	"github.com/jonwraymond/toolruntime"
+)
+
+// liveGateway implements ToolGateway with canned responses
+type liveGateway struct {
+	runs int
+}
+
+func (g *liveGateway) SearchTools(_ context.Context, query string, _ int) ([]toolindex.Summary, error) {
+	return []toolindex.Summary{{ID: "ns:" + query, Name: query, Namespace: "ns", Tags: []string{"t"}}}, nil
+}
+
+func (g *liveGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return []string{"ns"}, nil
+}
+
+func (g *liveGateway) DescribeTool(_ context.Context, id string, _ tooldocs.DetailLevel) (tooldocs.ToolDoc, error) {
+	if id == "missing" {
+		return tooldocs.ToolDoc{}, errors.New("tool not found")
+	}
+	return tooldocs.ToolDoc{Summary: "does " + id}, nil
+}
+
+func (g *liveGateway) ListToolExamples(_ context.Context, id string, _ int) ([]tooldocs.ToolExample, error) {
+	return []tooldocs.ToolExample{{ID: id + "-1", Args: map[string]any{"x": "y"}}}, nil
+}
+
+func (g *liveGateway) RunTool(_ context.Context, id string, _ map[string]any) (toolrun.RunResult, error) {
+	g.runs++
+	return toolrun.RunResult{
+		Structured: map[string]any{"run": float64(g.runs)},
+		Backend:    toolmodel.ToolBackend{Kind: "mcp"},
+	}, nil
+}
+
+func (g *liveGateway) RunChain(_ context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	results := make([]toolrun.StepResult, len(steps))
+	for i, step := range steps {
+		results[i] = toolrun.StepResult{ToolID: step.ToolID, Backend: toolmodel.ToolBackend{Kind: "mcp"}}
+	}
+	results[len(results)-1].Err = errors.New("step failed")
+	return toolrun.RunResult{Structured: "done"}, results, errors.New("chain failed")
+}
+
+// exercise makes one of each gateway call
+func exercise(t *testing.T, gw toolruntime.ToolGateway) []any {
+	t.Helper()
+	ctx := context.Background()
+	var out []any
+	add := func(v any, err error) {
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+		}
+		out = append(out, v, msg)
+	}
+
+	add(gw.SearchTools(ctx, "weather", 5))
+	add(gw.ListNamespaces(ctx))
+	add(gw.DescribeTool(ctx, "ns:weather", tooldocs.DetailFull))
+	add(gw.DescribeTool(ctx, "missing", tooldocs.DetailSummary))
+	add(gw.ListToolExamples(ctx, "ns:weather", 2))
+	add(gw.RunTool(ctx, "ns:weather", map[string]any{"city": "Paris", "days": 3}))
+	add(gw.RunTool(ctx, "ns:weather", map[string]any{"city": "Paris", "days": 3}))
+
+	result, steps, err := gw.RunChain(ctx, []toolrun.ChainStep{{ToolID: "ns:a"}, {ToolID: "ns:b", UsePrevious: true}})
+	stepErrs := make([]string, len(steps))
+	for i := range steps {
+		if steps[i].Err != nil {
+			stepErrs[i] = steps[i].Err.Error()
+			steps[i].Err = nil
+		}
+	}
+	add(result, err)
+	out = append(out, steps, stepErrs)
+	return out
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var fixture bytes.Buffer
+	recorder := NewRecorder(RecorderConfig{Gateway: &liveGateway{}, Writer: &fixture})
+	recorded := exercise(t, recorder)
+	if err := recorder.Err(); err != nil {
+		t.Fatalf("Recorder.Err() = %v", err)
+	}
+
+	interactions, err := Load(&fixture)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(interactions) != 8 {
+		t.Fatalf("interactions = %d, want 8", len(interactions))
+	}
+
+	gw := New(Config{Interactions: interactions})
+	replayed := exercise(t, gw)
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed = %#v\nwant %#v", replayed, recorded)
+	}
+	if unused := gw.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %v, want none", unused)
+	}
+
+	calls := gw.GetToolCalls()
+	if len(calls) != 4 {
+		t.Fatalf("tool calls = %d, want 4", len(calls))
+	}
+	if calls[0].ToolID != "ns:weather" || calls[0].BackendKind != "mcp" || calls[3].ErrorOp != "chain" {
+		t.Errorf("tool calls = %+v", calls)
+	}
+}
+
+func TestReplayUnexpectedCall(t *testing.T) {
+	gw := New(Config{Interactions: []Interaction{
+		{Op: OpRunTool, Request: []byte(`{ "args": {"n": 1}, "id": "ns:a" }`), Response: []byte(`{"Structured": 1}`)},
+	}})
+	ctx := context.Background()
+
+	if _, err := gw.RunTool(ctx, "ns:a", map[string]any{"n": 2}); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("RunTool() with other args error = %v, want ErrUnexpectedCall", err)
+	}
+	if _, err := gw.SearchTools(ctx, "x", 1); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("SearchTools() error = %v, want ErrUnexpectedCall", err)
+	}
+	if len(gw.Unused()) != 1 {
+		t.Errorf("Unused() = %d, want 1", len(gw.Unused()))
+	}
+
+	// Hand-formatted requests still match
+	result, err := gw.RunTool(ctx, "ns:a", map[string]any{"n": 1})
+	if err != nil || result.Structured != float64(1) {
+		t.Errorf("RunTool() = %+v, %v; want recorded result", result, err)
+	}
+	if _, err := gw.RunTool(ctx, "ns:a", map[string]any{"n": 1}); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("RunTool() after fixture exhausted error = %v, want ErrUnexpectedCall", err)
+	}
+	if len(gw.GetToolCalls()) != 1 {
+		t.Errorf("tool calls = %d, want 1", len(gw.GetToolCalls()))
+	}
+}
+
+func TestReplayCanceled(t *testing.T) {
+	gw := New(Config{Interactions: []Interaction{{Op: OpListNamespaces, Request: []byte(`{}`)}}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := gw.ListNamespaces(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListNamespaces() error = %v, want Canceled", err)
+	}
+	if len(gw.Unused()) != 1 {
+		t.Error("canceled call should not consume the interaction")
+	}
+}
+
+func TestLoadInvalidFixture(t *testing.T) {
+	_, err := Load(strings.NewReader(`{"op":"run_tool","request":{}}` + "\n" + "not json\n"))
+	if !errors.Is(err, ErrInvalidFixture) {
+		t.Errorf("Load() error = %v, want ErrInvalidFixture", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	recorder := NewRecorder(RecorderConfig{Gateway: &liveGateway{}, Writer: failingWriter{}})
+	if _, err := recorder.ListNamespaces(context.Background()); err != nil {
+		t.Fatalf("ListNamespaces() error = %v, want the live result", err)
+	}
+	if err := recorder.Err(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Err() = %v, want write error", err)
+	}
+}
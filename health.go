@@ -0,0 +1,383 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for HealthConfig.
+const (
+	defaultHealthInterval     = 10 * time.Second
+	defaultHealthTimeout      = 5 * time.Second
+	defaultFailureThreshold   = 3
+	defaultCircuitOpenTimeout = 30 * time.Second
+)
+
+// HealthProber is an optional extension to Backend for backends that can
+// check the availability of their underlying runtime without executing code.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: return nil when the backend can accept executions.
+type HealthProber interface {
+	Backend
+
+	// HealthCheck probes the backend's underlying runtime.
+	HealthCheck(ctx context.Context) error
+}
+
+// CircuitState is the state of a backend's circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets executions through.
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen rejects executions until a probe succeeds or the open
+	// timeout elapses.
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets a single trial execution through.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// BackendHealth is a snapshot of a backend's health.
+type BackendHealth struct {
+	// Kind identifies the backend.
+	Kind BackendKind
+
+	// Healthy reports whether the last probe or execution succeeded.
+	Healthy bool
+
+	// Circuit is the circuit breaker state.
+	Circuit CircuitState
+
+	// ConsecutiveFailures counts failed probes and executions since the
+	// last success.
+	ConsecutiveFailures int
+
+	// LastError is the most recent failure, if any.
+	LastError error
+
+	// LastProbe is when the backend was last probed.
+	LastProbe time.Time
+}
+
+// HealthConfig configures a HealthMonitor.
+type HealthConfig struct {
+	// Interval is the time between background probes.
+	// Default: 10s
+	Interval time.Duration
+
+	// Timeout bounds each probe.
+	// Default: 5s
+	Timeout time.Duration
+
+	// FailureThreshold is the number of consecutive failures that opens a
+	// backend's circuit.
+	// Default: 3
+	FailureThreshold int
+
+	// OpenTimeout is how long a circuit stays open before a trial execution
+	// is let through. A successful probe closes it earlier.
+	// Default: 30s
+	OpenTimeout time.Duration
+
+	// Now returns the current time. Default: time.Now
+	Now func() time.Time
+
+	// Logger is an optional logger for circuit state changes.
+	Logger Logger
+}
+
+// HealthMonitor probes backends in the background and keeps a circuit
+// breaker per watched backend. Backends that implement HealthProber are
+// probed; all backends are tracked through the outcome of their executions.
+// Backends are told apart by equality, so two docker backends for different
+// daemons have separate circuits; backends whose type is not comparable are
+// not watched. Set RuntimeConfig.Health to have DefaultRuntime skip backends
+// whose circuit is open.
+type HealthMonitor struct {
+	interval         time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+	logger           Logger
+
+	mu       sync.Mutex
+	backends []*backendHealth // in watch order
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// backendHealth is the mutable health state of one backend.
+type backendHealth struct {
+	backend  Backend
+	health   BackendHealth
+	openedAt time.Time
+	trial    bool // a half-open trial execution is in flight
+}
+
+// NewHealthMonitor creates a HealthMonitor that probes watched backends
+// every Interval. Call Probe to check them immediately, e.g. at startup,
+// and Close to stop background probing.
+func NewHealthMonitor(cfg HealthConfig) *HealthMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultHealthInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHealthTimeout
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultCircuitOpenTimeout
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	m := &HealthMonitor{
+		interval:         cfg.Interval,
+		timeout:          cfg.Timeout,
+		failureThreshold: cfg.FailureThreshold,
+		openTimeout:      cfg.OpenTimeout,
+		now:              cfg.Now,
+		logger:           cfg.Logger,
+		stop:             make(chan struct{}),
+	}
+	m.wg.Add(1)
+	go m.probeLoop()
+	return m
+}
+
+// Watch adds backend to the monitor. Watching a backend again keeps its
+// health state. Nil and non-comparable backends are ignored.
+func (m *HealthMonitor) Watch(backend Backend) {
+	if backend == nil || !reflect.ValueOf(backend).Comparable() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lookupLocked(backend) != nil {
+		return
+	}
+	m.backends = append(m.backends, &backendHealth{
+		backend: backend,
+		health:  BackendHealth{Kind: backend.Kind(), Healthy: true, Circuit: CircuitClosed},
+	})
+}
+
+// Unwatch removes backend and its health state from the monitor, e.g. once
+// it is no longer registered with a runtime.
+func (m *HealthMonitor) Unwatch(backend Backend) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backends = slices.DeleteFunc(m.backends, func(h *backendHealth) bool {
+		return sameBackend(h.backend, backend)
+	})
+}
+
+// Probe checks every watched backend that implements HealthProber, in
+// parallel, and updates their circuits.
+func (m *HealthMonitor) Probe(ctx context.Context) {
+	m.mu.Lock()
+	var probers []HealthProber
+	for _, h := range m.backends {
+		if prober, ok := h.backend.(HealthProber); ok {
+			probers = append(probers, prober)
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, prober := range probers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+			_, span := StartSpan(probeCtx, "toolruntime.health_probe", Attr("toolruntime.backend", string(prober.Kind())))
+			err := prober.HealthCheck(probeCtx)
+			span.RecordError(err)
+			span.End()
+			m.record(prober, err, true)
+		}()
+	}
+	wg.Wait()
+}
+
+// Status returns the health of all watched backends, ordered by kind and
+// then by the order they were watched in.
+func (m *HealthMonitor) Status() []BackendHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]BackendHealth, 0, len(m.backends))
+	for _, h := range m.backends {
+		out = append(out, h.snapshot(m.now(), m.openTimeout))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// Health returns the health of backend and whether it is watched.
+func (m *HealthMonitor) Health(backend Backend) (BackendHealth, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.lookupLocked(backend)
+	if h == nil {
+		return BackendHealth{}, false
+	}
+	return h.snapshot(m.now(), m.openTimeout), true
+}
+
+// Close stops background probing.
+func (m *HealthMonitor) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	m.wg.Wait()
+	return nil
+}
+
+// lookupLocked returns the health state of backend, or nil if it is not
+// watched. The caller must hold m.mu.
+func (m *HealthMonitor) lookupLocked(backend Backend) *backendHealth {
+	for _, h := range m.backends {
+		if sameBackend(h.backend, backend) {
+			return h
+		}
+	}
+	return nil
+}
+
+func (m *HealthMonitor) probeLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.Probe(context.Background())
+		}
+	}
+}
+
+// allow reports whether an execution may use backend. An open circuit
+// admits a single trial execution once OpenTimeout has elapsed. Unwatched
+// backends are always allowed.
+func (m *HealthMonitor) allow(backend Backend) bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.lookupLocked(backend)
+	if h == nil {
+		return true
+	}
+	switch h.health.Circuit {
+	case CircuitOpen:
+		if m.now().Sub(h.openedAt) < m.openTimeout {
+			return false
+		}
+		h.health.Circuit = CircuitHalfOpen
+		h.trial = true
+		return true
+	case CircuitHalfOpen:
+		if h.trial {
+			return false
+		}
+		h.trial = true
+		return true
+	}
+	return true
+}
+
+// recordExecution updates the backend's circuit with the outcome of an
+// execution. Only infrastructure failures (ErrRuntimeUnavailable or
+// retryable errors) count against the backend; other errors are caused by
+// the request and count as a success.
+func (m *HealthMonitor) recordExecution(backend Backend, err error) {
+	if m == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrQueueFull) || errors.Is(err, ErrQueueTimeout) {
+		// The backend was never reached or the caller gave up; this says
+		// nothing about the backend
+		m.mu.Lock()
+		if h := m.lookupLocked(backend); h != nil {
+			h.trial = false
+		}
+		m.mu.Unlock()
+		return
+	}
+	if err != nil && !errors.Is(err, ErrRuntimeUnavailable) && !IsRetryable(err) {
+		err = nil
+	}
+	m.record(backend, err, false)
+}
+
+// record updates the backend's health with a probe or execution outcome.
+func (m *HealthMonitor) record(backend Backend, err error, probe bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.lookupLocked(backend)
+	if h == nil {
+		return
+	}
+	kind := h.health.Kind
+	now := m.now()
+	if probe {
+		h.health.LastProbe = now
+	}
+	if !probe {
+		h.trial = false
+	}
+
+	if err == nil {
+		if h.health.Circuit != CircuitClosed && m.logger != nil {
+			m.logger.Info("circuit closed", "backend", kind)
+		}
+		h.health.Healthy = true
+		h.health.Circuit = CircuitClosed
+		h.health.ConsecutiveFailures = 0
+		h.health.LastError = nil
+		return
+	}
+
+	h.health.Healthy = false
+	h.health.ConsecutiveFailures++
+	h.health.LastError = err
+	if h.health.Circuit == CircuitHalfOpen || (h.health.Circuit == CircuitClosed && h.health.ConsecutiveFailures >= m.failureThreshold) {
+		if m.logger != nil {
+			m.logger.Warn("circuit opened", "backend", kind, "failures", h.health.ConsecutiveFailures, "error", err)
+		}
+		h.health.Circuit = CircuitOpen
+		h.openedAt = now
+	}
+}
+
+// snapshot returns the health, reporting an expired open circuit as half-open.
+func (h *backendHealth) snapshot(now time.Time, openTimeout time.Duration) BackendHealth {
+	health := h.health
+	if health.Circuit == CircuitOpen && now.Sub(h.openedAt) >= openTimeout {
+		health.Circuit = CircuitHalfOpen
+	}
+	return health
+}
@@ -0,0 +1,333 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// proberBackend reports a configurable health check result
+type proberBackend struct {
+	countingBackend
+	mu  sync.Mutex
+	err error
+}
+
+func (b *proberBackend) HealthCheck(_ context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+func (b *proberBackend) setErr(err error) {
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+}
+
+// fakeClock is a settable time source
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestHealthMonitor(t *testing.T, clock *fakeClock) *HealthMonitor {
+	t.Helper()
+	m := NewHealthMonitor(HealthConfig{
+		Interval:         time.Hour,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		Now:              clock.Now,
+	})
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+func TestHealthMonitorProbe(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	m := newTestHealthMonitor(t, clock)
+	backend := &proberBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+	m.Watch(backend)
+
+	backend.setErr(errors.New("daemon down"))
+	m.Probe(context.Background())
+	if h, _ := m.Health(backend); h.Healthy || h.Circuit != CircuitClosed || h.ConsecutiveFailures != 1 {
+		t.Fatalf("after one failure: %+v, want unhealthy and closed", h)
+	}
+
+	m.Probe(context.Background())
+	h, _ := m.Health(backend)
+	if h.Circuit != CircuitOpen || h.LastError == nil || !h.LastProbe.Equal(clock.Now()) {
+		t.Fatalf("after threshold: %+v, want open", h)
+	}
+	if m.allow(backend) {
+		t.Error("allow() with open circuit = true, want false")
+	}
+
+	backend.setErr(nil)
+	m.Probe(context.Background())
+	if h, _ := m.Health(backend); !h.Healthy || h.Circuit != CircuitClosed || h.ConsecutiveFailures != 0 {
+		t.Errorf("after successful probe: %+v, want healthy and closed", h)
+	}
+	if !m.allow(backend) {
+		t.Error("allow() with closed circuit = false, want true")
+	}
+}
+
+func TestHealthMonitorHalfOpen(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	m := newTestHealthMonitor(t, clock)
+	backend := &mockBackend{kind: BackendGVisor}
+	m.Watch(backend)
+
+	unavailable := NewRuntimeError(BackendGVisor, "execute", ErrRuntimeUnavailable, true)
+	m.recordExecution(backend, unavailable)
+	m.recordExecution(backend, unavailable)
+	if m.allow(backend) {
+		t.Fatal("allow() with open circuit = true, want false")
+	}
+
+	clock.Advance(time.Minute)
+	if h, _ := m.Health(backend); h.Circuit != CircuitHalfOpen {
+		t.Errorf("Circuit after OpenTimeout = %q, want half_open", h.Circuit)
+	}
+	if !m.allow(backend) {
+		t.Fatal("allow() after OpenTimeout = false, want a trial")
+	}
+	if m.allow(backend) {
+		t.Error("allow() during trial = true, want false")
+	}
+
+	// A failed trial reopens the circuit
+	m.recordExecution(backend, unavailable)
+	if h, _ := m.Health(backend); h.Circuit != CircuitOpen {
+		t.Errorf("Circuit after failed trial = %q, want open", h.Circuit)
+	}
+
+	// A successful trial closes it
+	clock.Advance(time.Minute)
+	if !m.allow(backend) {
+		t.Fatal("allow() after OpenTimeout = false, want a trial")
+	}
+	m.recordExecution(backend, nil)
+	if h, _ := m.Health(backend); h.Circuit != CircuitClosed {
+		t.Errorf("Circuit after successful trial = %q, want closed", h.Circuit)
+	}
+}
+
+func TestHealthMonitorIgnoresRequestErrors(t *testing.T) {
+	m := newTestHealthMonitor(t, &fakeClock{})
+	backend := &mockBackend{kind: BackendDocker}
+	m.Watch(backend)
+
+	for range 5 {
+		m.recordExecution(backend, ErrSandboxViolation)
+		m.recordExecution(backend, context.Canceled)
+	}
+	if h, _ := m.Health(backend); !h.Healthy || h.Circuit != CircuitClosed {
+		t.Errorf("Health() = %+v, want healthy and closed", h)
+	}
+}
+
+func TestHealthMonitorStatus(t *testing.T) {
+	m := newTestHealthMonitor(t, &fakeClock{})
+	m.Watch(&mockBackend{kind: BackendWASM})
+	m.Watch(&mockBackend{kind: BackendDocker})
+	m.Watch(nil)
+
+	status := m.Status()
+	if len(status) != 2 || status[0].Kind != BackendDocker || status[1].Kind != BackendWASM {
+		t.Errorf("Status() = %+v, want docker then wasm", status)
+	}
+	unwatched := &mockBackend{kind: BackendGVisor}
+	if _, ok := m.Health(unwatched); ok {
+		t.Error("Health(unwatched) ok = true, want false")
+	}
+	if !m.allow(unwatched) {
+		t.Error("allow(unwatched) = false, want true")
+	}
+}
+
+func TestHealthMonitorPerBackend(t *testing.T) {
+	m := newTestHealthMonitor(t, &fakeClock{})
+	primary := &proberBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+	fallback := &proberBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+	m.Watch(primary)
+	m.Watch(fallback)
+	m.Watch(primary)
+
+	primary.setErr(errors.New("daemon down"))
+	m.Probe(context.Background())
+	m.Probe(context.Background())
+	if h, _ := m.Health(primary); h.Circuit != CircuitOpen {
+		t.Errorf("primary circuit = %q, want open", h.Circuit)
+	}
+	if h, _ := m.Health(fallback); h.Circuit != CircuitClosed || !h.Healthy {
+		t.Errorf("fallback health = %+v, want healthy and closed", h)
+	}
+	if status := m.Status(); len(status) != 2 {
+		t.Errorf("Status() = %+v, want one entry per backend", status)
+	}
+
+	m.Unwatch(primary)
+	if _, ok := m.Health(primary); ok {
+		t.Error("Health(unwatched) ok = true, want false")
+	}
+	if !m.allow(primary) {
+		t.Error("allow(unwatched) = false, want true")
+	}
+	if status := m.Status(); len(status) != 1 {
+		t.Errorf("Status() after Unwatch = %+v, want only the fallback", status)
+	}
+}
+
+func TestHealthMonitorBackgroundProbe(t *testing.T) {
+	m := NewHealthMonitor(HealthConfig{Interval: time.Millisecond, FailureThreshold: 1})
+	defer m.Close()
+	backend := &proberBackend{
+		countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}},
+		err:             errors.New("daemon down"),
+	}
+	m.Watch(backend)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if h, _ := m.Health(backend); h.Circuit == CircuitOpen {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("background probe did not open the circuit")
+}
+
+func TestDefaultRuntimeHealth(t *testing.T) {
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	t.Run("fails fast", func(t *testing.T) {
+		m := newTestHealthMonitor(t, &fakeClock{})
+		backend := &proberBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+			Health:         m,
+			DefaultProfile: ProfileStandard,
+		})
+
+		backend.setErr(errors.New("daemon down"))
+		m.Probe(context.Background())
+		m.Probe(context.Background())
+
+		_, err := rt.Execute(context.Background(), req)
+		if !errors.Is(err, ErrRuntimeUnavailable) {
+			t.Fatalf("Execute() error = %v, want ErrRuntimeUnavailable", err)
+		}
+		if backend.calls != 0 {
+			t.Errorf("backend calls = %d, want 0", backend.calls)
+		}
+
+		backend.setErr(nil)
+		m.Probe(context.Background())
+		if _, err := rt.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute() after recovery error = %v", err)
+		}
+	})
+
+	t.Run("execution failures fall through", func(t *testing.T) {
+		m := newTestHealthMonitor(t, &fakeClock{})
+		unavailable := NewRuntimeError(BackendDocker, "container_run", ErrRuntimeUnavailable, true)
+		primary := &flakyBackend{
+			mockBackend: mockBackend{kind: BackendDocker},
+			errs:        []error{unavailable, unavailable, unavailable},
+		}
+		fallback := &countingBackend{mockBackend: mockBackend{kind: BackendGVisor}}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+			Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+			Health:         m,
+			DefaultProfile: ProfileStandard,
+		})
+
+		for range 3 {
+			result, err := rt.Execute(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Backend.Kind != BackendGVisor {
+				t.Errorf("backend = %q, want gvisor", result.Backend.Kind)
+			}
+		}
+		if primary.calls != 2 {
+			t.Errorf("primary calls = %d, want 2 (circuit opens after threshold)", primary.calls)
+		}
+		if fallback.calls != 3 {
+			t.Errorf("fallback calls = %d, want 3", fallback.calls)
+		}
+	})
+
+	t.Run("same kind fallback keeps its circuit", func(t *testing.T) {
+		m := newTestHealthMonitor(t, &fakeClock{})
+		primary := &proberBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+		fallback := &proberBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+			Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+			Health:         m,
+			DefaultProfile: ProfileStandard,
+		})
+
+		primary.setErr(errors.New("daemon down"))
+		m.Probe(context.Background())
+		m.Probe(context.Background())
+
+		if _, err := rt.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if primary.calls != 0 || fallback.calls != 1 {
+			t.Errorf("calls = %d primary, %d fallback; want 0 and 1", primary.calls, fallback.calls)
+		}
+	})
+
+	t.Run("retired backends are unwatched", func(t *testing.T) {
+		m := newTestHealthMonitor(t, &fakeClock{})
+		primary := &mockBackend{kind: BackendDocker}
+		fallback := &mockBackend{kind: BackendGVisor}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+			Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+			Health:         m,
+			DefaultProfile: ProfileStandard,
+		})
+
+		replacement := &mockBackend{kind: BackendDocker}
+		rt.RegisterBackend(ProfileStandard, replacement)
+		if _, ok := m.Health(primary); ok {
+			t.Error("replaced backend is still watched")
+		}
+		if _, ok := m.Health(replacement); !ok {
+			t.Error("registered backend is not watched")
+		}
+
+		rt.SetFallbacks(ProfileStandard)
+		if _, ok := m.Health(fallback); ok {
+			t.Error("removed fallback is still watched")
+		}
+
+		rt.UnregisterBackend(ProfileStandard)
+		if status := m.Status(); len(status) != 0 {
+			t.Errorf("Status() = %+v, want no watched backends", status)
+		}
+	})
+}
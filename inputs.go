@@ -0,0 +1,24 @@
+package toolruntime
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// InputsEnv is the environment variable through which backends pass
+// ExecuteRequest.Inputs, JSON-encoded, to executed code. Language wrappers
+// decode it into the __in variable.
+const InputsEnv = "TOOLRUNTIME_INPUTS"
+
+// EncodeInputs returns the JSON encoding of inputs passed in InputsEnv. Nil
+// inputs encode as an empty object, so __in is always a map.
+func EncodeInputs(inputs map[string]any) ([]byte, error) {
+	if inputs == nil {
+		inputs = map[string]any{}
+	}
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInputs, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,29 @@
+package toolruntime
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncodeInputs(t *testing.T) {
+	data, err := EncodeInputs(nil)
+	if err != nil || string(data) != "{}" {
+		t.Errorf("EncodeInputs(nil) = %s, %v; want {}", data, err)
+	}
+	data, err = EncodeInputs(map[string]any{"rows": []int{1, 2}, "name": "x"})
+	if err != nil || string(data) != `{"name":"x","rows":[1,2]}` {
+		t.Errorf("EncodeInputs() = %s, %v", data, err)
+	}
+}
+
+func TestExecuteRequestValidateInputs(t *testing.T) {
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Inputs: map[string]any{"fn": func() {}}}
+	if err := req.Validate(); !errors.Is(err, ErrInvalidInputs) {
+		t.Errorf("Validate() error = %v, want ErrInvalidInputs", err)
+	}
+
+	req.Inputs = map[string]any{"n": 1}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package toolruntime
+
+import "context"
+
+// ExecuteFunc executes a request. It is the signature shared by
+// Runtime.Execute and Backend.Execute.
+type ExecuteFunc func(ctx context.Context, req ExecuteRequest) (ExecuteResult, error)
+
+// Interceptor wraps request execution. It may inspect or rewrite the request,
+// short-circuit by returning without calling next, or post-process the
+// result returned by next.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must pass ctx (or a derived context) to next.
+// - Ownership: req is passed by value; maps and slices inside it are shared
+//   with the caller and must be copied before mutation.
+type Interceptor func(ctx context.Context, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error)
+
+// BackendInterceptor wraps a single backend invocation. It runs once per
+// attempted backend, including fallbacks, and receives the selected backend.
+//
+// Contract: same as Interceptor.
+type BackendInterceptor func(ctx context.Context, backend Backend, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error)
+
+// ChainInterceptors composes interceptors into a single Interceptor.
+// The first interceptor is the outermost. Nil interceptors are skipped.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error) {
+		return chainExecute(interceptors, next)(ctx, req)
+	}
+}
+
+// chainExecute returns an ExecuteFunc that runs interceptors around final.
+func chainExecute(interceptors []Interceptor, final ExecuteFunc) ExecuteFunc {
+	next := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic := interceptors[i]
+		if ic == nil {
+			continue
+		}
+		inner := next
+		next = func(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+			return ic(ctx, req, inner)
+		}
+	}
+	return next
+}
+
+// chainBackend returns an ExecuteFunc that runs interceptors around final
+// for the given backend.
+func chainBackend(interceptors []BackendInterceptor, backend Backend, final ExecuteFunc) ExecuteFunc {
+	next := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic := interceptors[i]
+		if ic == nil {
+			continue
+		}
+		inner := next
+		next = func(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+			return ic(ctx, backend, req, inner)
+		}
+	}
+	return next
+}
+
+// WrapRuntime returns a Runtime that runs interceptors around rt.Execute.
+// The first interceptor is the outermost.
+func WrapRuntime(rt Runtime, interceptors ...Interceptor) Runtime {
+	return &interceptedRuntime{execute: chainExecute(interceptors, rt.Execute)}
+}
+
+// interceptedRuntime is the Runtime returned by WrapRuntime.
+type interceptedRuntime struct {
+	execute ExecuteFunc
+}
+
+// Execute implements the Runtime interface.
+func (r *interceptedRuntime) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	return r.execute(ctx, req)
+}
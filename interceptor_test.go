@@ -0,0 +1,158 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func recordingInterceptor(name string, calls *[]string) Interceptor {
+	return func(ctx context.Context, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error) {
+		*calls = append(*calls, name+":before")
+		result, err := next(ctx, req)
+		*calls = append(*calls, name+":after")
+		return result, err
+	}
+}
+
+func TestDefaultRuntimeInterceptorOrder(t *testing.T) {
+	var calls []string
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &mockBackend{kind: BackendUnsafeHost},
+		},
+		Interceptors: []Interceptor{
+			recordingInterceptor("outer", &calls),
+			nil,
+			recordingInterceptor("inner", &calls),
+		},
+		DefaultProfile: ProfileDev,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []string{"outer:before", "inner:before", "inner:after", "outer:after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestDefaultRuntimeInterceptorShortCircuit(t *testing.T) {
+	errUnauthorized := errors.New("unauthorized")
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{ProfileDev: backend},
+		Interceptors: []Interceptor{
+			func(_ context.Context, req ExecuteRequest, _ ExecuteFunc) (ExecuteResult, error) {
+				if req.Metadata["user"] == nil {
+					return ExecuteResult{}, errUnauthorized
+				}
+				return ExecuteResult{}, nil
+			},
+		},
+		DefaultProfile: ProfileDev,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if !errors.Is(err, errUnauthorized) {
+		t.Errorf("Execute() error = %v, want %v", err, errUnauthorized)
+	}
+	if backend.calls != 0 {
+		t.Errorf("backend calls = %d, want 0", backend.calls)
+	}
+}
+
+func TestDefaultRuntimeInterceptorRewrites(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev:      &mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "dev"}},
+			ProfileStandard: &mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "standard"}},
+		},
+		Interceptors: []Interceptor{
+			func(ctx context.Context, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error) {
+				req.Profile = ProfileStandard
+				result, err := next(ctx, req)
+				result.Value = fmt.Sprintf("%v!", result.Value)
+				return result, err
+			},
+		},
+		DefaultProfile: ProfileDev,
+	})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "standard!" {
+		t.Errorf("Execute().Value = %v, want %q", result.Value, "standard!")
+	}
+}
+
+func TestDefaultRuntimeBackendInterceptors(t *testing.T) {
+	var kinds []BackendKind
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: &mockBackend{kind: BackendGVisor, executeErr: fmt.Errorf("%w: down", ErrRuntimeUnavailable)},
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileStandard: {&mockBackend{kind: BackendDocker}},
+		},
+		BackendInterceptors: []BackendInterceptor{
+			func(ctx context.Context, backend Backend, req ExecuteRequest, next ExecuteFunc) (ExecuteResult, error) {
+				kinds = append(kinds, backend.Kind())
+				return next(ctx, req)
+			},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []BackendKind{BackendGVisor, BackendDocker}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("intercepted kinds = %v, want %v", kinds, want)
+	}
+}
+
+func TestWrapRuntime(t *testing.T) {
+	var calls []string
+	inner := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &mockBackend{kind: BackendUnsafeHost},
+		},
+		DefaultProfile: ProfileDev,
+	})
+	rt := WrapRuntime(inner,
+		ChainInterceptors(recordingInterceptor("a", &calls), recordingInterceptor("b", &calls)),
+		recordingInterceptor("c", &calls),
+	)
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []string{"a:before", "b:before", "c:before", "c:after", "b:after", "a:after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+// countingBackend counts Execute calls
+type countingBackend struct {
+	mockBackend
+	calls int
+}
+
+func (b *countingBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	b.calls++
+	return b.mockBackend.Execute(ctx, req)
+}
@@ -0,0 +1,409 @@
+package toolruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Language describes how backends run code written in one programming
+// language: how a snippet is wrapped with the __in/__out plumbing and the
+// result envelope, where the program is written and how it is run.
+type Language struct {
+	// Name identifies the language in ExecuteRequest.Language, such as
+	// "python". Names are matched case-insensitively.
+	Name string
+
+	// Aliases are alternative names, such as "py".
+	Aliases []string
+
+	// Extension is the extension of the program file, including the dot.
+	Extension string
+
+	// Image is the default container image for the language.
+	Image string
+
+	// Command runs the program from the directory holding it. Command[0]
+	// is the interpreter or toolchain.
+	Command []string
+
+	// Files are support files written next to the program unless the
+	// request provides them, such as go.mod.
+	Files map[string]string
+
+	// Wrap returns the program that runs code. It decodes InputsEnv into
+	// __in and writes __out, __meta and __err in the result envelope.
+	Wrap func(code string) string
+
+	// Detect reports whether code looks like it is written in the
+	// language. It is only consulted by LanguageRegistry.Detect; requests
+	// that name no language run in the first registered language.
+	Detect func(code string) bool
+}
+
+// SourceFile returns the name of the file the wrapped program is written to.
+func (l Language) SourceFile() string {
+	return "main" + l.Extension
+}
+
+// LanguageRegistry maps language names to Languages. It is safe for
+// concurrent use.
+type LanguageRegistry struct {
+	mu      sync.RWMutex
+	langs   []Language
+	byAlias map[string]int
+}
+
+// NewLanguageRegistry returns an empty registry.
+func NewLanguageRegistry() *LanguageRegistry {
+	return &LanguageRegistry{byAlias: make(map[string]int)}
+}
+
+// DefaultLanguages returns a new registry with the built-in languages: Go,
+// JavaScript, Python and shell. Go is the default language.
+func DefaultLanguages() *LanguageRegistry {
+	r := NewLanguageRegistry()
+	for _, lang := range []Language{goLanguage(), javaScriptLanguage(), pythonLanguage(), shellLanguage()} {
+		_ = r.Register(lang)
+	}
+	return r
+}
+
+// Register adds lang, replacing a registered language of the same name. It
+// fails if lang has no name, command or wrapper, or if one of its names
+// belongs to another language.
+func (r *LanguageRegistry) Register(lang Language) error {
+	if lang.Name == "" || len(lang.Command) == 0 || lang.Wrap == nil {
+		return fmt.Errorf("language %q: name, command and wrapper are required", lang.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx, replace := r.byAlias[strings.ToLower(lang.Name)]
+	if replace && !strings.EqualFold(r.langs[idx].Name, lang.Name) {
+		return fmt.Errorf("language %q: name is an alias of %q", lang.Name, r.langs[idx].Name)
+	}
+	if !replace {
+		idx = len(r.langs)
+	}
+	for _, alias := range lang.Aliases {
+		if i, ok := r.byAlias[strings.ToLower(alias)]; ok && i != idx {
+			return fmt.Errorf("language %q: alias %q belongs to %q", lang.Name, alias, r.langs[i].Name)
+		}
+	}
+
+	if replace {
+		for alias, i := range r.byAlias {
+			if i == idx {
+				delete(r.byAlias, alias)
+			}
+		}
+		r.langs[idx] = lang
+	} else {
+		r.langs = append(r.langs, lang)
+	}
+	for _, name := range append([]string{lang.Name}, lang.Aliases...) {
+		r.byAlias[strings.ToLower(name)] = idx
+	}
+	return nil
+}
+
+// Lookup returns the language with the given name or alias.
+func (r *LanguageRegistry) Lookup(name string) (Language, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idx, ok := r.byAlias[strings.ToLower(name)]
+	if !ok {
+		return Language{}, false
+	}
+	return r.langs[idx], true
+}
+
+// Names returns the names of the registered languages in registration order.
+func (r *LanguageRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, len(r.langs))
+	for i, lang := range r.langs {
+		names[i] = lang.Name
+	}
+	return names
+}
+
+// Detect returns the first registered language whose heuristics match code.
+// The heuristics look for plain substrings and can be fooled by string
+// literals, so Resolve never uses them; callers opt in by naming the result.
+func (r *LanguageRegistry) Detect(code string) (Language, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, lang := range r.langs {
+		if lang.Detect != nil && lang.Detect(code) {
+			return lang, true
+		}
+	}
+	return Language{}, false
+}
+
+// Resolve returns the language of a request: the named language, or, when
+// name is empty, the first registered language. Code is never guessed at;
+// callers that want detection call Detect and name its result. Unknown
+// names fail with ErrInvalidRequest.
+func (r *LanguageRegistry) Resolve(name string) (Language, error) {
+	if name != "" {
+		lang, ok := r.Lookup(name)
+		if !ok {
+			return Language{}, fmt.Errorf("%w: unknown language %q", ErrInvalidRequest, name)
+		}
+		return lang, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.langs) == 0 {
+		return Language{}, fmt.Errorf("%w: no languages registered", ErrInvalidRequest)
+	}
+	return r.langs[0], nil
+}
+
+// containsAny reports whether code contains one of the markers.
+func containsAny(code string, markers ...string) bool {
+	return slices.ContainsFunc(markers, func(m string) bool {
+		return strings.Contains(code, m)
+	})
+}
+
+// quoteSource returns code as a JSON string literal, which is also a valid
+// Python and JavaScript string literal.
+func quoteSource(code string) string {
+	data, _ := json.Marshal(code)
+	return string(data)
+}
+
+// goLanguage runs Go with `go run`. Complete programs with a package clause
+// and a main function are run unchanged.
+func goLanguage() Language {
+	return Language{
+		Name:      "go",
+		Aliases:   []string{"golang"},
+		Extension: ".go",
+		Image:     "golang:1.22-alpine",
+		Command:   []string{"go", "run", "."},
+		Files:     map[string]string{"go.mod": "module toolruntime_exec\n\ngo 1.21\n"},
+		Wrap:      wrapGo,
+		Detect: func(code string) bool {
+			return containsAny(code, "package main", ":=", "fmt.")
+		},
+	}
+}
+
+func wrapGo(code string) string {
+	if strings.Contains(code, "package ") && strings.Contains(code, "func main()") {
+		return code
+	}
+
+	return fmt.Sprintf(`package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var __in map[string]any
+	_ = json.Unmarshal([]byte(os.Getenv(%q)), &__in)
+	_ = __in
+	var __out any
+	var __meta map[string]any
+	var __err error
+
+	// Write the result envelope, even if the code returns early or panics
+	defer func() {
+		env := map[string]any{}
+		if r := recover(); r != nil {
+			env["error"] = map[string]string{"type": "panic", "message": fmt.Sprint(r)}
+		} else if __err != nil {
+			env["error"] = map[string]string{"message": __err.Error()}
+		}
+		if __out != nil {
+			env["value"] = __out
+		}
+		if __meta != nil {
+			env["metadata"] = __meta
+		}
+		data, err := json.Marshal(env)
+		if err != nil {
+			data, _ = json.Marshal(map[string]any{"error": map[string]string{"type": "encoding", "message": err.Error()}})
+		}
+		fmt.Printf("\n%%s%%s:%%s\n", %q, os.Getenv(%q), data)
+		if env["error"] != nil {
+			os.Exit(1)
+		}
+	}()
+
+	// User code starts here
+	%s
+	// User code ends here
+}
+`, InputsEnv, ResultMarkerPrefix, ResultNonceEnv, code)
+}
+
+// pythonLanguage runs Python 3. The code is executed in the module
+// namespace, so __out, __meta and __err are plain globals; an uncaught
+// exception is reported with its class as the error type, and sys.exit()
+// with a non-zero status as an exit error.
+func pythonLanguage() Language {
+	return Language{
+		Name:      "python",
+		Aliases:   []string{"py", "python3"},
+		Extension: ".py",
+		Image:     "python:3.12-slim",
+		Command:   []string{"python3", "main.py"},
+		Wrap:      wrapPython,
+		Detect: func(code string) bool {
+			return containsAny(code, "print(", "def ", "import ", "elif ", "None")
+		},
+	}
+}
+
+func wrapPython(code string) string {
+	return fmt.Sprintf(`import json as __json, os as __os, sys as __sys, traceback as __traceback
+
+__in = __json.loads(__os.environ.get(%q) or "{}")
+__out = None
+__meta = None
+__err = None
+
+
+def __envelope(error=None):
+    env = {}
+    if error is not None:
+        env["error"] = error
+    if __out is not None:
+        env["value"] = __out
+    if __meta is not None:
+        env["metadata"] = __meta
+    try:
+        data = __json.dumps(env, default=str)
+    except Exception as e:
+        data = __json.dumps({"error": {"type": "encoding", "message": str(e)}})
+    __sys.stdout.write("\n" + %q + __os.environ.get(%q, "") + ":" + data + "\n")
+    __sys.stdout.flush()
+
+
+# The envelope is written on every exit, including sys.exit()
+__failure = None
+try:
+    try:
+        exec(compile(%s, "<code>", "exec"), globals())
+    except SystemExit as e:
+        if e.code not in (None, 0):
+            __failure = {"type": "exit", "message": "exit status " + str(e.code)}
+        raise
+    except BaseException as e:
+        __traceback.print_exc()
+        __failure = {"type": type(e).__name__, "message": str(e)}
+        raise SystemExit(1)
+    if __err is not None:
+        __failure = {"message": str(__err)}
+        raise SystemExit(1)
+finally:
+    __envelope(__failure)
+`, InputsEnv, ResultMarkerPrefix, ResultNonceEnv, quoteSource(code))
+}
+
+// javaScriptLanguage runs JavaScript with Node.js. The code runs in an async
+// function, so it may use await; a thrown error or rejected promise is
+// reported with its name as the error type.
+func javaScriptLanguage() Language {
+	return Language{
+		Name:      "javascript",
+		Aliases:   []string{"js", "node"},
+		Extension: ".js",
+		Image:     "node:22-slim",
+		Command:   []string{"node", "main.js"},
+		Wrap:      wrapJavaScript,
+		Detect: func(code string) bool {
+			return containsAny(code, "console.", "=>", "const ", "let ", "function ", "require(")
+		},
+	}
+}
+
+func wrapJavaScript(code string) string {
+	return fmt.Sprintf(`const __in = JSON.parse(process.env[%s] || "{}");
+let __out, __meta, __err;
+
+function __envelope(error) {
+  const env = {};
+  if (error != null) env.error = error;
+  if (__out !== undefined && __out !== null) env.value = __out;
+  if (__meta != null) env.metadata = __meta;
+  let data;
+  try {
+    data = JSON.stringify(env);
+  } catch (e) {
+    data = JSON.stringify({ error: { type: "encoding", message: String(e) } });
+  }
+  process.stdout.write("\n" + %s + (process.env[%s] || "") + ":" + data + "\n");
+}
+
+(async () => {
+// User code starts here
+%s
+// User code ends here
+})().then(
+  () => {
+    if (__err != null) {
+      __envelope({ message: String(__err && __err.message || __err) });
+      process.exitCode = 1;
+      return;
+    }
+    __envelope();
+  },
+  (e) => {
+    console.error(e);
+    __envelope({ type: (e && e.name) || "Error", message: String(e && e.message || e) });
+    process.exitCode = 1;
+  },
+);
+`, quoteSource(InputsEnv), quoteSource(ResultMarkerPrefix), quoteSource(ResultNonceEnv), code)
+}
+
+// shellLanguage runs POSIX shell scripts. __in holds the JSON-encoded
+// inputs and __out, if set, must hold a JSON value; a non-zero exit status
+// is reported as an error of type "exit".
+func shellLanguage() Language {
+	return Language{
+		Name:      "shell",
+		Aliases:   []string{"sh"},
+		Extension: ".sh",
+		Image:     "alpine:3.20",
+		Command:   []string{"sh", "main.sh"},
+		Wrap:      wrapShell,
+		Detect: func(code string) bool {
+			return strings.HasPrefix(code, "#!/bin/sh") || containsAny(code, "echo ", "$(")
+		},
+	}
+}
+
+func wrapShell(code string) string {
+	return fmt.Sprintf(`__in=$%s
+__out=
+
+__envelope() {
+  __status=$?
+  if [ "$__status" -ne 0 ]; then
+    printf '\n%%s%%s:{"error":{"type":"exit","message":"exit status %%d"}}\n' %s "$%s" "$__status"
+  elif [ -n "$__out" ]; then
+    printf '\n%%s%%s:{"value":%%s}\n' %s "$%s" "$__out"
+  fi
+}
+trap __envelope EXIT
+
+# User code starts here
+%s
+# User code ends here
+`, InputsEnv, ResultMarkerPrefix, ResultNonceEnv, ResultMarkerPrefix, ResultNonceEnv, code)
+}
@@ -0,0 +1,147 @@
+package toolruntime
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefaultLanguages(t *testing.T) {
+	r := DefaultLanguages()
+
+	if got := r.Names(); !slices.Equal(got, []string{"go", "javascript", "python", "shell"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	for _, name := range []string{"go", "golang", "Python", "py", "js", "node", "sh"} {
+		if _, ok := r.Lookup(name); !ok {
+			t.Errorf("Lookup(%q) not found", name)
+		}
+	}
+	// The shell image has no bash, so bash is not an alias of sh
+	for _, name := range []string{"cobol", "bash"} {
+		if _, ok := r.Lookup(name); ok {
+			t.Errorf("Lookup(%q) found", name)
+		}
+	}
+}
+
+func TestLanguageWrappers(t *testing.T) {
+	for _, lang := range DefaultLanguages().langs {
+		t.Run(lang.Name, func(t *testing.T) {
+			code := "answer = 42"
+			wrapped := lang.Wrap(code)
+			if !strings.Contains(wrapped, code) && !strings.Contains(wrapped, quoteSource(code)) {
+				t.Errorf("wrapped program does not contain the code:\n%s", wrapped)
+			}
+			for _, want := range []string{InputsEnv, ResultMarkerPrefix, ResultNonceEnv} {
+				if !strings.Contains(wrapped, want) {
+					t.Errorf("wrapped program does not contain %q:\n%s", want, wrapped)
+				}
+			}
+		})
+	}
+
+	// Complete Go programs are run unchanged
+	program := "package main\n\nfunc main() {}\n"
+	if got := wrapGo(program); got != program {
+		t.Errorf("wrapGo() changed a complete program:\n%s", got)
+	}
+}
+
+func TestLanguageRegistryResolve(t *testing.T) {
+	r := DefaultLanguages()
+
+	tests := []struct {
+		name     string
+		language string
+		want     string
+	}{
+		{"named", "javascript", "javascript"},
+		{"alias", "PY", "python"},
+		{"default", "", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, err := r.Resolve(tt.language)
+			if err != nil || lang.Name != tt.want {
+				t.Errorf("Resolve() = %q, %v; want %q", lang.Name, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := r.Resolve("cobol"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Resolve(cobol) error = %v, want ErrInvalidRequest", err)
+	}
+	if _, err := NewLanguageRegistry().Resolve(""); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Resolve() on empty registry error = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestLanguageRegistryDetect(t *testing.T) {
+	r := DefaultLanguages()
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"x := 1\nfmt.Println(x)", "go"},
+		{"def f():\n    return None", "python"},
+		{"const x = await f();", "javascript"},
+		{"echo $(date)", "shell"},
+	}
+
+	for _, tt := range tests {
+		if lang, ok := r.Detect(tt.code); !ok || lang.Name != tt.want {
+			t.Errorf("Detect(%q) = %q, %v; want %q", tt.code, lang.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestLanguageRegistryRegister(t *testing.T) {
+	r := DefaultLanguages()
+	wrap := func(code string) string { return code }
+
+	ruby := Language{Name: "ruby", Aliases: []string{"rb"}, Extension: ".rb", Command: []string{"ruby", "main.rb"}, Wrap: wrap}
+	if err := r.Register(ruby); err != nil {
+		t.Fatalf("Register(ruby) error = %v", err)
+	}
+	if lang, ok := r.Lookup("rb"); !ok || lang.Name != "ruby" {
+		t.Errorf("Lookup(rb) = %q, %v; want ruby", lang.Name, ok)
+	}
+
+	// Registering a language again replaces it and its aliases
+	python := Language{Name: "python", Aliases: []string{"py3"}, Extension: ".py", Command: []string{"python3.13", "main.py"}, Wrap: wrap}
+	if err := r.Register(python); err != nil {
+		t.Fatalf("Register(python) error = %v", err)
+	}
+	if lang, _ := r.Lookup("py3"); lang.Command[0] != "python3.13" {
+		t.Errorf("Lookup(py3).Command = %v, want replaced language", lang.Command)
+	}
+	if _, ok := r.Lookup("py"); ok {
+		t.Error("Lookup(py) found after its language was replaced")
+	}
+	if len(r.Names()) != 5 {
+		t.Errorf("Names() = %v, want 5 languages", r.Names())
+	}
+
+	tests := []struct {
+		name string
+		lang Language
+	}{
+		{"missing name", Language{Command: []string{"x"}, Wrap: wrap}},
+		{"missing command", Language{Name: "x", Wrap: wrap}},
+		{"missing wrapper", Language{Name: "x", Command: []string{"x"}}},
+		{"alias taken", Language{Name: "x", Aliases: []string{"js"}, Command: []string{"x"}, Wrap: wrap}},
+		{"name is alias", Language{Name: "golang", Command: []string{"x"}, Wrap: wrap}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Register(tt.lang); err == nil {
+				t.Error("Register() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// errRuntimeShutDown is returned for executions submitted after Shutdown.
+var errRuntimeShutDown = fmt.Errorf("%w: runtime shut down", ErrRuntimeUnavailable)
+
+// ClosableBackend is an optional extension to Backend for backends that hold
+// resources, such as clients or module caches, that must be released.
+//
+// Contract:
+// - Concurrency: DefaultRuntime calls Close once, after the backend is idle.
+// - Context: must honor cancellation/deadlines.
+// - Errors: Close should release as much as possible and report the first failure.
+type ClosableBackend interface {
+	Backend
+
+	// Close releases the backend's resources. The backend must not be used
+	// afterwards.
+	Close(ctx context.Context) error
+}
+
+// CloseBackend closes backend if it implements ClosableBackend.
+func CloseBackend(ctx context.Context, backend Backend) error {
+	if closer, ok := backend.(ClosableBackend); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// lifecycle tracks in-flight executions and backend usage so DefaultRuntime
+// can shut down gracefully. The zero value is ready to use.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	ctx      context.Context // canceled to cancel in-flight executions
+	cancel   context.CancelFunc
+	inflight sync.WaitGroup
+	active   map[*registration]int
+	retired  map[*registration]bool // closed once no longer active
+}
+
+// registration is a backend registered with the runtime. The runtime tracks
+// backends by registration rather than by comparing Backend values, which
+// need not be comparable: registering the same backend again reuses its
+// registration, while copies of non-comparable backends get their own.
+type registration struct {
+	backend Backend
+}
+
+// sameBackend reports whether a and b are equal, comparable backends.
+func sameBackend(a, b Backend) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.IsValid() && vb.IsValid() && va.Type() == vb.Type() &&
+		va.Comparable() && vb.Comparable() && va.Equal(vb)
+}
+
+// backendsOf returns the backends of regs.
+func backendsOf(regs []*registration) []Backend {
+	backends := make([]Backend, len(regs))
+	for i, reg := range regs {
+		backends[i] = reg.backend
+	}
+	return backends
+}
+
+// begin registers an execution. The returned context is canceled when the
+// runtime cancels in-flight executions; done must be called when the
+// execution returns. It fails with ErrRuntimeUnavailable after shutdown.
+func (l *lifecycle) begin(ctx context.Context) (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ctx, nil, errRuntimeShutDown
+	}
+	if l.ctx == nil {
+		l.ctx, l.cancel = context.WithCancel(context.Background())
+	}
+	l.inflight.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(l.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		l.inflight.Done()
+	}, nil
+}
+
+// isClosed reports whether the runtime has shut down.
+func (l *lifecycle) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// acquire marks reg as in use.
+func (l *lifecycle) acquire(reg *registration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active == nil {
+		l.active = make(map[*registration]int)
+	}
+	l.active[reg]++
+}
+
+// release marks one use of reg as done and returns true if it was retired
+// and is now idle, in which case the caller must close its backend.
+func (l *lifecycle) release(reg *registration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active[reg]--
+	if l.active[reg] > 0 {
+		return false
+	}
+	delete(l.active, reg)
+	if !l.retired[reg] {
+		return false
+	}
+	delete(l.retired, reg)
+	return true
+}
+
+// retire marks reg for closing and returns true if it is idle, in which case
+// the caller must close its backend now. Otherwise the last release closes it.
+func (l *lifecycle) retire(reg *registration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[reg] == 0 {
+		return true
+	}
+	if l.retired == nil {
+		l.retired = make(map[*registration]bool)
+	}
+	l.retired[reg] = true
+	return false
+}
+
+// unretire cancels a pending close of reg after it was registered again.
+func (l *lifecycle) unretire(reg *registration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.retired, reg)
+}
+
+// retiredFor returns the registration of backend if it is retired but still
+// in use, so that registering it again cancels its pending close.
+func (l *lifecycle) retiredFor(backend Backend) *registration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for reg := range l.retired {
+		if sameBackend(reg.backend, backend) {
+			return reg
+		}
+	}
+	return nil
+}
+
+// Shutdown stops admitting new executions, waits for in-flight executions to
+// finish and then closes every registered backend that implements
+// ClosableBackend: primary backends first, then fallbacks, each ordered by
+// profile. If ctx is done before in-flight executions finish, they are
+// canceled and Shutdown waits for them to return before closing backends.
+//
+// New executions fail with ErrRuntimeUnavailable once Shutdown has been
+// called. Shutdown returns ctx.Err() if draining was cut short, joined with
+// any errors from closing backends. Calling Shutdown again only waits for
+// in-flight executions. Shutdown does not close RuntimeConfig.Health, nor
+// backends that only a Router returns; their owner closes them after
+// Shutdown returns.
+func (r *DefaultRuntime) Shutdown(ctx context.Context) error {
+	r.life.mu.Lock()
+	alreadyClosed := r.life.closed
+	r.life.closed = true
+	r.life.mu.Unlock()
+
+	if r.logger != nil && !alreadyClosed {
+		r.logger.Info("shutting down runtime")
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		r.life.inflight.Wait()
+		close(drained)
+	}()
+
+	var errs []error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		if r.logger != nil {
+			r.logger.Warn("canceling in-flight executions", "error", ctx.Err())
+		}
+		errs = append(errs, ctx.Err())
+		r.life.mu.Lock()
+		if r.life.cancel != nil {
+			r.life.cancel()
+		}
+		r.life.mu.Unlock()
+		<-drained
+	}
+	if alreadyClosed {
+		return errors.Join(errs...)
+	}
+
+	closeCtx := context.WithoutCancel(ctx)
+	for _, backend := range r.registeredBackends() {
+		if err := CloseBackend(closeCtx, backend); err != nil {
+			if r.logger != nil {
+				r.logger.Error("closing backend failed", "backend", backend.Kind(), "error", err)
+			}
+			errs = append(errs, fmt.Errorf("close backend %s: %w", backend.Kind(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registeredBackends returns the distinct registered backends: primary
+// backends first, then fallbacks, each ordered by profile.
+func (r *DefaultRuntime) registeredBackends() []Backend {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return backendsOf(r.registrationsLocked())
+}
+
+// registrationsLocked returns the distinct registrations: primary backends
+// first, then fallbacks, each ordered by profile. The caller must hold r.mu.
+func (r *DefaultRuntime) registrationsLocked() []*registration {
+	var regs []*registration
+	seen := make(map[*registration]bool)
+	add := func(reg *registration) {
+		if reg != nil && !seen[reg] {
+			seen[reg] = true
+			regs = append(regs, reg)
+		}
+	}
+	for _, profile := range sortedProfiles(r.backends) {
+		add(r.backends[profile])
+	}
+	for _, profile := range sortedProfiles(r.fallbacks) {
+		for _, reg := range r.fallbacks[profile] {
+			add(reg)
+		}
+	}
+	return regs
+}
+
+// registerLocked returns the registration for backend, reusing the one of an
+// equal backend that is registered or retired but still in use. It returns
+// nil for a nil backend. The caller must hold r.mu.
+func (r *DefaultRuntime) registerLocked(backend Backend) *registration {
+	if backend == nil {
+		return nil
+	}
+	for _, reg := range r.registrationsLocked() {
+		if sameBackend(reg.backend, backend) {
+			return reg
+		}
+	}
+	if reg := r.life.retiredFor(backend); reg != nil {
+		return reg
+	}
+	return &registration{backend: backend}
+}
+
+// referencedLocked reports whether reg is registered as a primary or
+// fallback backend. The caller must hold r.mu.
+func (r *DefaultRuntime) referencedLocked(reg *registration) bool {
+	for _, b := range r.backends {
+		if b == reg {
+			return true
+		}
+	}
+	for _, fallbacks := range r.fallbacks {
+		if slices.Contains(fallbacks, reg) {
+			return true
+		}
+	}
+	return false
+}
+
+// retireLocked retires the distinct registrations of regs that are no
+// longer referenced, stops watching their health, and returns the backends
+// that are idle and must be closed by the caller after releasing r.mu. Busy
+// backends are closed by their last release. The caller must hold r.mu.
+func (r *DefaultRuntime) retireLocked(regs ...*registration) []Backend {
+	var idle []Backend
+	seen := make(map[*registration]bool)
+	for _, reg := range regs {
+		if reg == nil || seen[reg] || r.referencedLocked(reg) {
+			continue
+		}
+		seen[reg] = true
+		r.health.Unwatch(reg.backend)
+		if r.life.retire(reg) {
+			idle = append(idle, reg.backend)
+		}
+	}
+	return idle
+}
+
+// useBackendsLocked marks regs as in use so their backends are not closed
+// while an execution may run on them. The caller must hold r.mu.
+func (r *DefaultRuntime) useBackendsLocked(regs []*registration) {
+	for _, reg := range regs {
+		r.life.acquire(reg)
+	}
+}
+
+// releaseBackends undoes useBackendsLocked and closes backends that were
+// retired while in use.
+func (r *DefaultRuntime) releaseBackends(regs []*registration) {
+	for _, reg := range regs {
+		if r.life.release(reg) {
+			r.closeRetired(reg.backend)
+		}
+	}
+}
+
+// closeRetired closes backends that were removed from the runtime.
+func (r *DefaultRuntime) closeRetired(backends ...Backend) {
+	for _, backend := range backends {
+		if err := CloseBackend(context.Background(), backend); err != nil && r.logger != nil {
+			r.logger.Error("closing backend failed", "backend", backend.Kind(), "error", err)
+		}
+	}
+}
+
+func sortedProfiles[V any](m map[SecurityProfile]V) []SecurityProfile {
+	profiles := make([]SecurityProfile, 0, len(m))
+	for profile := range m {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i] < profiles[j] })
+	return profiles
+}
@@ -0,0 +1,340 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// closeLog records the order in which backends are closed
+type closeLog struct {
+	mu     sync.Mutex
+	closed []string
+}
+
+func (l *closeLog) names() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.closed...)
+}
+
+// closableBackend wraps a backend and records Close calls
+type closableBackend struct {
+	Backend
+	name string
+	log  *closeLog
+	err  error
+}
+
+func (b *closableBackend) Close(_ context.Context) error {
+	b.log.mu.Lock()
+	defer b.log.mu.Unlock()
+	b.log.closed = append(b.log.closed, b.name)
+	return b.err
+}
+
+func TestCloseBackend(t *testing.T) {
+	log := &closeLog{}
+	if err := CloseBackend(context.Background(), &mockBackend{kind: BackendDocker}); err != nil {
+		t.Errorf("CloseBackend(not closable) error = %v", err)
+	}
+	closeErr := errors.New("close failed")
+	backend := &closableBackend{Backend: &mockBackend{kind: BackendDocker}, name: "docker", log: log, err: closeErr}
+	if err := CloseBackend(context.Background(), backend); !errors.Is(err, closeErr) {
+		t.Errorf("CloseBackend() error = %v, want %v", err, closeErr)
+	}
+	if got := log.names(); len(got) != 1 {
+		t.Errorf("closed = %v, want one close", got)
+	}
+}
+
+func TestDefaultRuntimeShutdownDrains(t *testing.T) {
+	log := &closeLog{}
+	blocking := newBlockingBackend(BackendDocker)
+	backend := &closableBackend{Backend: blocking, name: "docker", log: log}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	execErr := make(chan error, 1)
+	go func() {
+		_, err := rt.Execute(context.Background(), req)
+		execErr <- err
+	}()
+	<-blocking.started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- rt.Shutdown(context.Background())
+	}()
+
+	// New work is refused while draining
+	deadline := time.Now().Add(time.Second)
+	for !rt.life.isClosed() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := rt.Execute(context.Background(), req); !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("Execute() during shutdown error = %v, want ErrRuntimeUnavailable", err)
+	}
+	if _, err := rt.ExecuteStream(context.Background(), req); !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("ExecuteStream() during shutdown error = %v, want ErrRuntimeUnavailable", err)
+	}
+	if got := log.names(); len(got) != 0 {
+		t.Errorf("closed before drain = %v, want none", got)
+	}
+
+	close(blocking.release)
+	if err := <-execErr; err != nil {
+		t.Errorf("in-flight Execute() error = %v, want nil", err)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if got := log.names(); !reflect.DeepEqual(got, []string{"docker"}) {
+		t.Errorf("closed = %v, want [docker]", got)
+	}
+
+	// Shutdown is idempotent
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() error = %v", err)
+	}
+	if got := log.names(); len(got) != 1 {
+		t.Errorf("closed after second Shutdown = %v, want one close", got)
+	}
+}
+
+func TestDefaultRuntimeShutdownRouterBackends(t *testing.T) {
+	log := &closeLog{}
+	registered := &closableBackend{Backend: &mockBackend{kind: BackendDocker}, name: "registered", log: log}
+	blocking := newBlockingBackend(BackendGVisor)
+	routed := &closableBackend{Backend: blocking, name: "routed", log: log}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{ProfileStandard: registered},
+		Router: RouterFunc(func(context.Context, ExecuteRequest, []Backend) ([]Backend, error) {
+			return []Backend{routed}, nil
+		}),
+		DefaultProfile: ProfileStandard,
+	})
+
+	execErr := make(chan error, 1)
+	go func() {
+		_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+		execErr <- err
+	}()
+	<-blocking.started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- rt.Shutdown(context.Background())
+	}()
+
+	// Shutdown drains executions on a backend only the router returned
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown() returned %v before the routed execution finished", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(blocking.release)
+	if err := <-execErr; err != nil {
+		t.Errorf("in-flight Execute() error = %v, want nil", err)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+
+	// but leaves closing it to its owner
+	if got := log.names(); !reflect.DeepEqual(got, []string{"registered"}) {
+		t.Errorf("closed = %v, want only the registered backend", got)
+	}
+}
+
+func TestDefaultRuntimeShutdownCancels(t *testing.T) {
+	log := &closeLog{}
+	blocking := newBlockingBackend(BackendDocker)
+	backend := &closableBackend{Backend: blocking, name: "docker", log: log}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile: ProfileStandard,
+	})
+
+	execErr := make(chan error, 1)
+	go func() {
+		_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+		execErr <- err
+	}()
+	<-blocking.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := rt.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want DeadlineExceeded", err)
+	}
+	if err := <-execErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("in-flight Execute() error = %v, want Canceled", err)
+	}
+	if got := log.names(); !reflect.DeepEqual(got, []string{"docker"}) {
+		t.Errorf("closed = %v, want [docker]", got)
+	}
+}
+
+func TestDefaultRuntimeShutdownOrder(t *testing.T) {
+	log := &closeLog{}
+	closable := func(kind BackendKind, name string) *closableBackend {
+		return &closableBackend{Backend: &mockBackend{kind: kind}, name: name, log: log}
+	}
+	standard := closable(BackendDocker, "standard")
+	hardened := closable(BackendGVisor, "hardened")
+	fallback := closable(BackendKata, "fallback")
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: standard,
+			ProfileHardened: hardened,
+			ProfileDev:      &mockBackend{kind: BackendUnsafeHost},
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileStandard: {fallback, hardened},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+
+	closeErr := errors.New("close failed")
+	fallback.err = closeErr
+	if err := rt.Shutdown(context.Background()); !errors.Is(err, closeErr) {
+		t.Errorf("Shutdown() error = %v, want %v", err, closeErr)
+	}
+	want := []string{"hardened", "standard", "fallback"}
+	if got := log.names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("closed = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultRuntimeUnregisterBackendCloses(t *testing.T) {
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+
+	t.Run("idle", func(t *testing.T) {
+		log := &closeLog{}
+		backend := &closableBackend{Backend: &mockBackend{kind: BackendDocker}, name: "docker", log: log}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+			DefaultProfile: ProfileStandard,
+		})
+		rt.UnregisterBackend(ProfileStandard)
+		if got := log.names(); len(got) != 1 {
+			t.Errorf("closed = %v, want [docker]", got)
+		}
+	})
+
+	t.Run("in use", func(t *testing.T) {
+		log := &closeLog{}
+		blocking := newBlockingBackend(BackendDocker)
+		backend := &closableBackend{Backend: blocking, name: "docker", log: log}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+			DefaultProfile: ProfileStandard,
+		})
+
+		execErr := make(chan error, 1)
+		go func() {
+			_, err := rt.Execute(context.Background(), req)
+			execErr <- err
+		}()
+		<-blocking.started
+
+		rt.UnregisterBackend(ProfileStandard)
+		if got := log.names(); len(got) != 0 {
+			t.Errorf("closed while in use = %v, want none", got)
+		}
+		close(blocking.release)
+		if err := <-execErr; err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if got := log.names(); len(got) != 1 {
+			t.Errorf("closed after execution = %v, want [docker]", got)
+		}
+	})
+
+	t.Run("still a fallback", func(t *testing.T) {
+		log := &closeLog{}
+		backend := &closableBackend{Backend: &mockBackend{kind: BackendDocker}, name: "docker", log: log}
+		rt := NewDefaultRuntime(RuntimeConfig{
+			Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+			Fallbacks:      map[SecurityProfile][]Backend{ProfileHardened: {backend}},
+			DefaultProfile: ProfileStandard,
+		})
+		rt.UnregisterBackend(ProfileStandard)
+		if got := log.names(); len(got) != 0 {
+			t.Errorf("closed = %v, want none", got)
+		}
+	})
+}
+
+// valueBackend is a non-comparable backend used by value
+type valueBackend struct {
+	tags []string
+	log  *closeLog
+}
+
+func (b valueBackend) Kind() BackendKind { return BackendDocker }
+
+func (b valueBackend) Execute(_ context.Context, _ ExecuteRequest) (ExecuteResult, error) {
+	return ExecuteResult{Backend: BackendInfo{Kind: BackendDocker}}, nil
+}
+
+func (b valueBackend) Close(_ context.Context) error {
+	b.log.mu.Lock()
+	defer b.log.mu.Unlock()
+	b.log.closed = append(b.log.closed, b.tags[0])
+	return nil
+}
+
+func TestDefaultRuntimeNonComparableBackend(t *testing.T) {
+	log := &closeLog{}
+	backend := valueBackend{tags: []string{"value"}, log: log}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {backend}},
+		DefaultProfile: ProfileStandard,
+	})
+
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+	if _, err := rt.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	rt.RegisterBackend(ProfileHardened, backend)
+	rt.UnregisterBackend(ProfileHardened)
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := log.names(); len(got) != 3 {
+		t.Errorf("closed = %v, want the unregistered copy and both registrations", got)
+	}
+}
+
+func TestDefaultRuntimeSetFallbacksRetires(t *testing.T) {
+	log := &closeLog{}
+	closable := func(name string) *closableBackend {
+		return &closableBackend{Backend: &mockBackend{kind: BackendDocker}, name: name, log: log}
+	}
+	old, kept, added := closable("old"), closable("kept"), closable("added")
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: closable("primary")},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {old, kept}},
+		DefaultProfile: ProfileStandard,
+	})
+
+	rt.SetFallbacks(ProfileStandard, kept, added)
+	if got := log.names(); !reflect.DeepEqual(got, []string{"old"}) {
+		t.Errorf("closed = %v, want [old]", got)
+	}
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := log.names(); !reflect.DeepEqual(got, []string{"old", "primary", "kept", "added"}) {
+		t.Errorf("closed = %v, want old, then primary and fallbacks", got)
+	}
+}
@@ -0,0 +1,108 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Execution outcomes reported to Metrics.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeError       = "error"
+	OutcomeTimeout     = "timeout"
+	OutcomeCanceled    = "canceled"
+	OutcomeInvalid     = "invalid"
+	OutcomeDenied      = "denied"
+	OutcomeUnavailable = "unavailable"
+	OutcomeRejected    = "rejected"
+	OutcomeLimit       = "limit_exceeded"
+)
+
+// Limit names reported to Metrics.RecordLimitViolation.
+const (
+	LimitTimeout      = "timeout"
+	LimitResource     = "resource"
+	LimitQueueFull    = "queue_full"
+	LimitQueueTimeout = "queue_timeout"
+	LimitToolCalls    = "tool_calls"
+	LimitChainSteps   = "chain_steps"
+)
+
+// ExecutionMetrics describes a completed execution.
+type ExecutionMetrics struct {
+	// Profile is the effective security profile.
+	Profile SecurityProfile
+
+	// Backend is the backend that produced the result, if any.
+	Backend BackendKind
+
+	// Outcome classifies the result; see the Outcome constants.
+	Outcome string
+
+	// Duration is the execution time reported by the backend.
+	Duration time.Duration
+
+	// QueueWait is the time spent waiting for admission.
+	QueueWait time.Duration
+
+	// ToolCalls is the number of tool invocations.
+	ToolCalls int
+}
+
+// Metrics receives measurements from the runtime and gateways.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: recording must be best-effort, fast, and must not panic.
+type Metrics interface {
+	// RecordExecution records a completed execution.
+	RecordExecution(m ExecutionMetrics)
+
+	// RecordLimitViolation records that a limit was hit; see the Limit constants.
+	RecordLimitViolation(limit string)
+
+	// RecordGatewayError records a failed gateway operation.
+	RecordGatewayError(gateway, op string)
+}
+
+// OutcomeOf classifies an execution error as one of the Outcome constants.
+func OutcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, context.Canceled):
+		return OutcomeCanceled
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case errors.Is(err, ErrMissingGateway), errors.Is(err, ErrMissingCode), errors.Is(err, ErrInvalidLimits),
+		errors.Is(err, ErrInvalidRequest):
+		return OutcomeInvalid
+	case errors.Is(err, ErrBackendDenied):
+		return OutcomeDenied
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrQueueTimeout):
+		return OutcomeRejected
+	case errors.Is(err, ErrResourceLimit):
+		return OutcomeLimit
+	case errors.Is(err, ErrRuntimeUnavailable):
+		return OutcomeUnavailable
+	}
+	return OutcomeError
+}
+
+// limitOf returns the limit violated by err, or "" if none.
+func limitOf(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrTimeout):
+		return LimitTimeout
+	case errors.Is(err, ErrResourceLimit):
+		return LimitResource
+	case errors.Is(err, ErrQueueFull):
+		return LimitQueueFull
+	case errors.Is(err, ErrQueueTimeout):
+		return LimitQueueTimeout
+	}
+	return ""
+}
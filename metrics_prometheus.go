@@ -0,0 +1,254 @@
+package toolruntime
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets are the histogram buckets, in seconds, used for
+// execution duration and queue wait.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// DefaultToolCallBuckets are the histogram buckets used for tool calls per execution.
+var DefaultToolCallBuckets = []float64{0, 1, 2, 5, 10, 25, 50, 100}
+
+// PrometheusMetrics is a Metrics implementation that keeps measurements in
+// memory and serves them in the Prometheus text exposition format.
+// It implements http.Handler.
+type PrometheusMetrics struct {
+	executions      *metricVec
+	duration        *metricVec
+	queueWait       *metricVec
+	toolCalls       *metricVec
+	limitViolations *metricVec
+	gatewayErrors   *metricVec
+}
+
+// NewPrometheusMetrics creates an empty PrometheusMetrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		executions: newCounterVec("toolruntime_executions_total",
+			"Executions by profile, backend and outcome.", "profile", "backend", "outcome"),
+		duration: newHistogramVec("toolruntime_execution_duration_seconds",
+			"Execution duration in seconds.", DefaultDurationBuckets, "profile", "backend"),
+		queueWait: newHistogramVec("toolruntime_queue_wait_seconds",
+			"Time spent waiting for admission in seconds.", DefaultDurationBuckets, "profile"),
+		toolCalls: newHistogramVec("toolruntime_tool_calls_per_execution",
+			"Tool invocations per execution.", DefaultToolCallBuckets, "profile", "backend"),
+		limitViolations: newCounterVec("toolruntime_limit_violations_total",
+			"Limit violations by limit.", "limit"),
+		gatewayErrors: newCounterVec("toolruntime_gateway_errors_total",
+			"Failed gateway operations by gateway and operation.", "gateway", "op"),
+	}
+}
+
+// RecordExecution implements Metrics.
+func (p *PrometheusMetrics) RecordExecution(m ExecutionMetrics) {
+	profile, backend := string(m.Profile), string(m.Backend)
+	p.executions.add(1, profile, backend, m.Outcome)
+	p.duration.observe(m.Duration.Seconds(), profile, backend)
+	p.queueWait.observe(m.QueueWait.Seconds(), profile)
+	p.toolCalls.observe(float64(m.ToolCalls), profile, backend)
+}
+
+// RecordLimitViolation implements Metrics.
+func (p *PrometheusMetrics) RecordLimitViolation(limit string) {
+	p.limitViolations.add(1, limit)
+}
+
+// RecordGatewayError implements Metrics.
+func (p *PrometheusMetrics) RecordGatewayError(gateway, op string) {
+	p.gatewayErrors.add(1, gateway, op)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	for _, v := range []*metricVec{p.executions, p.duration, p.queueWait, p.toolCalls, p.limitViolations, p.gatewayErrors} {
+		v.write(cw)
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+// metricVec is a counter or histogram family keyed by label values.
+type metricVec struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64 // nil for counters
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series holds the state of one label combination.
+type series struct {
+	labelValues []string
+	value       float64  // counter value or histogram sum
+	count       uint64   // histogram observation count
+	bucketCount []uint64 // non-cumulative count per bucket
+}
+
+func newCounterVec(name, help string, labelNames ...string) *metricVec {
+	return &metricVec{name: name, help: help, labelNames: labelNames, series: make(map[string]*series)}
+}
+
+func newHistogramVec(name, help string, buckets []float64, labelNames ...string) *metricVec {
+	v := newCounterVec(name, help, labelNames...)
+	v.buckets = append([]float64(nil), buckets...)
+	sort.Float64s(v.buckets)
+	return v
+}
+
+func (v *metricVec) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if v.buckets != nil {
+			s.bucketCount = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *metricVec) add(delta float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value += delta
+}
+
+func (v *metricVec) observe(value float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.get(labelValues)
+	s.value += value
+	s.count++
+	for i, upper := range v.buckets {
+		if value <= upper {
+			s.bucketCount[i]++
+			break
+		}
+	}
+}
+
+func (v *metricVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	kind := "counter"
+	if v.buckets != nil {
+		kind = "histogram"
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, kind)
+
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := v.series[k]
+		labels := formatLabels(v.labelNames, s.labelValues)
+		if v.buckets == nil {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, labels, formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += s.bucketCount[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, withLabel(labels, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, withLabel(labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, labels, formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, labels, s.count)
+	}
+}
+
+// formatLabels renders {name="value",...}, or "" when there are no labels.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel appends a label to a rendered label set.
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + escapeLabelValue(value) + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts bytes written and remembers the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+var (
+	_ Metrics      = (*PrometheusMetrics)(nil)
+	_ http.Handler = (*PrometheusMetrics)(nil)
+)
@@ -0,0 +1,126 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutcomeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, OutcomeSuccess},
+		{context.Canceled, OutcomeCanceled},
+		{context.DeadlineExceeded, OutcomeTimeout},
+		{fmt.Errorf("wrapped: %w", ErrTimeout), OutcomeTimeout},
+		{ErrMissingGateway, OutcomeInvalid},
+		{fmt.Errorf("%w: unknown language", ErrInvalidRequest), OutcomeInvalid},
+		{ErrBackendDenied, OutcomeDenied},
+		{ErrQueueTimeout, OutcomeRejected},
+		{ErrResourceLimit, OutcomeLimit},
+		{ErrRuntimeUnavailable, OutcomeUnavailable},
+		{errors.New("boom"), OutcomeError},
+	}
+
+	for _, tt := range tests {
+		if got := OutcomeOf(tt.err); got != tt.want {
+			t.Errorf("OutcomeOf(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPrometheusMetricsExposition(t *testing.T) {
+	m := NewPrometheusMetrics()
+	m.RecordExecution(ExecutionMetrics{
+		Profile:   ProfileStandard,
+		Backend:   BackendDocker,
+		Outcome:   OutcomeSuccess,
+		Duration:  20 * time.Millisecond,
+		QueueWait: 0,
+		ToolCalls: 3,
+	})
+	m.RecordExecution(ExecutionMetrics{
+		Profile:  ProfileStandard,
+		Backend:  BackendDocker,
+		Outcome:  OutcomeSuccess,
+		Duration: 2 * time.Second,
+	})
+	m.RecordLimitViolation(LimitTimeout)
+	m.RecordGatewayError("direct", `run "tool"`)
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE toolruntime_executions_total counter\n",
+		`toolruntime_executions_total{profile="standard",backend="docker",outcome="success"} 2` + "\n",
+		"# TYPE toolruntime_execution_duration_seconds histogram\n",
+		`toolruntime_execution_duration_seconds_bucket{profile="standard",backend="docker",le="0.025"} 1` + "\n",
+		`toolruntime_execution_duration_seconds_bucket{profile="standard",backend="docker",le="2.5"} 2` + "\n",
+		`toolruntime_execution_duration_seconds_bucket{profile="standard",backend="docker",le="+Inf"} 2` + "\n",
+		`toolruntime_execution_duration_seconds_sum{profile="standard",backend="docker"} 2.02` + "\n",
+		`toolruntime_execution_duration_seconds_count{profile="standard",backend="docker"} 2` + "\n",
+		`toolruntime_tool_calls_per_execution_sum{profile="standard",backend="docker"} 3` + "\n",
+		`toolruntime_queue_wait_seconds_count{profile="standard"} 2` + "\n",
+		`toolruntime_limit_violations_total{limit="timeout"} 1` + "\n",
+		`toolruntime_gateway_errors_total{gateway="direct",op="run \"tool\""} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestPrometheusMetricsServeHTTP(t *testing.T) {
+	m := NewPrometheusMetrics()
+	m.RecordLimitViolation(LimitQueueFull)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want Prometheus text format", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `toolruntime_limit_violations_total{limit="queue_full"} 1`) {
+		t.Errorf("body missing limit violation:\n%s", rec.Body.String())
+	}
+}
+
+func TestDefaultRuntimeMetrics(t *testing.T) {
+	m := NewPrometheusMetrics()
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev:      &mockBackend{kind: BackendUnsafeHost},
+			ProfileStandard: &mockBackend{kind: BackendDocker, executeErr: fmt.Errorf("%w: slow", ErrTimeout)},
+		},
+		Metrics:        m,
+		DefaultProfile: ProfileDev,
+	})
+	ctx := context.Background()
+
+	_, _ = rt.Execute(ctx, ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	_, _ = rt.Execute(ctx, ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Profile: ProfileStandard})
+
+	var b strings.Builder
+	_, _ = m.WriteTo(&b)
+	out := b.String()
+
+	for _, want := range []string{
+		`toolruntime_executions_total{profile="dev",backend="unsafe_host",outcome="success"} 1`,
+		`toolruntime_executions_total{profile="standard",backend="",outcome="timeout"} 1`,
+		`toolruntime_limit_violations_total{limit="timeout"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition missing %q\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package toolruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// truncationMarker returns the marker appended to output truncated by
+// omitted bytes.
+func truncationMarker(omitted int64) string {
+	return fmt.Sprintf("\n[truncated: %d bytes omitted]\n", omitted)
+}
+
+// OutputBuffer is an io.Writer that keeps at most limit bytes of output in
+// memory and counts the rest. When spilling is enabled, output that exceeds
+// the limit is written in full to a temporary file. A limit of zero keeps
+// everything. It is not safe for concurrent use.
+type OutputBuffer struct {
+	name  string
+	limit int64
+	spill bool
+
+	buf   bytes.Buffer
+	total int64
+	file  *os.File
+	err   error
+}
+
+// NewOutputBuffer returns an OutputBuffer keeping limit bytes. name, such as
+// "stdout", is used in the name of the spill file.
+func NewOutputBuffer(name string, limit int64, spill bool) *OutputBuffer {
+	return &OutputBuffer{name: name, limit: limit, spill: spill}
+}
+
+// Write records p. It never fails, so that a producer is not interrupted by
+// the limit; spill errors are reported by Close.
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if b.spill && b.err == nil && (b.file != nil || b.Truncated()) {
+		b.spillWrite(p)
+	}
+	if b.limit <= 0 {
+		b.buf.Write(p)
+		return len(p), nil
+	}
+	if room := b.limit - int64(b.buf.Len()); room > 0 {
+		b.buf.Write(p[:min(room, int64(len(p)))])
+	}
+	return len(p), nil
+}
+
+// WriteString records s.
+func (b *OutputBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// spillWrite writes p to the spill file, creating it with the output kept
+// so far on first use.
+func (b *OutputBuffer) spillWrite(p []byte) {
+	if b.file == nil {
+		b.file, b.err = os.CreateTemp("", "toolruntime-"+b.name+"-*")
+		if b.err != nil {
+			return
+		}
+		if _, b.err = b.file.Write(b.buf.Bytes()); b.err != nil {
+			return
+		}
+	}
+	_, b.err = b.file.Write(p)
+}
+
+// Truncated reports whether output exceeded the limit.
+func (b *OutputBuffer) Truncated() bool {
+	return b.limit > 0 && b.total > b.limit
+}
+
+// String returns the kept output, followed by a truncation marker if output
+// exceeded the limit.
+func (b *OutputBuffer) String() string {
+	if !b.Truncated() {
+		return b.buf.String()
+	}
+	return b.buf.String() + truncationMarker(b.total-int64(b.buf.Len()))
+}
+
+// Close closes the spill file and returns its path, or "" if output was not
+// spilled. If spilling failed, the file is removed and the error returned.
+func (b *OutputBuffer) Close() (string, error) {
+	if b.file == nil {
+		return "", b.err
+	}
+	path := b.file.Name()
+	err := errors.Join(b.err, b.file.Close())
+	b.file = nil
+	if err != nil {
+		_ = os.Remove(path)
+		return "", fmt.Errorf("spill %s: %w", b.name, err)
+	}
+	return path, nil
+}
+
+// TruncateValue returns v, or, if its JSON encoding exceeds limit bytes, the
+// first limit bytes of the encoding followed by a truncation marker as a
+// string. A limit of zero keeps every value.
+func TruncateValue(v any, limit int64) any {
+	if limit <= 0 || v == nil {
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil || int64(len(data)) <= limit {
+		return v
+	}
+	return string(data[:limit]) + truncationMarker(int64(len(data))-limit)
+}
+
+// OutputCapture captures the stdout and stderr of an execution within the
+// output limits of its request, and decodes the result envelope that the
+// code writes to stdout. Backends pass Nonce to executed code in
+// ResultNonceEnv, write output to Stdout and Stderr and call Finish once to
+// fill in the result.
+type OutputCapture struct {
+	// Stdout receives the stdout of the executed code, including the
+	// result envelope, which is stripped from the captured output.
+	Stdout io.Writer
+
+	// Stderr receives the stderr of the executed code.
+	Stderr io.Writer
+
+	nonce    string
+	stdout   *OutputBuffer
+	stderr   *OutputBuffer
+	envelope *envelopeWriter
+	codeErr  error
+	maxValue int64
+	maxEnv   int64
+}
+
+// envelopeSlack is the room a result envelope gets beyond the value and
+// stdout limits for metadata, an error and a value that is truncated.
+const envelopeSlack = 64 << 10
+
+// NewOutputCapture returns an OutputCapture for req with a new result nonce.
+// When the request limits stdout or the value, the result envelope may be at
+// most the larger of the two limits plus 64 KiB; zero limits leave it
+// unlimited like the output.
+func NewOutputCapture(req ExecuteRequest) *OutputCapture {
+	c := &OutputCapture{
+		nonce:    NewResultNonce(),
+		stdout:   NewOutputBuffer("stdout", req.Limits.MaxStdoutBytes, req.SpillOutput),
+		stderr:   NewOutputBuffer("stderr", req.Limits.MaxStderrBytes, req.SpillOutput),
+		maxValue: req.Limits.MaxValueBytes,
+	}
+	if req.Limits.MaxStdoutBytes > 0 || req.Limits.MaxValueBytes > 0 {
+		c.maxEnv = max(req.Limits.MaxStdoutBytes, req.Limits.MaxValueBytes) + envelopeSlack
+	}
+	return c.WithStream(nil, nil)
+}
+
+// WithStream additionally copies output to stdout and stderr, when non-nil,
+// as it is written, with the result envelope stripped. It must be called
+// before any output is written.
+func (c *OutputCapture) WithStream(stdout, stderr io.Writer) *OutputCapture {
+	c.envelope = newEnvelopeWriter(teeWriter(c.stdout, stdout), c.nonce, c.maxEnv)
+	c.Stdout = c.envelope
+	c.Stderr = teeWriter(c.stderr, stderr)
+	return c
+}
+
+// Nonce returns the nonce that executed code uses to frame its result
+// envelope.
+func (c *OutputCapture) Nonce() string {
+	return c.nonce
+}
+
+// Finish sets the captured output and spill files on result, sets its Value
+// and Metadata from the result envelope, if the code wrote one, truncates
+// its Value and marks the output limits as enforced. An error reported in
+// the envelope is returned by Err. An envelope over its limit fails with
+// ErrResourceLimit. On error, no spill file is left behind.
+func (c *OutputCapture) Finish(result *ExecuteResult) error {
+	c.envelope.flush()
+	env, found, envErr := c.envelope.result()
+	if found && envErr == nil {
+		result.Value = env.Value
+		result.Metadata = env.Metadata
+		if env.Error != nil {
+			c.codeErr = env.Error
+		}
+	}
+
+	result.Stdout = c.stdout.String()
+	result.Stderr = c.stderr.String()
+	result.Value = TruncateValue(result.Value, c.maxValue)
+	result.LimitsEnforced.Output = true
+
+	stdoutFile, stdoutErr := c.stdout.Close()
+	stderrFile, stderrErr := c.stderr.Close()
+	if err := errors.Join(stdoutErr, stderrErr, envErr); err != nil {
+		RemoveSpillFiles(ExecuteResult{StdoutFile: stdoutFile, StderrFile: stderrFile})
+		return err
+	}
+	result.StdoutFile, result.StderrFile = stdoutFile, stderrFile
+	return nil
+}
+
+// Err returns the *ResultError reported in the result envelope, or nil. It
+// is set by Finish.
+func (c *OutputCapture) Err() error {
+	return c.codeErr
+}
+
+// teeWriter returns buf, or a writer duplicating writes to buf and w when w
+// is non-nil.
+func teeWriter(buf, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
+
+// RemoveSpillFiles removes the spill files of result, if any.
+func RemoveSpillFiles(result ExecuteResult) {
+	for _, path := range []string{result.StdoutFile, result.StderrFile} {
+		if path != "" {
+			_ = os.Remove(path)
+		}
+	}
+}
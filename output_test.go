@@ -0,0 +1,125 @@
+package toolruntime
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	b := NewOutputBuffer("stdout", 5, false)
+	for _, chunk := range []string{"abc", "defg", "hij"} {
+		if n, err := b.WriteString(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("WriteString(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if !b.Truncated() || b.String() != "abcde\n[truncated: 5 bytes omitted]\n" {
+		t.Errorf("String() = %q, Truncated() = %v", b.String(), b.Truncated())
+	}
+	if path, err := b.Close(); path != "" || err != nil {
+		t.Errorf("Close() = %q, %v; want no spill file", path, err)
+	}
+
+	unlimited := NewOutputBuffer("stdout", 0, true)
+	_, _ = unlimited.WriteString(strings.Repeat("x", 1<<10))
+	if unlimited.Truncated() || len(unlimited.String()) != 1<<10 {
+		t.Errorf("unlimited buffer kept %d bytes, truncated = %v", len(unlimited.String()), unlimited.Truncated())
+	}
+	if path, _ := unlimited.Close(); path != "" {
+		t.Errorf("Close() = %q, want no spill file without truncation", path)
+	}
+}
+
+func TestOutputBufferSpill(t *testing.T) {
+	b := NewOutputBuffer("stdout", 4, true)
+	for _, chunk := range []string{"ab", "cdef", "gh"} {
+		_, _ = b.WriteString(chunk)
+	}
+	path, err := b.Close()
+	if err != nil || path == "" {
+		t.Fatalf("Close() = %q, %v; want a spill file", path, err)
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "abcdefgh" {
+		t.Errorf("spill file = %q, %v; want the full output", data, err)
+	}
+	if b.String() != "abcd\n[truncated: 4 bytes omitted]\n" {
+		t.Errorf("String() = %q", b.String())
+	}
+}
+
+func TestTruncateValue(t *testing.T) {
+	if got := TruncateValue(map[string]any{"a": 1}, 100); got.(map[string]any)["a"] != 1 {
+		t.Errorf("TruncateValue() under limit = %v, want unchanged", got)
+	}
+	if got := TruncateValue("abcdefgh", 4); got != `"abc`+truncationMarker(6) {
+		t.Errorf("TruncateValue() = %q", got)
+	}
+	if got := TruncateValue("abcdefgh", 0); got != "abcdefgh" {
+		t.Errorf("TruncateValue() without limit = %v, want unchanged", got)
+	}
+}
+
+func TestOutputCaptureFinish(t *testing.T) {
+	req := ExecuteRequest{
+		Limits:      Limits{MaxStdoutBytes: 3, MaxValueBytes: 4},
+		SpillOutput: true,
+	}
+	capture := NewOutputCapture(req)
+	_, _ = io.WriteString(capture.Stdout, "hello")
+	_, _ = io.WriteString(capture.Stderr, "warn")
+
+	result := ExecuteResult{Value: "long value"}
+	if err := capture.Finish(&result); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	defer RemoveSpillFiles(result)
+
+	if !strings.HasPrefix(result.Stdout, "hel\n[truncated") || result.Stderr != "warn" {
+		t.Errorf("Stdout = %q, Stderr = %q", result.Stdout, result.Stderr)
+	}
+	if result.StdoutFile == "" || result.StderrFile != "" {
+		t.Errorf("StdoutFile = %q, StderrFile = %q; want only stdout spilled", result.StdoutFile, result.StderrFile)
+	}
+	if v, _ := result.Value.(string); !strings.HasPrefix(v, `"lon`+"\n[truncated") {
+		t.Errorf("Value = %v, want truncated", result.Value)
+	}
+	if !result.LimitsEnforced.Output {
+		t.Error("LimitsEnforced.Output should be set")
+	}
+}
+
+func TestResultCacheSkipsSpilledResults(t *testing.T) {
+	backend := &countingBackend{mockBackend: mockBackend{kind: BackendDocker, result: ExecuteResult{StdoutFile: "/tmp/spill"}}}
+	rt := newCachedRuntime(backend, NewResultCache(CacheConfig{}))
+	req := ExecuteRequest{Code: "a", Gateway: &mockToolGateway{}}
+
+	for range 2 {
+		if _, err := rt.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if backend.calls != 2 {
+		t.Errorf("backend calls = %d, want 2 (spilled results are not cached)", backend.calls)
+	}
+}
+
+func TestRedactorSpillFile(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "spill-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(strings.Repeat("x", 32<<10-3) + "s3cret tail")
+	_ = f.Close()
+
+	r := NewRedactor(map[string]string{"KEY": "s3cret"})
+	result := r.Result(ExecuteResult{StdoutFile: f.Name()})
+	data, err := os.ReadFile(result.StdoutFile)
+	if err != nil || !strings.HasSuffix(string(data), "[REDACTED] tail") {
+		t.Errorf("spill file ends with %q, %v; want secret redacted", data[len(data)-20:], err)
+	}
+}
@@ -0,0 +1,320 @@
+package toolruntime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTenantKey is the ExecuteRequest.Metadata key that identifies the
+// tenant when QuotaConfig.TenantKey is empty.
+const DefaultTenantKey = "tenant"
+
+// defaultQuotaWindow is used when Quota.Window is zero.
+const defaultQuotaWindow = time.Hour
+
+// Usage is the resource usage accounted to a tenant.
+type Usage struct {
+	// Executions is the number of executions.
+	Executions int
+
+	// Duration is the total execution time, used as a measure of CPU time.
+	Duration time.Duration
+
+	// ToolCalls is the total number of tool invocations.
+	ToolCalls int
+
+	// MemoryMBSeconds is the memory limit (in MiB) multiplied by execution
+	// time in seconds, counted only for executions whose backend enforced
+	// the memory limit.
+	MemoryMBSeconds float64
+}
+
+// Add returns the sum of u and other.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		Executions:      u.Executions + other.Executions,
+		Duration:        u.Duration + other.Duration,
+		ToolCalls:       u.ToolCalls + other.ToolCalls,
+		MemoryMBSeconds: u.MemoryMBSeconds + other.MemoryMBSeconds,
+	}
+}
+
+// Quota limits a tenant's usage within a fixed time window.
+// Zero limits are unlimited.
+type Quota struct {
+	// Window is the accounting period. Usage resets at the start of each window.
+	// Default: 1 hour
+	Window time.Duration
+
+	// MaxExecutions limits the number of executions per window.
+	MaxExecutions int
+
+	// MaxDuration limits total execution time per window.
+	MaxDuration time.Duration
+
+	// MaxToolCalls limits total tool invocations per window.
+	MaxToolCalls int
+
+	// MaxMemoryMBSeconds limits total memory-seconds per window.
+	MaxMemoryMBSeconds float64
+}
+
+// exceeded returns the name of the first limit that usage has reached, or "".
+func (q Quota) exceeded(u Usage) string {
+	switch {
+	case q.MaxExecutions > 0 && u.Executions >= q.MaxExecutions:
+		return "executions"
+	case q.MaxDuration > 0 && u.Duration >= q.MaxDuration:
+		return "duration"
+	case q.MaxToolCalls > 0 && u.ToolCalls >= q.MaxToolCalls:
+		return "tool calls"
+	case q.MaxMemoryMBSeconds > 0 && u.MemoryMBSeconds >= q.MaxMemoryMBSeconds:
+		return "memory-seconds"
+	}
+	return ""
+}
+
+// QuotaStore persists per-tenant usage.
+// Windows are identified by their start time.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: Add must apply the delta atomically with respect to other Add calls.
+//   Deltas may be negative; the runtime reserves an execution with Add before
+//   running it and releases the reservation if it never reaches a backend.
+type QuotaStore interface {
+	// Usage returns the usage recorded for tenant in the window.
+	Usage(ctx context.Context, tenant string, window time.Time) (Usage, error)
+
+	// Add adds delta to the usage for tenant in the window and returns the new total.
+	Add(ctx context.Context, tenant string, window time.Time, delta Usage) (Usage, error)
+}
+
+// MemoryQuotaStore is an in-memory QuotaStore.
+// It keeps only the most recent window per tenant.
+type MemoryQuotaStore struct {
+	mu      sync.Mutex
+	tenants map[string]windowUsage
+}
+
+type windowUsage struct {
+	window time.Time
+	usage  Usage
+}
+
+// NewMemoryQuotaStore creates an empty in-memory quota store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{tenants: make(map[string]windowUsage)}
+}
+
+// Usage implements QuotaStore.
+func (s *MemoryQuotaStore) Usage(ctx context.Context, tenant string, window time.Time) (Usage, error) {
+	if ctx.Err() != nil {
+		return Usage{}, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wu, ok := s.tenants[tenant]
+	if !ok || !wu.window.Equal(window) {
+		return Usage{}, nil
+	}
+	return wu.usage, nil
+}
+
+// Add implements QuotaStore.
+func (s *MemoryQuotaStore) Add(ctx context.Context, tenant string, window time.Time, delta Usage) (Usage, error) {
+	if ctx.Err() != nil {
+		return Usage{}, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wu := s.tenants[tenant]
+	if !wu.window.Equal(window) {
+		wu = windowUsage{window: window}
+	}
+	wu.usage = wu.usage.Add(delta)
+	s.tenants[tenant] = wu
+	return wu.usage, nil
+}
+
+// QuotaConfig configures a QuotaManager.
+type QuotaConfig struct {
+	// Store persists usage.
+	// Default: NewMemoryQuotaStore()
+	Store QuotaStore
+
+	// TenantKey is the ExecuteRequest.Metadata key holding the tenant identifier.
+	// Requests without a tenant are not subject to quotas.
+	// Default: DefaultTenantKey
+	TenantKey string
+
+	// Tenants maps tenant identifiers to their quotas.
+	Tenants map[string]Quota
+
+	// Default is the quota for tenants not listed in Tenants.
+	Default Quota
+
+	// Now returns the current time. Default: time.Now
+	Now func() time.Time
+}
+
+// QuotaManager enforces per-tenant quotas across executions.
+// It rejects requests with ErrResourceLimit once a tenant has exhausted
+// its quota for the current window, and deducts actual usage after each
+// execution. A DefaultRuntime reserves each execution atomically before
+// running it, so concurrent requests cannot exceed MaxExecutions; the other
+// limits are only known after execution and may be overshot by the
+// executions in flight when they are reached.
+type QuotaManager struct {
+	store        QuotaStore
+	tenantKey    string
+	tenants      map[string]Quota
+	defaultQuota Quota
+	now          func() time.Time
+}
+
+// NewQuotaManager creates a QuotaManager with the given configuration.
+func NewQuotaManager(cfg QuotaConfig) *QuotaManager {
+	m := &QuotaManager{
+		store:        cfg.Store,
+		tenantKey:    cfg.TenantKey,
+		tenants:      make(map[string]Quota, len(cfg.Tenants)),
+		defaultQuota: cfg.Default,
+		now:          cfg.Now,
+	}
+	if m.store == nil {
+		m.store = NewMemoryQuotaStore()
+	}
+	if m.tenantKey == "" {
+		m.tenantKey = DefaultTenantKey
+	}
+	if m.now == nil {
+		m.now = time.Now
+	}
+	for tenant, q := range cfg.Tenants {
+		m.tenants[tenant] = q
+	}
+	return m
+}
+
+// Tenant returns the tenant identifier carried in req, or "" if none.
+func (m *QuotaManager) Tenant(req ExecuteRequest) string {
+	v, ok := req.Metadata[m.tenantKey]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// Usage returns the tenant's usage in the current window.
+func (m *QuotaManager) Usage(ctx context.Context, tenant string) (Usage, error) {
+	q := m.quota(tenant)
+	return m.store.Usage(ctx, tenant, m.window(q))
+}
+
+// Check returns ErrResourceLimit if the tenant of req has exhausted its quota.
+// Requests without a tenant always pass.
+func (m *QuotaManager) Check(ctx context.Context, req ExecuteRequest) error {
+	tenant := m.Tenant(req)
+	if tenant == "" {
+		return nil
+	}
+	q := m.quota(tenant)
+	usage, err := m.store.Usage(ctx, tenant, m.window(q))
+	if err != nil {
+		return err
+	}
+	if limit := q.exceeded(usage); limit != "" {
+		return fmt.Errorf("%w: tenant %q exhausted %s quota", ErrResourceLimit, tenant, limit)
+	}
+	return nil
+}
+
+// Record deducts the usage of a completed execution from the tenant's quota.
+// Requests without a tenant are ignored.
+func (m *QuotaManager) Record(ctx context.Context, req ExecuteRequest, result ExecuteResult) error {
+	tenant := m.Tenant(req)
+	if tenant == "" {
+		return nil
+	}
+	q := m.quota(tenant)
+	_, err := m.store.Add(ctx, tenant, m.window(q), usageOf(req, result))
+	return err
+}
+
+// reserve atomically charges one execution to the tenant of req in the
+// current window, which it returns. If the tenant had already exhausted its
+// quota, the execution is released again and reserve fails with
+// ErrResourceLimit. Requests without a tenant always pass.
+func (m *QuotaManager) reserve(ctx context.Context, req ExecuteRequest) (time.Time, error) {
+	tenant := m.Tenant(req)
+	if tenant == "" {
+		return time.Time{}, nil
+	}
+	q := m.quota(tenant)
+	window := m.window(q)
+	total, err := m.store.Add(ctx, tenant, window, Usage{Executions: 1})
+	if err != nil {
+		return window, err
+	}
+	if limit := q.exceeded(total.Add(Usage{Executions: -1})); limit != "" {
+		_, _ = m.store.Add(context.WithoutCancel(ctx), tenant, window, Usage{Executions: -1})
+		return window, fmt.Errorf("%w: tenant %q exhausted %s quota", ErrResourceLimit, tenant, limit)
+	}
+	return window, nil
+}
+
+// settle completes a reservation made by reserve in window: if the
+// execution ran, the rest of its usage u is added, otherwise the reserved
+// execution is released.
+func (m *QuotaManager) settle(ctx context.Context, req ExecuteRequest, window time.Time, u Usage, ran bool) error {
+	tenant := m.Tenant(req)
+	if tenant == "" {
+		return nil
+	}
+	if ran {
+		u.Executions--
+	} else {
+		u = Usage{Executions: -1}
+	}
+	if u == (Usage{}) {
+		return nil
+	}
+	_, err := m.store.Add(ctx, tenant, window, u)
+	return err
+}
+
+func (m *QuotaManager) quota(tenant string) Quota {
+	if q, ok := m.tenants[tenant]; ok {
+		return q
+	}
+	return m.defaultQuota
+}
+
+// window returns the start of the current window for q.
+func (m *QuotaManager) window(q Quota) time.Time {
+	size := q.Window
+	if size <= 0 {
+		size = defaultQuotaWindow
+	}
+	return m.now().Truncate(size)
+}
+
+// usageOf computes the usage of a single execution.
+func usageOf(req ExecuteRequest, result ExecuteResult) Usage {
+	u := Usage{
+		Executions: 1,
+		Duration:   result.Duration,
+		ToolCalls:  len(result.ToolCalls),
+	}
+	if result.LimitsEnforced.Memory && req.Limits.MemoryBytes > 0 {
+		u.MemoryMBSeconds = float64(req.Limits.MemoryBytes) / (1 << 20) * result.Duration.Seconds()
+	}
+	return u
+}
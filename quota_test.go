@@ -0,0 +1,299 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryQuotaStore(t *testing.T) {
+	store := NewMemoryQuotaStore()
+	ctx := context.Background()
+	w1 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	w2 := w1.Add(time.Hour)
+
+	if _, err := store.Add(ctx, "acme", w1, Usage{Executions: 1, ToolCalls: 2}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	total, err := store.Add(ctx, "acme", w1, Usage{Executions: 1, ToolCalls: 3})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if total.Executions != 2 || total.ToolCalls != 5 {
+		t.Errorf("Add() total = %+v, want 2 executions and 5 tool calls", total)
+	}
+
+	usage, err := store.Usage(ctx, "acme", w2)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if usage != (Usage{}) {
+		t.Errorf("Usage() in new window = %+v, want zero", usage)
+	}
+
+	total, _ = store.Add(ctx, "acme", w2, Usage{Executions: 1})
+	if total.Executions != 1 {
+		t.Errorf("Add() in new window = %+v, want reset usage", total)
+	}
+
+	ctxCanceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := store.Usage(ctxCanceled, "acme", w2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Usage() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestQuotaManagerTenant(t *testing.T) {
+	m := NewQuotaManager(QuotaConfig{})
+	if got := m.Tenant(ExecuteRequest{Metadata: map[string]any{"tenant": "acme"}}); got != "acme" {
+		t.Errorf("Tenant() = %q, want %q", got, "acme")
+	}
+	if got := m.Tenant(ExecuteRequest{Metadata: map[string]any{"tenant": 42}}); got != "42" {
+		t.Errorf("Tenant() = %q, want %q", got, "42")
+	}
+	if got := m.Tenant(ExecuteRequest{}); got != "" {
+		t.Errorf("Tenant() = %q, want empty", got)
+	}
+
+	m = NewQuotaManager(QuotaConfig{TenantKey: "org"})
+	if got := m.Tenant(ExecuteRequest{Metadata: map[string]any{"org": "acme"}}); got != "acme" {
+		t.Errorf("Tenant() with custom key = %q, want %q", got, "acme")
+	}
+}
+
+func TestQuotaManagerCheck(t *testing.T) {
+	ctx := context.Background()
+	req := ExecuteRequest{Metadata: map[string]any{"tenant": "acme"}}
+
+	tests := []struct {
+		name   string
+		quota  Quota
+		result ExecuteResult
+		req    ExecuteRequest
+	}{
+		{
+			name:   "executions",
+			quota:  Quota{MaxExecutions: 1},
+			result: ExecuteResult{},
+			req:    req,
+		},
+		{
+			name:   "duration",
+			quota:  Quota{MaxDuration: time.Minute},
+			result: ExecuteResult{Duration: 2 * time.Minute},
+			req:    req,
+		},
+		{
+			name:   "tool calls",
+			quota:  Quota{MaxToolCalls: 2},
+			result: ExecuteResult{ToolCalls: []ToolCallRecord{{ToolID: "a"}, {ToolID: "b"}}},
+			req:    req,
+		},
+		{
+			name:   "memory-seconds",
+			quota:  Quota{MaxMemoryMBSeconds: 100},
+			result: ExecuteResult{Duration: 2 * time.Second, LimitsEnforced: LimitsEnforced{Memory: true}},
+			req: ExecuteRequest{
+				Metadata: map[string]any{"tenant": "acme"},
+				Limits:   Limits{MemoryBytes: 64 << 20},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewQuotaManager(QuotaConfig{Tenants: map[string]Quota{"acme": tt.quota}})
+
+			if err := m.Check(ctx, tt.req); err != nil {
+				t.Fatalf("Check() before usage error = %v", err)
+			}
+			if err := m.Record(ctx, tt.req, tt.result); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+			if err := m.Check(ctx, tt.req); !errors.Is(err, ErrResourceLimit) {
+				t.Errorf("Check() after usage error = %v, want %v", err, ErrResourceLimit)
+			}
+		})
+	}
+}
+
+func TestQuotaManagerWindowReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	m := NewQuotaManager(QuotaConfig{
+		Default: Quota{MaxExecutions: 1, Window: time.Hour},
+		Now:     func() time.Time { return now },
+	})
+	ctx := context.Background()
+	req := ExecuteRequest{Metadata: map[string]any{"tenant": "acme"}}
+
+	_ = m.Record(ctx, req, ExecuteResult{})
+	if err := m.Check(ctx, req); !errors.Is(err, ErrResourceLimit) {
+		t.Fatalf("Check() error = %v, want %v", err, ErrResourceLimit)
+	}
+
+	now = now.Add(time.Hour)
+	if err := m.Check(ctx, req); err != nil {
+		t.Errorf("Check() in next window error = %v, want nil", err)
+	}
+}
+
+func TestQuotaManagerIgnoresRequestsWithoutTenant(t *testing.T) {
+	m := NewQuotaManager(QuotaConfig{Default: Quota{MaxExecutions: 1}})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_ = m.Record(ctx, ExecuteRequest{}, ExecuteResult{})
+		if err := m.Check(ctx, ExecuteRequest{}); err != nil {
+			t.Fatalf("Check() error = %v, want nil", err)
+		}
+	}
+}
+
+func TestDefaultRuntimeQuotas(t *testing.T) {
+	quotas := NewQuotaManager(QuotaConfig{
+		Tenants: map[string]Quota{"acme": {MaxToolCalls: 2}},
+	})
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &toolBackend{
+				countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}},
+				tools:           []string{"a", "b"},
+			},
+		},
+		Quotas:         quotas,
+		DefaultProfile: ProfileDev,
+	})
+	req := ExecuteRequest{
+		Code:     "test",
+		Gateway:  &mockToolGateway{},
+		Metadata: map[string]any{"tenant": "acme"},
+	}
+
+	if _, err := rt.Execute(context.Background(), req); err != nil {
+		t.Fatalf("first Execute() error = %v", err)
+	}
+
+	usage, err := quotas.Usage(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if usage.Executions != 1 || usage.ToolCalls != 2 {
+		t.Errorf("Usage() = %+v, want 1 execution and 2 tool calls", usage)
+	}
+
+	if _, err := rt.Execute(context.Background(), req); !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("second Execute() error = %v, want %v", err, ErrResourceLimit)
+	}
+}
+
+func TestDefaultRuntimeQuotasConcurrent(t *testing.T) {
+	quotas := NewQuotaManager(QuotaConfig{Default: Quota{MaxExecutions: 3}})
+	backend := newBlockingBackend(BackendUnsafeHost)
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+		Quotas:         quotas,
+		DefaultProfile: ProfileDev,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Metadata: map[string]any{"tenant": "acme"}}
+
+	// Requests in flight hold their reservation, so only three of them run
+	const requests = 10
+	errs := make(chan error, requests)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := rt.Execute(context.Background(), req)
+			errs <- err
+		}()
+	}
+	for range 3 {
+		<-backend.started
+	}
+	for range requests - 3 {
+		if err := <-errs; !errors.Is(err, ErrResourceLimit) {
+			t.Errorf("Execute() error = %v, want ErrResourceLimit", err)
+		}
+	}
+	close(backend.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Execute() error = %v, want nil", err)
+		}
+	}
+	usage, err := quotas.Usage(context.Background(), "acme")
+	if err != nil || usage.Executions != 3 {
+		t.Errorf("Usage() = %+v, %v; want 3 executions", usage, err)
+	}
+}
+
+// sharedRecorderGateway records the tool calls of every execution it serves
+type sharedRecorderGateway struct {
+	mockToolGateway
+	calls []ToolCallRecord
+}
+
+func (g *sharedRecorderGateway) GetToolCalls() []ToolCallRecord {
+	return g.calls
+}
+
+func TestDefaultRuntimeQuotasCountOwnToolCalls(t *testing.T) {
+	quotas := NewQuotaManager(QuotaConfig{})
+	backend := &toolBackend{
+		countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost}},
+		tools:           []string{"a"},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+		Quotas:         quotas,
+		DefaultProfile: ProfileDev,
+	})
+
+	// Calls of other executions recorded by a shared gateway are not charged
+	gateway := &sharedRecorderGateway{calls: []ToolCallRecord{{ToolID: "x"}, {ToolID: "y"}, {ToolID: "z"}}}
+	req := ExecuteRequest{Code: "test", Gateway: gateway, Metadata: map[string]any{"tenant": "acme"}}
+	if _, err := rt.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	// Calls of failed executions are charged
+	backend.executeErr = errors.New("boom")
+	if _, err := rt.Execute(context.Background(), req); err == nil {
+		t.Fatal("Execute() error = nil, want error")
+	}
+
+	usage, err := quotas.Usage(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if usage.Executions != 2 || usage.ToolCalls != 2 {
+		t.Errorf("Usage() = %+v, want 2 executions and 2 tool calls", usage)
+	}
+}
+
+func TestDefaultRuntimeQuotasSkipUnexecuted(t *testing.T) {
+	quotas := NewQuotaManager(QuotaConfig{Default: Quota{MaxExecutions: 1}})
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Quotas:         quotas,
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{
+		Code:     "test",
+		Gateway:  &mockToolGateway{},
+		Metadata: map[string]any{"tenant": "acme"},
+	}
+
+	// No backend is configured, so nothing runs and no usage is recorded
+	_, _ = rt.Execute(context.Background(), req)
+
+	usage, _ := quotas.Usage(context.Background(), "acme")
+	if usage.Executions != 0 {
+		t.Errorf("Usage().Executions = %d, want 0", usage.Executions)
+	}
+}
@@ -0,0 +1,299 @@
+package toolruntime
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces secret values in redacted output.
+const Redacted = "[REDACTED]"
+
+// validateEnv checks that Env and Secrets use valid, distinct variable names
+// that do not shadow the variables set by the runtime.
+func validateEnv(env, secrets map[string]string) error {
+	for _, vars := range []map[string]string{env, secrets} {
+		for name, value := range vars {
+			if name == "" || strings.ContainsAny(name, "=\x00") {
+				return fmt.Errorf("%w: invalid variable name %q", ErrInvalidEnv, name)
+			}
+			if strings.HasPrefix(name, "TOOLRUNTIME_") {
+				return fmt.Errorf("%w: %s is reserved for the runtime", ErrInvalidEnv, name)
+			}
+			if strings.ContainsRune(value, 0) {
+				return fmt.Errorf("%w: value of %s contains NUL", ErrInvalidEnv, name)
+			}
+		}
+	}
+	for name := range secrets {
+		if _, ok := env[name]; ok {
+			return fmt.Errorf("%w: %s is set in both Env and Secrets", ErrInvalidEnv, name)
+		}
+	}
+	return nil
+}
+
+// EnvList returns env and secrets as KEY=value entries sorted by name, the
+// form in which backends pass them to executed code.
+func EnvList(env, secrets map[string]string) []string {
+	list := make([]string, 0, len(env)+len(secrets))
+	for _, vars := range []map[string]string{env, secrets} {
+		for name, value := range vars {
+			list = append(list, name+"="+value)
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
+// Redactor scrubs secret values from execution output. A nil Redactor leaves
+// everything unchanged.
+type Redactor struct {
+	replacer *strings.Replacer
+	maxLen   int
+}
+
+// NewRedactor returns a Redactor for the values of secrets, or nil if there
+// are no non-empty values. Longer values are matched first, so a secret that
+// contains another is redacted whole.
+func NewRedactor(secrets map[string]string) *Redactor {
+	values := make([]string, 0, len(secrets))
+	for _, v := range secrets {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, Redacted)
+	}
+	return &Redactor{replacer: strings.NewReplacer(pairs...), maxLen: len(values[0])}
+}
+
+// String returns s with every secret value replaced by Redacted.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// Value returns v with secrets redacted from strings, including those nested
+// in maps and slices decoded from JSON. Other values are returned unchanged.
+func (r *Redactor) Value(v any) any {
+	if r == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case []byte:
+		return []byte(r.String(string(v)))
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = r.Value(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[r.String(k)] = r.Value(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Error returns err with secrets redacted from its message. errors.Is still
+// matches the sentinels err wraps, but the returned error does not unwrap to
+// err, so errors.As cannot recover the unredacted message.
+func (r *Redactor) Error(err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+	msg := err.Error()
+	if redacted := r.String(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
+
+// Result returns result with secrets redacted from Stdout, Stderr, Value,
+// Metadata, the artifacts and the spill files. Spill files that cannot be
+// redacted are removed.
+func (r *Redactor) Result(result ExecuteResult) ExecuteResult {
+	if r == nil {
+		return result
+	}
+	result.Stdout = r.String(result.Stdout)
+	result.Stderr = r.String(result.Stderr)
+	result.Value = r.Value(result.Value)
+	if result.Metadata != nil {
+		result.Metadata = r.Value(result.Metadata).(map[string]any)
+	}
+	if result.Artifacts != nil {
+		result.Artifacts = r.artifacts(result.Artifacts)
+	}
+	for _, path := range []*string{&result.StdoutFile, &result.StderrFile} {
+		if *path == "" {
+			continue
+		}
+		if err := r.File(*path); err != nil {
+			_ = os.Remove(*path)
+			*path = ""
+		}
+	}
+	return result
+}
+
+// artifacts returns a copy of artifacts with secrets redacted from their
+// paths and content. Redacted artifacts keep their MIME type and get a new
+// hash.
+func (r *Redactor) artifacts(artifacts []Artifact) []Artifact {
+	out := make([]Artifact, len(artifacts))
+	for i, a := range artifacts {
+		path, content := r.String(a.Path), r.String(string(a.Content))
+		if path != a.Path || content != string(a.Content) {
+			mimeType := a.MIMEType
+			a = NewArtifact(path, []byte(content))
+			a.MIMEType = mimeType
+		}
+		out[i] = a
+	}
+	return out
+}
+
+// File redacts the file at path in place.
+func (r *Redactor) File(path string) error {
+	if r == nil {
+		return nil
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".redact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	s := streamRedactor{r: r}
+	buf := make([]byte, 32<<10)
+	for err == nil {
+		var n int
+		n, err = in.Read(buf)
+		if _, werr := out.Write(s.write(buf[:n])); werr != nil {
+			err = werr
+		}
+	}
+	if err == io.EOF {
+		_, err = out.Write(s.flush())
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), path)
+}
+
+// redactedError carries a redacted message for an error. It matches the
+// targets of err through Is but does not expose err itself.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Is(target error) bool { return errors.Is(e.err, target) }
+
+// Tracer returns a Tracer whose spans redact secrets from recorded errors,
+// attributes and events before passing them to tracer. It returns tracer
+// unchanged for a nil Redactor or tracer.
+func (r *Redactor) Tracer(tracer Tracer) Tracer {
+	if r == nil || tracer == nil {
+		return tracer
+	}
+	return redactingTracer{tracer: tracer, r: r}
+}
+
+// redactingTracer wraps the spans of a Tracer in redactingSpan.
+type redactingTracer struct {
+	tracer Tracer
+	r      *Redactor
+}
+
+func (t redactingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, name, t.r.attrs(attrs)...)
+	return ctx, redactingSpan{span: span, r: t.r}
+}
+
+// redactingSpan redacts secrets before they reach the wrapped Span.
+type redactingSpan struct {
+	span Span
+	r    *Redactor
+}
+
+func (s redactingSpan) SetAttributes(attrs ...Attribute) { s.span.SetAttributes(s.r.attrs(attrs)...) }
+func (s redactingSpan) AddEvent(name string, attrs ...Attribute) {
+	s.span.AddEvent(s.r.String(name), s.r.attrs(attrs)...)
+}
+func (s redactingSpan) RecordError(err error) { s.span.RecordError(s.r.Error(err)) }
+func (s redactingSpan) End()                  { s.span.End() }
+
+// attrs returns attrs with secrets redacted from their values.
+func (r *Redactor) attrs(attrs []Attribute) []Attribute {
+	out := make([]Attribute, len(attrs))
+	for i, a := range attrs {
+		out[i] = Attribute{Key: a.Key, Value: r.Value(a.Value)}
+	}
+	return out
+}
+
+// streamRedactor redacts output that arrives in chunks, where a secret may
+// be split across chunks. It holds back the tail of the output that could
+// start a secret until more output or a flush arrives.
+type streamRedactor struct {
+	r       *Redactor
+	pending string
+}
+
+// write returns the redacted output of chunk that is safe to emit.
+func (s *streamRedactor) write(chunk []byte) []byte {
+	if s.r == nil {
+		return chunk
+	}
+	data := s.r.String(s.pending + string(chunk))
+	keep := min(len(data), s.r.maxLen-1)
+	s.pending = data[len(data)-keep:]
+	return []byte(data[:len(data)-keep])
+}
+
+// flush returns the held back output.
+func (s *streamRedactor) flush() []byte {
+	if s.pending == "" {
+		return nil
+	}
+	data := s.r.String(s.pending)
+	s.pending = ""
+	return []byte(data)
+}
@@ -0,0 +1,202 @@
+package toolruntime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecuteRequestValidateEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		secrets map[string]string
+		wantErr bool
+	}{
+		{"env and secrets", map[string]string{"MODE": "fast"}, map[string]string{"API_KEY": "k"}, false},
+		{"empty name", map[string]string{"": "x"}, nil, true},
+		{"name with equals", map[string]string{"A=B": "x"}, nil, true},
+		{"value with NUL", nil, map[string]string{"KEY": "a\x00b"}, true},
+		{"reserved name", map[string]string{OutputDirEnv: "/tmp"}, nil, true},
+		{"name in both", map[string]string{"KEY": "a"}, map[string]string{"KEY": "b"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Env: tt.env, Secrets: tt.secrets}
+			err := req.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidEnv) {
+				t.Errorf("Validate() error = %v, want ErrInvalidEnv", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestEnvList(t *testing.T) {
+	got := EnvList(map[string]string{"B": "2"}, map[string]string{"A": "1"})
+	if strings.Join(got, ",") != "A=1,B=2" {
+		t.Errorf("EnvList() = %v, want [A=1 B=2]", got)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor(map[string]string{"SHORT": "abc", "LONG": "abcdef", "EMPTY": ""})
+
+	if got := r.String("key=abcdef, prefix=abc"); got != "key=[REDACTED], prefix=[REDACTED]" {
+		t.Errorf("String() = %q", got)
+	}
+	value := r.Value(map[string]any{"token": "abc", "list": []any{"x abcdef", 1.0}})
+	if fmt.Sprint(value) != "map[list:[x [REDACTED] 1] token:[REDACTED]]" {
+		t.Errorf("Value() = %v", value)
+	}
+
+	err := r.Error(fmt.Errorf("%w: bad key abcdef", ErrSandboxViolation))
+	if err.Error() != "sandbox policy violation: bad key [REDACTED]" || !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("Error() = %v, want redacted and wrapping ErrSandboxViolation", err)
+	}
+	var runtimeErr *RuntimeError
+	if errors.As(r.Error(NewRuntimeError(BackendDocker, "run", errors.New("key abcdef"), false)), &runtimeErr) {
+		t.Errorf("errors.As() recovered the unredacted error %v", runtimeErr)
+	}
+
+	artifact := NewArtifact("out.txt", []byte("key=abcdef"))
+	artifacts := r.Result(ExecuteResult{Artifacts: []Artifact{artifact}}).Artifacts
+	if want := NewArtifact("out.txt", []byte("key=[REDACTED]")); len(artifacts) != 1 || !reflect.DeepEqual(artifacts[0], want) {
+		t.Errorf("Artifacts = %+v, want %+v", artifacts, want)
+	}
+	if string(artifact.Content) != "key=abcdef" {
+		t.Error("Result() modified the original artifact")
+	}
+
+	if NewRedactor(map[string]string{"EMPTY": ""}) != nil {
+		t.Error("NewRedactor() with only empty values should be nil")
+	}
+	var none *Redactor
+	if none.String("abc") != "abc" {
+		t.Error("nil Redactor should leave strings unchanged")
+	}
+}
+
+func TestStreamRedactorSplitSecret(t *testing.T) {
+	s := streamRedactor{r: NewRedactor(map[string]string{"KEY": "s3cret"})}
+	var out []byte
+	for _, chunk := range []string{"token: s3", "cr", "et done", "!"} {
+		out = append(out, s.write([]byte(chunk))...)
+	}
+	out = append(out, s.flush()...)
+	if string(out) != "token: [REDACTED] done!" {
+		t.Errorf("output = %q, want secret redacted across chunks", out)
+	}
+}
+
+func TestDefaultRuntimeRedactsSecrets(t *testing.T) {
+	backend := &mockBackend{
+		kind: BackendDocker,
+		result: ExecuteResult{
+			Stdout:   "using s3cret\n",
+			Stderr:   "s3cret",
+			Value:    map[string]any{"key": "s3cret"},
+			Metadata: map[string]any{"auth": []any{"token s3cret"}},
+		},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Secrets: map[string]string{"API_KEY": "s3cret"}}
+
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "using [REDACTED]\n" || result.Stderr != Redacted || fmt.Sprint(result.Value) != "map[key:[REDACTED]]" {
+		t.Errorf("result = %+v, want secret redacted", result)
+	}
+	if fmt.Sprint(result.Metadata) != "map[auth:[token [REDACTED]]]" {
+		t.Errorf("Metadata = %v, want secret redacted", result.Metadata)
+	}
+
+	backend.executeErr = fmt.Errorf("%w: rejected s3cret", ErrSandboxViolation)
+	_, err = rt.Execute(context.Background(), req)
+	if !errors.Is(err, ErrSandboxViolation) || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Execute() error = %v, want redacted ErrSandboxViolation", err)
+	}
+}
+
+// spanBackend records its failure on a span before returning it
+type spanBackend struct {
+	mockBackend
+}
+
+func (b *spanBackend) Execute(ctx context.Context, _ ExecuteRequest) (ExecuteResult, error) {
+	_, span := StartSpan(ctx, "backend.run", Attr("command", "login s3cret"))
+	defer span.End()
+	span.RecordError(b.executeErr)
+	return ExecuteResult{}, b.executeErr
+}
+
+func TestDefaultRuntimeRedactsSpans(t *testing.T) {
+	var buf bytes.Buffer
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{ProfileStandard: &spanBackend{
+			mockBackend: mockBackend{kind: BackendDocker, executeErr: errors.New("auth failed for s3cret")},
+		}},
+		DefaultProfile: ProfileStandard,
+		Tracer:         NewOTLPJSONTracer(&buf, "test"),
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Secrets: map[string]string{"API_KEY": "s3cret"}}
+
+	if _, err := rt.Execute(context.Background(), req); err == nil {
+		t.Fatal("Execute() error = nil, want backend error")
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("spans leak the secret: %s", buf.String())
+	}
+	span, ok := decodeSpans(t, &buf)["backend.run"]
+	if !ok || span.Status.Message != "auth failed for [REDACTED]" {
+		t.Errorf("backend span = %+v, want redacted error status", span)
+	}
+}
+
+func TestExecuteStreamRedactsSecrets(t *testing.T) {
+	final := ExecuteResult{Stdout: "key=s3cret\n"}
+	backend := &streamBackend{
+		mockBackend: mockBackend{kind: BackendDocker},
+		events: []StreamEvent{
+			{Type: StreamEventStdout, Data: []byte("key=s3c")},
+			{Type: StreamEventStdout, Data: []byte("ret\n")},
+			{Type: StreamEventExit, Result: &final},
+		},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile: ProfileStandard,
+	})
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Secrets: map[string]string{"API_KEY": "s3cret"}}
+
+	ch, err := rt.ExecuteStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var stdout string
+	var last StreamEvent
+	for _, ev := range collectEvents(t, ch) {
+		if ev.Type == StreamEventStdout {
+			stdout += string(ev.Data)
+		}
+		last = ev
+	}
+	if stdout != "key=[REDACTED]\n" {
+		t.Errorf("streamed stdout = %q, want secret redacted", stdout)
+	}
+	if last.Type != StreamEventExit || last.Result.Stdout != "key=[REDACTED]\n" {
+		t.Errorf("last event = %+v, want exit with redacted stdout", last)
+	}
+}
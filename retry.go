@@ -0,0 +1,71 @@
+package toolruntime
+
+import (
+	"context"
+	"time"
+)
+
+// Defaults for RetryPolicy.
+const (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Second
+)
+
+// RetryPolicy configures automatic retries of backend failures. Only errors
+// that wrap a RuntimeError marked Retryable are retried; backends set it
+// only for failures that happened before user code started, so retried
+// requests never run user code twice.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries per backend after the first attempt.
+	// If zero, failures are not retried.
+	MaxRetries int
+
+	// Backoff is the delay before the first retry. It doubles after each
+	// retry, up to MaxBackoff.
+	// Default: 100ms
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between retries.
+	// Default: 5s
+	MaxBackoff time.Duration
+}
+
+// delay returns the backoff before retry number n (starting at 0).
+func (p RetryPolicy) delay(n int) time.Duration {
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	for i := 0; i < n && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// runBackendWithRetry runs req on backend, retrying retryable failures
+// according to the runtime's retry policy.
+func (r *DefaultRuntime) runBackendWithRetry(ctx context.Context, backend Backend, req ExecuteRequest, queueWait *time.Duration) (ExecuteResult, error) {
+	for n := 0; ; n++ {
+		result, err := r.runBackend(ctx, backend, req, queueWait)
+		if err == nil || n >= r.retry.MaxRetries || !IsRetryable(err) || ctx.Err() != nil {
+			return result, err
+		}
+
+		delay := r.retry.delay(n)
+		if r.logger != nil {
+			r.logger.Warn("retrying backend", "executionID", req.ExecutionID, "backend", backend.Kind(),
+				"retry", n+1, "delay", delay, "error", err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
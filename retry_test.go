@@ -0,0 +1,118 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyBackend fails with errs in order, then succeeds
+type flakyBackend struct {
+	mockBackend
+	errs  []error
+	calls int
+}
+
+func (b *flakyBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	b.calls++
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		return ExecuteResult{}, err
+	}
+	return b.mockBackend.Execute(ctx, req)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for n, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := p.delay(n); got != want*time.Millisecond {
+			t.Errorf("delay(%d) = %v, want %v", n, got, want*time.Millisecond)
+		}
+	}
+	if got := (RetryPolicy{}).delay(0); got != defaultRetryBackoff {
+		t.Errorf("default delay(0) = %v, want %v", got, defaultRetryBackoff)
+	}
+}
+
+func TestDefaultRuntimeRetry(t *testing.T) {
+	transient := NewRuntimeError(BackendDocker, "health_check", ErrRuntimeUnavailable, true)
+	permanent := NewRuntimeError(BackendDocker, "container_run", ErrSandboxViolation, false)
+
+	tests := []struct {
+		name      string
+		errs      []error
+		retries   int
+		wantCalls int
+		wantErr   error
+	}{
+		{"retries transient failure", []error{transient, transient}, 3, 3, nil},
+		{"gives up after max retries", []error{transient, transient, transient}, 1, 2, ErrRuntimeUnavailable},
+		{"does not retry permanent failure", []error{permanent}, 3, 1, ErrSandboxViolation},
+		{"disabled by default", []error{transient}, 0, 1, ErrRuntimeUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &flakyBackend{mockBackend: mockBackend{kind: BackendDocker}, errs: tt.errs}
+			rt := NewDefaultRuntime(RuntimeConfig{
+				Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+				Retry:          RetryPolicy{MaxRetries: tt.retries, Backoff: time.Millisecond},
+				DefaultProfile: ProfileStandard,
+			})
+
+			_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			if backend.calls != tt.wantCalls {
+				t.Errorf("backend calls = %d, want %d", backend.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDefaultRuntimeRetryThenFallback(t *testing.T) {
+	transient := NewRuntimeError(BackendDocker, "health_check", ErrRuntimeUnavailable, true)
+	primary := &flakyBackend{mockBackend: mockBackend{kind: BackendDocker}, errs: []error{transient, transient}}
+	fallback := &countingBackend{mockBackend: mockBackend{kind: BackendGVisor}}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+		Retry:          RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond},
+		DefaultProfile: ProfileStandard,
+	})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if primary.calls != 2 || fallback.calls != 1 || result.Backend.Kind != BackendGVisor {
+		t.Errorf("primary calls = %d, fallback calls = %d, backend = %q; want 2, 1, gvisor",
+			primary.calls, fallback.calls, result.Backend.Kind)
+	}
+}
+
+func TestDefaultRuntimeRetryCanceled(t *testing.T) {
+	transient := NewRuntimeError(BackendDocker, "health_check", ErrRuntimeUnavailable, true)
+	backend := &flakyBackend{mockBackend: mockBackend{kind: BackendDocker}, errs: []error{transient, transient}}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		Retry:          RetryPolicy{MaxRetries: 5, Backoff: time.Hour},
+		DefaultProfile: ProfileStandard,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := rt.Execute(ctx, ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Fatalf("Execute() error = %v, want last backend error", err)
+	}
+	if backend.calls != 1 {
+		t.Errorf("backend calls = %d, want 1", backend.calls)
+	}
+}
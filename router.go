@@ -0,0 +1,139 @@
+package toolruntime
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Router selects the ordered backend candidates for a request.
+// DefaultRuntime tries the returned backends in order, falling back on
+// ErrRuntimeUnavailable as described in RuntimeConfig.Fallbacks.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Ownership: req and candidates are read-only; return a new slice when changing candidates.
+// - Lifecycle: returned backends that are not registered stay caller-owned; Shutdown does not close them.
+// - Errors: return ErrBackendDenied (wrapped) to refuse a request by policy.
+type Router interface {
+	// Route returns the backends to try for req, in order.
+	// candidates holds the backends configured for the request's profile
+	// (primary first, then fallbacks), and req.Profile is the effective profile.
+	Route(ctx context.Context, req ExecuteRequest, candidates []Backend) ([]Backend, error)
+}
+
+// RouterFunc adapts a function to the Router interface.
+type RouterFunc func(ctx context.Context, req ExecuteRequest, candidates []Backend) ([]Backend, error)
+
+// Route calls f(ctx, req, candidates).
+func (f RouterFunc) Route(ctx context.Context, req ExecuteRequest, candidates []Backend) ([]Backend, error) {
+	return f(ctx, req, candidates)
+}
+
+// Policy is a Router that applies its rules in order. Each rule receives the
+// candidates returned by the previous rule, so later rules take precedence.
+// An empty Policy returns the candidates unchanged.
+type Policy []Router
+
+// Route applies each rule in order, stopping at the first error.
+func (p Policy) Route(ctx context.Context, req ExecuteRequest, candidates []Backend) ([]Backend, error) {
+	for _, rule := range p {
+		if rule == nil {
+			continue
+		}
+		var err error
+		candidates, err = rule.Route(ctx, req, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return candidates, nil
+}
+
+// Matcher reports whether a routing rule applies to a request.
+type Matcher func(req ExecuteRequest) bool
+
+// MatchLanguage matches requests whose Language is one of langs (case-insensitive).
+func MatchLanguage(langs ...string) Matcher {
+	return func(req ExecuteRequest) bool {
+		for _, lang := range langs {
+			if strings.EqualFold(req.Language, lang) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchProfile matches requests whose effective profile is one of profiles.
+func MatchProfile(profiles ...SecurityProfile) Matcher {
+	return func(req ExecuteRequest) bool {
+		for _, p := range profiles {
+			if req.Profile == p {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchMetadata matches requests whose Metadata[key] equals value.
+func MatchMetadata(key string, value any) Matcher {
+	return func(req ExecuteRequest) bool {
+		v, ok := req.Metadata[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// MatchAll matches requests that satisfy every matcher.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(req ExecuteRequest) bool {
+		for _, m := range matchers {
+			if !m(req) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RouteTo returns a rule that replaces the candidates with backends when
+// match reports true, and leaves them unchanged otherwise.
+func RouteTo(match Matcher, backends ...Backend) Router {
+	routed := append([]Backend(nil), backends...)
+	return RouterFunc(func(_ context.Context, req ExecuteRequest, candidates []Backend) ([]Backend, error) {
+		if match(req) {
+			return routed, nil
+		}
+		return candidates, nil
+	})
+}
+
+// DenyUnsafe returns a rule that removes unsafe host backends from the
+// candidates for the given profiles. If that leaves no candidates, the rule
+// returns ErrBackendDenied.
+//
+// DefaultRuntime always applies DenyUnsafe(RuntimeConfig.DenyUnsafeProfiles...)
+// after RuntimeConfig.Router, so custom rules cannot route around it.
+func DenyUnsafe(profiles ...SecurityProfile) Router {
+	denied := make(map[SecurityProfile]bool, len(profiles))
+	for _, p := range profiles {
+		denied[p] = true
+	}
+	return RouterFunc(func(_ context.Context, req ExecuteRequest, candidates []Backend) ([]Backend, error) {
+		if !denied[req.Profile] {
+			return candidates, nil
+		}
+		allowed := make([]Backend, 0, len(candidates))
+		for _, b := range candidates {
+			if b.Kind() != BackendUnsafeHost {
+				allowed = append(allowed, b)
+			}
+		}
+		if len(allowed) == 0 && len(candidates) > 0 {
+			return nil, fmt.Errorf("%w: unsafe backend denied for profile %q", ErrBackendDenied, req.Profile)
+		}
+		return allowed, nil
+	})
+}
@@ -0,0 +1,205 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPolicyAppliesRulesInOrder(t *testing.T) {
+	docker := &mockBackend{kind: BackendDocker}
+	gvisor := &mockBackend{kind: BackendGVisor}
+
+	policy := Policy{
+		RouteTo(MatchLanguage("python"), docker),
+		RouteTo(MatchMetadata("tenant", "external"), gvisor),
+	}
+
+	tests := []struct {
+		name string
+		req  ExecuteRequest
+		want BackendKind
+	}{
+		{
+			name: "python routes to docker",
+			req:  ExecuteRequest{Language: "Python"},
+			want: BackendDocker,
+		},
+		{
+			name: "external tenant overrides language",
+			req:  ExecuteRequest{Language: "python", Metadata: map[string]any{"tenant": "external"}},
+			want: BackendGVisor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.Route(context.Background(), tt.req, nil)
+			if err != nil {
+				t.Fatalf("Route() error = %v", err)
+			}
+			if len(got) != 1 || got[0].Kind() != tt.want {
+				t.Errorf("Route() = %v, want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyUnmatchedKeepsCandidates(t *testing.T) {
+	candidates := []Backend{&mockBackend{kind: BackendDocker}}
+	policy := Policy{RouteTo(MatchLanguage("go"), &mockBackend{kind: BackendUnsafeHost})}
+
+	got, err := policy.Route(context.Background(), ExecuteRequest{Language: "python"}, candidates)
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != candidates[0] {
+		t.Errorf("Route() = %v, want candidates unchanged", got)
+	}
+}
+
+func TestPolicyStopsAtError(t *testing.T) {
+	ruleErr := errors.New("denied by rule")
+	called := false
+	policy := Policy{
+		RouterFunc(func(_ context.Context, _ ExecuteRequest, _ []Backend) ([]Backend, error) {
+			return nil, ruleErr
+		}),
+		RouterFunc(func(_ context.Context, _ ExecuteRequest, c []Backend) ([]Backend, error) {
+			called = true
+			return c, nil
+		}),
+	}
+
+	_, err := policy.Route(context.Background(), ExecuteRequest{}, nil)
+	if !errors.Is(err, ruleErr) {
+		t.Errorf("Route() error = %v, want %v", err, ruleErr)
+	}
+	if called {
+		t.Error("Route() should not apply rules after an error")
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	req := ExecuteRequest{
+		Language: "go",
+		Profile:  ProfileDev,
+		Metadata: map[string]any{"tenant": "acme"},
+	}
+
+	tests := []struct {
+		name  string
+		match Matcher
+		want  bool
+	}{
+		{"language match", MatchLanguage("python", "GO"), true},
+		{"language mismatch", MatchLanguage("python"), false},
+		{"profile match", MatchProfile(ProfileDev), true},
+		{"profile mismatch", MatchProfile(ProfileHardened), false},
+		{"metadata match", MatchMetadata("tenant", "acme"), true},
+		{"metadata mismatch", MatchMetadata("tenant", "other"), false},
+		{"metadata missing", MatchMetadata("caller", "x"), false},
+		{"all match", MatchAll(MatchLanguage("go"), MatchProfile(ProfileDev)), true},
+		{"all mismatch", MatchAll(MatchLanguage("go"), MatchProfile(ProfileStandard)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.match(req); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDenyUnsafeRule(t *testing.T) {
+	unsafeBackend := &mockBackend{kind: BackendUnsafeHost}
+	docker := &mockBackend{kind: BackendDocker}
+	rule := DenyUnsafe(ProfileStandard)
+	ctx := context.Background()
+
+	t.Run("filters unsafe candidates", func(t *testing.T) {
+		got, err := rule.Route(ctx, ExecuteRequest{Profile: ProfileStandard}, []Backend{unsafeBackend, docker})
+		if err != nil {
+			t.Fatalf("Route() error = %v", err)
+		}
+		if len(got) != 1 || got[0] != docker {
+			t.Errorf("Route() = %v, want [docker]", got)
+		}
+	})
+
+	t.Run("denies when only unsafe remains", func(t *testing.T) {
+		_, err := rule.Route(ctx, ExecuteRequest{Profile: ProfileStandard}, []Backend{unsafeBackend})
+		if !errors.Is(err, ErrBackendDenied) {
+			t.Errorf("Route() error = %v, want %v", err, ErrBackendDenied)
+		}
+	})
+
+	t.Run("ignores other profiles", func(t *testing.T) {
+		got, err := rule.Route(ctx, ExecuteRequest{Profile: ProfileDev}, []Backend{unsafeBackend})
+		if err != nil {
+			t.Fatalf("Route() error = %v", err)
+		}
+		if len(got) != 1 {
+			t.Errorf("Route() = %v, want [unsafe]", got)
+		}
+	})
+}
+
+func TestDefaultRuntimeRouter(t *testing.T) {
+	devBackend := &mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "unsafe"}}
+	pythonBackend := &mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "python"}}
+	gvisorBackend := &mockBackend{kind: BackendGVisor, result: ExecuteResult{Value: "gvisor"}}
+
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: devBackend,
+		},
+		Router: Policy{
+			RouteTo(MatchLanguage("python"), pythonBackend),
+			RouteTo(MatchMetadata("tenant", "external"), gvisorBackend),
+		},
+		DefaultProfile: ProfileDev,
+	})
+
+	tests := []struct {
+		name string
+		req  ExecuteRequest
+		want any
+	}{
+		{"go uses profile backend", ExecuteRequest{Language: "go"}, "unsafe"},
+		{"python routed to docker", ExecuteRequest{Language: "python"}, "python"},
+		{"external tenant forced to gvisor", ExecuteRequest{Language: "go", Metadata: map[string]any{"tenant": "external"}}, "gvisor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Code = "test"
+			tt.req.Gateway = &mockToolGateway{}
+			result, err := rt.Execute(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("Execute().Value = %v, want %v", result.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRuntimeRouterCannotBypassDenyUnsafe(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Router:             RouteTo(MatchLanguage("go"), &mockBackend{kind: BackendUnsafeHost}),
+		DenyUnsafeProfiles: []SecurityProfile{ProfileStandard},
+		DefaultProfile:     ProfileStandard,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{
+		Language: "go",
+		Code:     "test",
+		Gateway:  &mockToolGateway{},
+	})
+	if !errors.Is(err, ErrBackendDenied) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrBackendDenied)
+	}
+}
@@ -2,13 +2,18 @@ package toolruntime
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolrun"
 )
 
 // Runtime is the main interface for code execution.
 // It manages backends and routes execution requests to the appropriate backend
-// based on the security profile.
+// based on the security profile and routing policy.
 //
 // Contract:
 // - Concurrency: implementations must be safe for concurrent use.
@@ -28,9 +33,67 @@ type RuntimeConfig struct {
 	// Backends maps security profiles to their backend implementations.
 	Backends map[SecurityProfile]Backend
 
+	// Fallbacks maps security profiles to ordered backends that are tried, in
+	// order, when the preceding backend fails with ErrRuntimeUnavailable.
+	// Fallbacks whose isolation is weaker than the profile's MinIsolation are
+	// never used.
+	Fallbacks map[SecurityProfile][]Backend
+
+	// Router optionally selects backends from the full request (language,
+	// metadata, code size, limits). It receives the profile's configured
+	// backends as candidates. Use Policy to compose rules. Routed backends
+	// whose isolation is weaker than the profile's MinIsolation are never
+	// used, unless they are the backend configured for the profile. Routed
+	// backends that are not registered are not closed by Shutdown.
+	Router Router
+
+	// Interceptors wrap every Execute call, before request validation and
+	// backend selection. The first interceptor is the outermost.
+	Interceptors []Interceptor
+
+	// BackendInterceptors wrap every backend invocation, including each
+	// fallback attempt. The first interceptor is the outermost.
+	BackendInterceptors []BackendInterceptor
+
+	// ProfileLimits configures admission control per security profile. A
+	// profile slot is held for the whole execution, including fallbacks.
+	ProfileLimits map[SecurityProfile]ConcurrencyLimit
+
+	// BackendLimits configures admission control per backend kind. A backend
+	// slot is held only while that backend is executing.
+	BackendLimits map[BackendKind]ConcurrencyLimit
+
+	// Quotas optionally enforces per-tenant usage quotas. Requests from a
+	// tenant that exhausted its quota fail with ErrResourceLimit.
+	Quotas *QuotaManager
+
+	// Tracer optionally records spans for each execution. It is propagated
+	// through the context so backends and gateways can add child spans.
+	Tracer Tracer
+
+	// Metrics optionally records execution counts, durations, queue wait,
+	// tool calls and limit violations.
+	Metrics Metrics
+
+	// Audit optionally receives a record of every execution, including
+	// failed and rejected ones, for compliance logging.
+	Audit AuditSink
+
+	// Health optionally tracks backend health. Every configured backend is
+	// watched, and backends whose circuit is open are skipped like
+	// unavailable backends so requests fail fast or fall back.
+	Health *HealthMonitor
+
+	// Retry optionally retries backend failures marked RuntimeError.Retryable,
+	// with exponential backoff, before falling back to the next backend.
+	Retry RetryPolicy
+
+	// StrictLimits applies ExecuteRequest.StrictLimits to every request.
+	StrictLimits bool
+
 	// DenyUnsafeProfiles lists profiles that cannot use the unsafe backend.
 	// If a profile is listed here and only the unsafe backend is available,
-	// execution will be denied.
+	// execution will be denied. It is applied as a DenyUnsafe rule after Router.
 	DenyUnsafeProfiles []SecurityProfile
 
 	// DefaultProfile is the profile to use when none is specified in the request.
@@ -52,14 +115,29 @@ type Logger interface {
 	Error(msg string, args ...any)
 }
 
-// DefaultRuntime is the default implementation of Runtime.
+// DefaultRuntime is the default implementation of Runtime and StreamingRuntime.
 // It routes requests to backends based on security profiles.
 type DefaultRuntime struct {
-	mu                 sync.RWMutex
-	backends           map[SecurityProfile]Backend
-	denyUnsafeProfiles map[SecurityProfile]bool
-	defaultProfile     SecurityProfile
-	logger             Logger
+	mu             sync.RWMutex
+	backends       map[SecurityProfile]*registration
+	fallbacks      map[SecurityProfile][]*registration
+	router         Router
+	defaultProfile SecurityProfile
+	strictLimits   bool
+	retry          RetryPolicy
+	health         *HealthMonitor
+	logger         Logger
+
+	execute             ExecuteFunc
+	backendInterceptors []BackendInterceptor
+	profileLimiters     map[SecurityProfile]*limiter
+	backendLimiters     map[BackendKind]*limiter
+	quotas              *QuotaManager
+	tracer              Tracer
+	metrics             Metrics
+	audit               AuditSink
+
+	life lifecycle
 }
 
 // toolCallRecorder is an optional interface implemented by gateways that
@@ -72,30 +150,272 @@ type toolCallRecorder interface {
 	GetToolCalls() []ToolCallRecord
 }
 
+// observingGateway wraps the request's gateway and records the IDs of the
+// tools run through it during one execution, whether or not the gateway
+// records tool calls itself. The result cache uses it to detect side effects
+// and the quota manager to charge tool calls.
+type observingGateway struct {
+	ToolGateway
+
+	mu    sync.Mutex
+	tools []string
+}
+
+// RunTool records id and delegates to the wrapped gateway.
+func (g *observingGateway) RunTool(ctx context.Context, id string, args map[string]any) (toolrun.RunResult, error) {
+	g.record(id)
+	return g.ToolGateway.RunTool(ctx, id, args)
+}
+
+// RunChain records the tool of every step and delegates to the wrapped
+// gateway.
+func (g *observingGateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	for _, step := range steps {
+		g.record(step.ToolID)
+	}
+	return g.ToolGateway.RunChain(ctx, steps)
+}
+
+// GetToolCalls exposes the tool call trace of the wrapped gateway, if it
+// records one, so that the runtime can still capture it.
+func (g *observingGateway) GetToolCalls() []ToolCallRecord {
+	if recorder, ok := g.ToolGateway.(toolCallRecorder); ok {
+		return recorder.GetToolCalls()
+	}
+	return nil
+}
+
+func (g *observingGateway) record(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tools = append(g.tools, id)
+}
+
+// observed returns the IDs of the tools run so far.
+func (g *observingGateway) observed() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.tools...)
+}
+
 // NewDefaultRuntime creates a new DefaultRuntime with the given configuration.
 func NewDefaultRuntime(cfg RuntimeConfig) *DefaultRuntime {
-	denyMap := make(map[SecurityProfile]bool)
-	for _, p := range cfg.DenyUnsafeProfiles {
-		denyMap[p] = true
+	var policy Policy
+	if cfg.Router != nil {
+		policy = append(policy, cfg.Router)
+	}
+	if len(cfg.DenyUnsafeProfiles) > 0 {
+		policy = append(policy, DenyUnsafe(cfg.DenyUnsafeProfiles...))
 	}
 
-	return &DefaultRuntime{
-		backends:           cfg.Backends,
-		denyUnsafeProfiles: denyMap,
-		defaultProfile:     cfg.DefaultProfile,
-		logger:             cfg.Logger,
+	r := &DefaultRuntime{
+		backends:            make(map[SecurityProfile]*registration, len(cfg.Backends)),
+		fallbacks:           make(map[SecurityProfile][]*registration, len(cfg.Fallbacks)),
+		router:              policy,
+		defaultProfile:      cfg.DefaultProfile,
+		strictLimits:        cfg.StrictLimits,
+		retry:               cfg.Retry,
+		health:              cfg.Health,
+		logger:              cfg.Logger,
+		backendInterceptors: append([]BackendInterceptor(nil), cfg.BackendInterceptors...),
+		profileLimiters:     make(map[SecurityProfile]*limiter, len(cfg.ProfileLimits)),
+		backendLimiters:     make(map[BackendKind]*limiter, len(cfg.BackendLimits)),
+		quotas:              cfg.Quotas,
+		tracer:              cfg.Tracer,
+		metrics:             cfg.Metrics,
+		audit:               cfg.Audit,
+	}
+	for profile, backend := range cfg.Backends {
+		r.backends[profile] = r.registerLocked(backend)
 	}
+	for profile, backends := range cfg.Fallbacks {
+		for _, backend := range backends {
+			r.fallbacks[profile] = append(r.fallbacks[profile], r.registerLocked(backend))
+		}
+	}
+	if r.health != nil {
+		for _, backend := range cfg.Backends {
+			r.health.Watch(backend)
+		}
+		for _, backends := range cfg.Fallbacks {
+			for _, backend := range backends {
+				r.health.Watch(backend)
+			}
+		}
+	}
+	for profile, limit := range cfg.ProfileLimits {
+		r.profileLimiters[profile] = newLimiter(limit)
+	}
+	for kind, limit := range cfg.BackendLimits {
+		r.backendLimiters[kind] = newLimiter(limit)
+	}
+	r.execute = chainExecute(append([]Interceptor(nil), cfg.Interceptors...), r.executeRequest)
+	return r
 }
 
 // Execute implements the Runtime interface.
-func (r *DefaultRuntime) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+func (r *DefaultRuntime) Execute(ctx context.Context, req ExecuteRequest) (result ExecuteResult, err error) {
+	// Assign the execution ID used for correlation
+	if req.ExecutionID == "" {
+		req.ExecutionID = ExecutionIDFromContext(ctx)
+	}
+	if req.ExecutionID == "" {
+		req.ExecutionID = NewExecutionID()
+	}
+	ctx = ContextWithExecutionID(ctx, req.ExecutionID)
+
+	// Audit every request, including those rejected before they run
+	start := time.Now()
+	defer func() {
+		r.recordAudit(ctx, start, req, result, err)
+	}()
+
 	// Check context first
 	if ctx.Err() != nil {
 		return ExecuteResult{}, ctx.Err()
 	}
 
-	// Validate request
+	// Refuse new work after Shutdown and track in-flight executions
+	ctx, done, err := r.life.begin(ctx)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	defer done()
+
+	if r.tracer != nil {
+		ctx = ContextWithTracer(ctx, r.tracer)
+	}
+	ctx = context.WithValue(ctx, preflightKey{}, preflightFunc(r.preflight))
+	ctx, span := StartSpan(ctx, "toolruntime.execute",
+		Attr("toolruntime.execution_id", req.ExecutionID),
+		Attr("toolruntime.language", req.Language),
+		Attr("toolruntime.profile", string(req.Profile)))
+	defer span.End()
+
+	execute := r.execute
+	if execute == nil {
+		execute = r.executeRequest
+	}
+	result, err = execute(ctx, req)
+	stampExecutionID(&result, req.ExecutionID)
+
+	span.SetAttributes(
+		Attr("toolruntime.backend", string(result.Backend.Kind)),
+		Attr("toolruntime.duration", result.Duration),
+		Attr("toolruntime.queue_wait", result.QueueWait),
+		Attr("toolruntime.tool_calls", len(result.ToolCalls)))
+	span.RecordError(err)
+	r.recordMetrics(req, result, err)
+	return result, err
+}
+
+// stampExecutionID sets id on the result and on tool call records that do
+// not carry an execution ID yet.
+func stampExecutionID(result *ExecuteResult, id string) {
+	if result.ExecutionID == "" {
+		result.ExecutionID = id
+	}
+	for i := range result.ToolCalls {
+		if result.ToolCalls[i].ExecutionID == "" {
+			result.ToolCalls[i].ExecutionID = id
+		}
+	}
+}
+
+// recordMetrics reports a completed execution to the configured Metrics.
+func (r *DefaultRuntime) recordMetrics(req ExecuteRequest, result ExecuteResult, err error) {
+	if r.metrics == nil {
+		return
+	}
+	profile := req.Profile
+	if profile == "" {
+		profile = r.defaultProfile
+	}
+	r.metrics.RecordExecution(ExecutionMetrics{
+		Profile:   profile,
+		Backend:   result.Backend.Kind,
+		Outcome:   OutcomeOf(err),
+		Duration:  result.Duration,
+		QueueWait: result.QueueWait,
+		ToolCalls: len(result.ToolCalls),
+	})
+	if limit := limitOf(err); limit != "" {
+		r.metrics.RecordLimitViolation(limit)
+	}
+}
+
+// preflightKey is the context key for the preflight checks of the runtime
+// executing a request.
+type preflightKey struct{}
+
+// preflightFunc checks that a request may be answered.
+type preflightFunc func(ctx context.Context, req ExecuteRequest) error
+
+// preflight runs the checks that a request answered by an interceptor
+// without reaching a backend, such as a cache hit, must still pass: request
+// validation, the tenant's quota and the routing policy. Outside a
+// DefaultRuntime it returns nil.
+func preflight(ctx context.Context, req ExecuteRequest) error {
+	check, _ := ctx.Value(preflightKey{}).(preflightFunc)
+	if check == nil {
+		return nil
+	}
+	return check(ctx, req)
+}
+
+// preflight implements preflightFunc for the runtime.
+func (r *DefaultRuntime) preflight(ctx context.Context, req ExecuteRequest) error {
 	if err := req.Validate(); err != nil {
+		return err
+	}
+	profile := req.Profile
+	if profile == "" {
+		profile = r.defaultProfile
+	}
+	if r.quotas != nil {
+		if err := r.quotas.Check(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	r.mu.RLock()
+	candidates := backendsOf(r.candidatesLocked(profile))
+	primary := r.primaryLocked(profile)
+	r.mu.RUnlock()
+	routeReq := req
+	routeReq.Profile = profile
+	candidates, err := r.router.Route(ctx, routeReq, candidates)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("%w: no backend for profile %q", ErrRuntimeUnavailable, profile)
+	}
+	for _, backend := range candidates {
+		if isolationAllowed(profile, primary, backend) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: every backend is weaker than profile %q allows", ErrBackendDenied, profile)
+}
+
+// isolationAllowed reports whether backend may run requests of profile: it
+// is the backend configured for the profile, or it isolates at least as
+// strongly as the profile requires. Routed and fallback backends never
+// weaken isolation.
+func isolationAllowed(profile SecurityProfile, primary, backend Backend) bool {
+	return backend.Kind().Isolation() >= profile.MinIsolation() || sameBackend(backend, primary)
+}
+
+// executeRequest validates req, selects backends and runs it. It is the
+// innermost step of the interceptor chain.
+func (r *DefaultRuntime) executeRequest(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	// Validate request
+	_, span := StartSpan(ctx, "toolruntime.validate")
+	err := req.Validate()
+	span.RecordError(err)
+	span.End()
+	if err != nil {
 		return ExecuteResult{}, err
 	}
 
@@ -105,64 +425,320 @@ func (r *DefaultRuntime) Execute(ctx context.Context, req ExecuteRequest) (Execu
 		profile = r.defaultProfile
 	}
 
-	// Get backend for profile
+	if r.quotas == nil {
+		return r.executeProfile(ctx, req, profile)
+	}
+
+	// Reserve an execution, rejecting tenants that exhausted their quota;
+	// concurrent requests cannot all pass before usage is recorded
+	window, err := r.quotas.reserve(ctx, req)
+	if err != nil {
+		if r.logger != nil {
+			r.logger.Warn("quota exceeded", "executionID", req.ExecutionID, "profile", profile, "error", err)
+		}
+		return ExecuteResult{}, err
+	}
+
+	// Count the tool calls of this execution through its own gateway; the
+	// result may omit them on failure or include calls of other executions
+	// recorded by a shared gateway
+	gateway := &observingGateway{ToolGateway: req.Gateway}
+	req.Gateway = gateway
+	result, err := r.executeProfile(ctx, req, profile)
+
+	// Settle the usage of executions that reached a backend, even if they
+	// failed or ctx was canceled during execution, and release the
+	// reservation of the others
+	attempted, _ := result.Backend.Details["attempted"].([]string)
+	usage := usageOf(req, result)
+	usage.ToolCalls = len(gateway.observed())
+	if qerr := r.quotas.settle(context.WithoutCancel(ctx), req, window, usage, len(attempted) > 0); qerr != nil && r.logger != nil {
+		r.logger.Warn("failed to record quota usage", "executionID", req.ExecutionID, "profile", profile, "error", qerr)
+	}
+	return result, err
+}
+
+// executeProfile runs a validated request on the backends for profile.
+func (r *DefaultRuntime) executeProfile(ctx context.Context, req ExecuteRequest, profile SecurityProfile) (ExecuteResult, error) {
+	// Wait for a profile slot
+	profileLimiter := r.profileLimiters[profile]
+	_, span := StartSpan(ctx, "toolruntime.admission", Attr("toolruntime.profile", string(profile)))
+	queueWait, err := profileLimiter.acquire(ctx)
+	span.SetAttributes(Attr("toolruntime.queue_wait", queueWait))
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		if r.logger != nil {
+			r.logger.Warn("admission denied", "executionID", req.ExecutionID, "profile", profile, "wait", queueWait, "error", err)
+		}
+		return ExecuteResult{QueueWait: queueWait}, err
+	}
+	defer profileLimiter.release()
+
+	// Get backend candidates for profile; they stay open until we are done
 	r.mu.RLock()
-	backend, ok := r.backends[profile]
-	isDenied := r.denyUnsafeProfiles[profile]
+	regs := r.candidatesLocked(profile)
+	r.useBackendsLocked(regs)
+	primary := r.primaryLocked(profile)
 	r.mu.RUnlock()
+	defer r.releaseBackends(regs)
+	candidates := backendsOf(regs)
 
-	if !ok {
-		return ExecuteResult{}, fmt.Errorf("%w: no backend for profile %q", ErrRuntimeUnavailable, profile)
+	// Apply routing policy
+	routeReq := req
+	routeReq.Profile = profile
+	_, span = StartSpan(ctx, "toolruntime.route", Attr("toolruntime.candidates", len(candidates)))
+	candidates, err = r.router.Route(ctx, routeReq, candidates)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		return ExecuteResult{}, err
 	}
 
-	// Check if unsafe backend is denied for this profile
-	if isDenied && backend.Kind() == BackendUnsafeHost {
-		return ExecuteResult{}, fmt.Errorf("%w: unsafe backend denied for profile %q", ErrBackendDenied, profile)
+	if len(candidates) == 0 {
+		return ExecuteResult{}, fmt.Errorf("%w: no backend for profile %q", ErrRuntimeUnavailable, profile)
 	}
 
-	// Log execution start
-	if r.logger != nil {
-		r.logger.Info("executing code", "profile", profile, "backend", backend.Kind())
+	var (
+		result    ExecuteResult
+		attempted []string
+		skipped   error
+	)
+	strict := req.StrictLimits || r.strictLimits
+	for _, backend := range candidates {
+		kind := backend.Kind()
+
+		// Never route or fall back to weaker isolation than the profile
+		// allows; only the backend configured for the profile is trusted
+		if !isolationAllowed(profile, primary, backend) {
+			if skipped == nil {
+				skipped = fmt.Errorf("%w: backend %s is weaker than profile %q allows", ErrBackendDenied, kind, profile)
+			}
+			if r.logger != nil {
+				r.logger.Warn("skipping backend with weaker isolation", "executionID", req.ExecutionID, "profile", profile, "backend", kind,
+					"isolation", kind.Isolation(), "minIsolation", profile.MinIsolation())
+			}
+			continue
+		}
+
+		// In strict mode, skip backends that cannot enforce the requested limits
+		if strict {
+			caps, _ := BackendCapabilities(backend)
+			if missing := caps.UnenforcedLimits(req); len(missing) > 0 {
+				if skipped == nil {
+					skipped = fmt.Errorf("%w: backend %s cannot enforce %s", ErrBackendDenied, kind, strings.Join(missing, ", "))
+				}
+				if r.logger != nil {
+					r.logger.Warn("skipping backend that cannot enforce limits", "executionID", req.ExecutionID, "profile", profile,
+						"backend", kind, "limits", missing)
+				}
+				continue
+			}
+		}
+
+		// Skip backends that report they cannot provide the request's files or artifacts
+		if caps, ok := BackendCapabilities(backend); ok {
+			if missing := caps.UnsupportedFeatures(req); len(missing) > 0 {
+				if skipped == nil {
+					skipped = fmt.Errorf("%w: backend %s does not support %s", ErrBackendDenied, kind, strings.Join(missing, ", "))
+				}
+				if r.logger != nil {
+					r.logger.Warn("skipping backend without required features", "executionID", req.ExecutionID, "profile", profile,
+						"backend", kind, "features", missing)
+				}
+				continue
+			}
+		}
+
+		// Fail fast on backends whose circuit is open
+		if !r.health.allow(backend) {
+			if skipped == nil {
+				skipped = fmt.Errorf("%w: circuit open for backend %s", ErrRuntimeUnavailable, kind)
+			}
+			if r.logger != nil {
+				r.logger.Warn("skipping backend with open circuit", "executionID", req.ExecutionID, "profile", profile, "backend", kind)
+			}
+			continue
+		}
+
+		if len(attempted) > 0 && r.logger != nil {
+			r.logger.Warn("falling back to next backend", "executionID", req.ExecutionID, "profile", profile, "backend", kind, "error", err)
+		}
+		attempted = append(attempted, string(kind))
+
+		// Log execution start
+		if r.logger != nil {
+			r.logger.Info("executing code", "executionID", req.ExecutionID, "profile", profile, "backend", kind)
+		}
+
+		// Delegate to backend
+		result, err = r.runBackendWithRetry(ctx, backend, req, &queueWait)
+		r.health.recordExecution(backend, err)
+		if err == nil || !errors.Is(err, ErrRuntimeUnavailable) || ctx.Err() != nil {
+			break
+		}
 	}
 
-	// Delegate to backend
-	result, err := backend.Execute(ctx, req)
-	if err != nil {
+	// Every candidate was skipped
+	if len(attempted) == 0 {
+		if skipped == nil {
+			skipped = fmt.Errorf("%w: no eligible backend for profile %q", ErrRuntimeUnavailable, profile)
+		}
 		if r.logger != nil {
-			r.logger.Error("execution failed", "profile", profile, "error", err)
+			r.logger.Error("execution failed", "executionID", req.ExecutionID, "profile", profile, "error", skipped)
 		}
-		return result, err
+		return ExecuteResult{QueueWait: queueWait}, skipped
 	}
 
-	// If the backend did not populate tool calls but the gateway can, capture them.
+	result.QueueWait = queueWait
+	recordAttempted(&result, attempted)
+
+	// If the backend did not populate tool calls but the gateway can, capture
+	// them, also for failed executions so they are audited
 	if len(result.ToolCalls) == 0 {
 		if recorder, ok := req.Gateway.(toolCallRecorder); ok {
 			result.ToolCalls = recorder.GetToolCalls()
 		}
 	}
 
+	if err != nil {
+		if r.logger != nil {
+			r.logger.Error("execution failed", "executionID", req.ExecutionID, "profile", profile, "error", err)
+		}
+		return result, err
+	}
+
 	if r.logger != nil {
-		r.logger.Info("execution completed", "profile", profile, "duration", result.Duration)
+		r.logger.Info("execution completed", "executionID", req.ExecutionID, "profile", profile, "duration", result.Duration)
 	}
 
 	return result, nil
 }
 
+// runBackend waits for a slot on the backend's limiter, adding the wait to
+// queueWait, and then invokes the backend through the backend interceptors.
+func (r *DefaultRuntime) runBackend(ctx context.Context, backend Backend, req ExecuteRequest, queueWait *time.Duration) (ExecuteResult, error) {
+	ctx, span := StartSpan(ctx, "toolruntime.backend", Attr("toolruntime.backend", string(backend.Kind())))
+	defer span.End()
+
+	backendLimiter := r.backendLimiters[backend.Kind()]
+	wait, err := backendLimiter.acquire(ctx)
+	*queueWait += wait
+	span.SetAttributes(Attr("toolruntime.queue_wait", wait))
+	if err != nil {
+		if r.logger != nil {
+			r.logger.Warn("admission denied", "executionID", req.ExecutionID, "backend", backend.Kind(), "wait", wait, "error", err)
+		}
+		span.RecordError(err)
+		return ExecuteResult{}, err
+	}
+	defer backendLimiter.release()
+
+	invoke := func(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+		return invokeBackend(ctx, backend, req)
+	}
+	result, err := chainBackend(r.backendInterceptors, backend, invoke)(ctx, req)
+	span.RecordError(err)
+	return result, err
+}
+
+// candidatesLocked returns the registrations of the primary backend followed
+// by the fallbacks for profile. The caller must hold r.mu.
+func (r *DefaultRuntime) candidatesLocked(profile SecurityProfile) []*registration {
+	var candidates []*registration
+	if reg := r.backends[profile]; reg != nil {
+		candidates = append(candidates, reg)
+	}
+	for _, reg := range r.fallbacks[profile] {
+		if reg != nil {
+			candidates = append(candidates, reg)
+		}
+	}
+	return candidates
+}
+
+// primaryLocked returns the backend configured for profile, or nil. The
+// caller must hold r.mu.
+func (r *DefaultRuntime) primaryLocked(profile SecurityProfile) Backend {
+	if reg := r.backends[profile]; reg != nil {
+		return reg.backend
+	}
+	return nil
+}
+
+// recordAttempted stores the kinds of the attempted backends in the result's
+// BackendInfo.Details under the "attempted" key.
+func recordAttempted(result *ExecuteResult, attempted []string) {
+	details := make(map[string]any, len(result.Backend.Details)+1)
+	for k, v := range result.Backend.Details {
+		details[k] = v
+	}
+	details["attempted"] = attempted
+	result.Backend.Details = details
+}
+
 // RegisterBackend registers a backend for a security profile.
-// This is thread-safe and can be called at runtime.
+// This is thread-safe and can be called at runtime. A backend it replaces is
+// retired like one removed by UnregisterBackend.
 func (r *DefaultRuntime) RegisterBackend(profile SecurityProfile, backend Backend) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 	if r.backends == nil {
-		r.backends = make(map[SecurityProfile]Backend)
+		r.backends = make(map[SecurityProfile]*registration)
 	}
-	r.backends[profile] = backend
+	old := r.backends[profile]
+	reg := r.registerLocked(backend)
+	r.backends[profile] = reg
+	r.life.unretire(reg)
+	retired := r.retireLocked(old)
+	if r.health != nil {
+		r.health.Watch(backend)
+	}
+	r.mu.Unlock()
+
+	r.closeRetired(retired...)
 }
 
 // UnregisterBackend removes a backend for a security profile.
-// This is thread-safe and can be called at runtime.
+// This is thread-safe and can be called at runtime. If the removed backend
+// implements ClosableBackend and is no longer registered as a primary or
+// fallback backend, it is closed once in-flight executions using it finish.
 func (r *DefaultRuntime) UnregisterBackend(profile SecurityProfile) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	old := r.backends[profile]
 	delete(r.backends, profile)
+	retired := r.retireLocked(old)
+	r.mu.Unlock()
+
+	r.closeRetired(retired...)
+}
+
+// SetFallbacks replaces the ordered fallback backends for a security profile.
+// This is thread-safe and can be called at runtime. Fallbacks it replaces are
+// retired like backends removed by UnregisterBackend.
+func (r *DefaultRuntime) SetFallbacks(profile SecurityProfile, backends ...Backend) {
+	r.mu.Lock()
+	if r.fallbacks == nil {
+		r.fallbacks = make(map[SecurityProfile][]*registration)
+	}
+	old := r.fallbacks[profile]
+	var regs []*registration
+	for _, backend := range backends {
+		reg := r.registerLocked(backend)
+		regs = append(regs, reg)
+		r.life.unretire(reg)
+	}
+	if len(regs) == 0 {
+		delete(r.fallbacks, profile)
+	} else {
+		r.fallbacks[profile] = regs
+	}
+	retired := r.retireLocked(old...)
+	if r.health != nil {
+		for _, backend := range backends {
+			r.health.Watch(backend)
+		}
+	}
+	r.mu.Unlock()
+
+	r.closeRetired(retired...)
 }
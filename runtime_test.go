@@ -3,6 +3,8 @@ package toolruntime
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 )
@@ -266,3 +268,141 @@ func TestDefaultRuntimeContract(t *testing.T) {
 		},
 	})
 }
+
+func TestDefaultRuntimeFallback(t *testing.T) {
+	unavailable := &mockBackend{
+		kind:       BackendGVisor,
+		executeErr: fmt.Errorf("%w: runsc missing", ErrRuntimeUnavailable),
+	}
+	docker := &mockBackend{
+		kind:   BackendDocker,
+		result: ExecuteResult{Value: "docker"},
+	}
+
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: unavailable,
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileStandard: {docker},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "docker" {
+		t.Errorf("Execute().Value = %v, want %v", result.Value, "docker")
+	}
+
+	attempted, _ := result.Backend.Details["attempted"].([]string)
+	want := []string{string(BackendGVisor), string(BackendDocker)}
+	if !reflect.DeepEqual(attempted, want) {
+		t.Errorf("Details[attempted] = %v, want %v", attempted, want)
+	}
+}
+
+func TestDefaultRuntimeFallbackOnlyOnUnavailable(t *testing.T) {
+	execErr := errors.New("user code failed")
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: &mockBackend{kind: BackendGVisor, executeErr: execErr},
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileStandard: {&mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker"}}},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if !errors.Is(err, execErr) {
+		t.Errorf("Execute() error = %v, want %v", err, execErr)
+	}
+}
+
+func TestDefaultRuntimeFallbackRespectsIsolation(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileHardened: &mockBackend{kind: BackendKata, executeErr: ErrRuntimeUnavailable},
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileHardened: {
+				&mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker"}},
+				&mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "unsafe"}},
+			},
+		},
+		DefaultProfile: ProfileHardened,
+	})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrRuntimeUnavailable)
+	}
+	if result.Value != nil {
+		t.Errorf("Execute().Value = %v, want nil (weaker fallbacks must be skipped)", result.Value)
+	}
+}
+
+func TestDefaultRuntimeRouterRespectsIsolation(t *testing.T) {
+	unsafe := &countingBackend{mockBackend: mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "unsafe"}}}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileHardened: &mockBackend{kind: BackendGVisor}},
+		Router:         RouteTo(MatchLanguage("go"), unsafe),
+		DefaultProfile: ProfileHardened,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Language: "go", Code: "test", Gateway: &mockToolGateway{}})
+	if !errors.Is(err, ErrBackendDenied) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrBackendDenied)
+	}
+	if unsafe.calls != 0 {
+		t.Errorf("routed unsafe backend ran %d times, want 0", unsafe.calls)
+	}
+}
+
+func TestDefaultRuntimeFallbackSkipsDeniedUnsafe(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: &mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "unsafe"}},
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileStandard: {&mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker"}}},
+		},
+		DenyUnsafeProfiles: []SecurityProfile{ProfileStandard},
+		DefaultProfile:     ProfileStandard,
+	})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "docker" {
+		t.Errorf("Execute().Value = %v, want %v", result.Value, "docker")
+	}
+}
+
+func TestDefaultRuntimeSetFallbacks(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: &mockBackend{kind: BackendGVisor, executeErr: ErrRuntimeUnavailable},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+	rt.SetFallbacks(ProfileStandard, &mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker"}})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "docker" {
+		t.Errorf("Execute().Value = %v, want %v", result.Value, "docker")
+	}
+
+	rt.SetFallbacks(ProfileStandard)
+	_, err = rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("Execute() after clearing fallbacks error = %v, want %v", err, ErrRuntimeUnavailable)
+	}
+}
@@ -0,0 +1,369 @@
+package toolruntime
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolrun"
+)
+
+// StreamEventType identifies the type of streaming event.
+type StreamEventType string
+
+const (
+	// StreamEventStdout indicates stdout data.
+	StreamEventStdout StreamEventType = "stdout"
+
+	// StreamEventStderr indicates stderr data.
+	StreamEventStderr StreamEventType = "stderr"
+
+	// StreamEventToolCall indicates a completed tool invocation.
+	StreamEventToolCall StreamEventType = "tool_call"
+
+	// StreamEventExit indicates that execution completed.
+	StreamEventExit StreamEventType = "exit"
+
+	// StreamEventError indicates that execution failed.
+	StreamEventError StreamEventType = "error"
+
+	// StreamEventRetry indicates that a failed attempt is retried, on the
+	// same or a fallback backend. The events before it belong to the failed
+	// attempt; consumers should discard its output.
+	StreamEventRetry StreamEventType = "retry"
+)
+
+// StreamEvent represents an incremental event emitted while code is running.
+type StreamEvent struct {
+	// Type identifies the event type.
+	Type StreamEventType
+
+	// Data contains the event payload for stdout/stderr events.
+	Data []byte
+
+	// ToolCall is set when Type is StreamEventToolCall.
+	ToolCall *ToolCallRecord
+
+	// Result is set when Type is StreamEventExit, and may be set when Type is
+	// StreamEventError if a partial result is available.
+	Result *ExecuteResult
+
+	// Error is set when Type is StreamEventError.
+	Error error
+}
+
+// StreamingRuntime is implemented by runtimes that can emit execution events
+// while code is running.
+//
+// Contract:
+// - Streaming: the returned channel is non-nil when err == nil and is closed when
+//   execution completes. The final event is StreamEventExit or StreamEventError.
+// - Context: canceling ctx stops execution; pending events may be dropped.
+type StreamingRuntime interface {
+	Runtime
+
+	// ExecuteStream runs code and streams stdout, stderr, tool call and exit events.
+	ExecuteStream(ctx context.Context, req ExecuteRequest) (<-chan StreamEvent, error)
+}
+
+// StreamingBackend is an optional extension to Backend for backends that can
+// emit output while code is running. Backends that do not implement it are
+// buffered by the runtime and their output is emitted after completion.
+//
+// Contract:
+// - Streaming: the returned channel is non-nil when err == nil and is closed when
+//   execution completes. The final event is StreamEventExit (with Result set)
+//   or StreamEventError.
+// - Errors: validation errors are returned synchronously.
+type StreamingBackend interface {
+	Backend
+
+	// ExecuteStream runs code and streams events as they occur.
+	ExecuteStream(ctx context.Context, req ExecuteRequest) (<-chan StreamEvent, error)
+}
+
+// StreamResult converts a completed execution into a closed event stream.
+// Non-empty stdout/stderr are emitted first, followed by an exit event, or an
+// error event when err is non-nil. It is useful for backends that need to
+// satisfy StreamingBackend when their underlying client cannot stream.
+func StreamResult(result ExecuteResult, err error) <-chan StreamEvent {
+	events := bufferedEvents(result, err)
+	ch := make(chan StreamEvent, len(events))
+	for _, ev := range events {
+		ch <- ev
+	}
+	close(ch)
+	return ch
+}
+
+// StreamWriter returns an io.Writer that sends every write to out as an
+// event of type typ, such as StreamEventStdout. Writes never fail; once ctx
+// is done, further output is dropped.
+func StreamWriter(ctx context.Context, out chan<- StreamEvent, typ StreamEventType) io.Writer {
+	return &streamWriter{ctx: ctx, out: out, typ: typ}
+}
+
+// streamWriter is the io.Writer returned by StreamWriter.
+type streamWriter struct {
+	ctx context.Context
+	out chan<- StreamEvent
+	typ StreamEventType
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+	select {
+	case w.out <- StreamEvent{Type: w.typ, Data: data}:
+	case <-w.ctx.Done():
+	}
+	return len(p), nil
+}
+
+// bufferedEvents returns the events describing a completed execution.
+func bufferedEvents(result ExecuteResult, err error) []StreamEvent {
+	var events []StreamEvent
+	if result.Stdout != "" {
+		events = append(events, StreamEvent{Type: StreamEventStdout, Data: []byte(result.Stdout)})
+	}
+	if result.Stderr != "" {
+		events = append(events, StreamEvent{Type: StreamEventStderr, Data: []byte(result.Stderr)})
+	}
+	return append(events, finalEvent(result, err))
+}
+
+// finalEvent returns the terminal exit or error event for an execution.
+func finalEvent(result ExecuteResult, err error) StreamEvent {
+	if err != nil {
+		return StreamEvent{Type: StreamEventError, Result: &result, Error: err}
+	}
+	return StreamEvent{Type: StreamEventExit, Result: &result}
+}
+
+// streamSinkKey is the context key for the active stream emitter.
+type streamSinkKey struct{}
+
+// streamEmitter delivers events to a stream consumer.
+// It is safe for concurrent use; events emitted after close are dropped.
+type streamEmitter struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	closed   bool
+	attempts int
+	sending  sync.WaitGroup
+	done     chan struct{}
+	ch       chan StreamEvent
+}
+
+func newStreamEmitter(ctx context.Context) *streamEmitter {
+	return &streamEmitter{
+		ctx:  ctx,
+		done: make(chan struct{}),
+		ch:   make(chan StreamEvent, 16),
+	}
+}
+
+// emit sends an event, giving up if the stream context is canceled or the
+// stream is closed. The lock is not held while sending, so a slow consumer
+// does not block other emitters.
+func (e *streamEmitter) emit(ev StreamEvent) {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.sending.Add(1)
+	e.mu.Unlock()
+	defer e.sending.Done()
+
+	select {
+	case e.ch <- ev:
+	case <-e.ctx.Done():
+	case <-e.done:
+	}
+}
+
+// startAttempt marks the start of a backend attempt and emits a retry event
+// for every attempt after the first.
+func (e *streamEmitter) startAttempt() {
+	e.mu.Lock()
+	e.attempts++
+	retry := e.attempts > 1
+	e.mu.Unlock()
+	if retry {
+		e.emit(StreamEvent{Type: StreamEventRetry})
+	}
+}
+
+// close drops pending events and closes the underlying channel once no
+// emit is sending.
+func (e *streamEmitter) close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	close(e.done)
+	e.mu.Unlock()
+
+	e.sending.Wait()
+	close(e.ch)
+}
+
+func withStreamEmitter(ctx context.Context, e *streamEmitter) context.Context {
+	return context.WithValue(ctx, streamSinkKey{}, e)
+}
+
+func streamEmitterFrom(ctx context.Context) *streamEmitter {
+	e, _ := ctx.Value(streamSinkKey{}).(*streamEmitter)
+	return e
+}
+
+// ExecuteStream implements StreamingRuntime.
+// Request validation errors are returned synchronously; all other failures are
+// delivered as a StreamEventError.
+func (r *DefaultRuntime) ExecuteStream(ctx context.Context, req ExecuteRequest) (<-chan StreamEvent, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if r.life.isClosed() {
+		return nil, errRuntimeShutDown
+	}
+
+	emitter := newStreamEmitter(ctx)
+	go func() {
+		defer emitter.close()
+		result, err := r.Execute(withStreamEmitter(ctx, emitter), req)
+		emitter.emit(finalEvent(result, err))
+	}()
+
+	return emitter.ch, nil
+}
+
+// invokeBackend executes req on backend. When the context carries a stream
+// emitter, output is forwarded as it is produced: streaming backends are
+// consumed incrementally and other backends are buffered. Every attempt
+// after the first is preceded by a StreamEventRetry. Secret values of req
+// are redacted from output, values and errors before they leave it, and from
+// the spans the backend records.
+func invokeBackend(ctx context.Context, backend Backend, req ExecuteRequest) (ExecuteResult, error) {
+	redactor := NewRedactor(req.Secrets)
+	if tracer := TracerFromContext(ctx); redactor != nil && tracer != nil {
+		ctx = ContextWithTracer(ctx, redactor.Tracer(tracer))
+	}
+	emitter := streamEmitterFrom(ctx)
+	if emitter == nil {
+		result, err := backend.Execute(ctx, req)
+		return redactor.Result(result), redactor.Error(err)
+	}
+
+	emitter.startAttempt()
+	req.Gateway = &streamingGateway{ToolGateway: req.Gateway, emitter: emitter}
+
+	sb, ok := backend.(StreamingBackend)
+	if !ok {
+		result, err := backend.Execute(ctx, req)
+		result, err = redactor.Result(result), redactor.Error(err)
+		if err == nil {
+			for _, ev := range bufferedEvents(result, nil) {
+				if ev.Type != StreamEventExit {
+					emitter.emit(ev)
+				}
+			}
+		}
+		return result, err
+	}
+
+	events, err := sb.ExecuteStream(ctx, req)
+	if err != nil {
+		return ExecuteResult{}, redactor.Error(err)
+	}
+
+	var (
+		result ExecuteResult
+		runErr error
+		stdout = streamRedactor{r: redactor}
+		stderr = streamRedactor{r: redactor}
+	)
+	for ev := range events {
+		switch ev.Type {
+		case StreamEventStdout:
+			emitData(emitter, StreamEventStdout, stdout.write(ev.Data))
+		case StreamEventStderr:
+			emitData(emitter, StreamEventStderr, stderr.write(ev.Data))
+		case StreamEventExit:
+			if ev.Result != nil {
+				result = *ev.Result
+			}
+		case StreamEventError:
+			if ev.Result != nil {
+				result = *ev.Result
+			}
+			runErr = ev.Error
+		default:
+			emitter.emit(ev)
+		}
+	}
+	emitData(emitter, StreamEventStdout, stdout.flush())
+	emitData(emitter, StreamEventStderr, stderr.flush())
+	return redactor.Result(result), redactor.Error(runErr)
+}
+
+// emitData emits a stdout or stderr event, unless data is empty.
+func emitData(emitter *streamEmitter, typ StreamEventType, data []byte) {
+	if len(data) > 0 {
+		emitter.emit(StreamEvent{Type: typ, Data: data})
+	}
+}
+
+// streamingGateway wraps a ToolGateway and emits a StreamEventToolCall for
+// every RunTool/RunChain invocation.
+type streamingGateway struct {
+	ToolGateway
+	emitter *streamEmitter
+}
+
+// RunTool delegates to the wrapped gateway and emits a tool call event.
+func (g *streamingGateway) RunTool(ctx context.Context, id string, args map[string]any) (toolrun.RunResult, error) {
+	start := time.Now()
+	result, err := g.ToolGateway.RunTool(ctx, id, args)
+
+	record := ToolCallRecord{ToolID: id, ExecutionID: ExecutionIDFromContext(ctx), Duration: time.Since(start)}
+	if err != nil {
+		record.ErrorOp = "run"
+	}
+	if result.Backend.Kind != "" {
+		record.BackendKind = string(result.Backend.Kind)
+	}
+	g.emitter.emit(StreamEvent{Type: StreamEventToolCall, ToolCall: &record})
+
+	return result, err
+}
+
+// RunChain delegates to the wrapped gateway and emits a tool call event per step.
+func (g *streamingGateway) RunChain(ctx context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	start := time.Now()
+	result, stepResults, err := g.ToolGateway.RunChain(ctx, steps)
+	if len(stepResults) == 0 {
+		return result, stepResults, err
+	}
+
+	stepDuration := time.Since(start) / time.Duration(len(stepResults))
+	for _, sr := range stepResults {
+		record := ToolCallRecord{ToolID: sr.ToolID, ExecutionID: ExecutionIDFromContext(ctx), Duration: stepDuration}
+		if sr.Err != nil {
+			record.ErrorOp = "chain"
+		}
+		if sr.Backend.Kind != "" {
+			record.BackendKind = string(sr.Backend.Kind)
+		}
+		g.emitter.emit(StreamEvent{Type: StreamEventToolCall, ToolCall: &record})
+	}
+
+	return result, stepResults, err
+}
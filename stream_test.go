@@ -0,0 +1,285 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jonwraymond/toolrun"
+)
+
+// streamBackend is a StreamingBackend that replays a fixed set of events
+type streamBackend struct {
+	mockBackend
+	events []StreamEvent
+}
+
+func (s *streamBackend) ExecuteStream(ctx context.Context, req ExecuteRequest) (<-chan StreamEvent, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ch := make(chan StreamEvent, len(s.events))
+	for _, ev := range s.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+// toolCallingBackend invokes a tool through the gateway before returning
+type toolCallingBackend struct {
+	mockBackend
+	toolID string
+}
+
+func (b *toolCallingBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	if _, err := req.Gateway.RunTool(ctx, b.toolID, nil); err != nil {
+		return ExecuteResult{}, err
+	}
+	return b.mockBackend.Execute(ctx, req)
+}
+
+func collectEvents(t *testing.T, ch <-chan StreamEvent) []StreamEvent {
+	t.Helper()
+	var events []StreamEvent
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestDefaultRuntimeImplementsStreamingRuntime(t *testing.T) {
+	t.Helper()
+	var _ StreamingRuntime = (*DefaultRuntime)(nil)
+}
+
+func TestExecuteStreamBuffersNonStreamingBackend(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &mockBackend{
+				kind:   BackendUnsafeHost,
+				result: ExecuteResult{Value: "ok", Stdout: "out", Stderr: "err"},
+			},
+		},
+		DefaultProfile: ProfileDev,
+	})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	wantTypes := []StreamEventType{StreamEventStdout, StreamEventStderr, StreamEventExit}
+	if len(events) != len(wantTypes) {
+		t.Fatalf("got %d events, want %d", len(events), len(wantTypes))
+	}
+	for i, want := range wantTypes {
+		if events[i].Type != want {
+			t.Errorf("events[%d].Type = %v, want %v", i, events[i].Type, want)
+		}
+	}
+	if string(events[0].Data) != "out" {
+		t.Errorf("stdout Data = %q, want %q", events[0].Data, "out")
+	}
+	if events[2].Result == nil || events[2].Result.Value != "ok" {
+		t.Errorf("exit Result = %+v, want Value %q", events[2].Result, "ok")
+	}
+}
+
+func TestExecuteStreamForwardsStreamingBackend(t *testing.T) {
+	final := ExecuteResult{Value: "done", Stdout: "a\nb\n"}
+	backend := &streamBackend{
+		mockBackend: mockBackend{kind: BackendDocker},
+		events: []StreamEvent{
+			{Type: StreamEventStdout, Data: []byte("a\n")},
+			{Type: StreamEventStdout, Data: []byte("b\n")},
+			{Type: StreamEventExit, Result: &final},
+		},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile: ProfileStandard,
+	})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if string(events[1].Data) != "b\n" {
+		t.Errorf("events[1].Data = %q, want %q", events[1].Data, "b\n")
+	}
+	last := events[len(events)-1]
+	if last.Type != StreamEventExit || last.Result.Value != "done" {
+		t.Errorf("last event = %+v, want exit with Value %q", last, "done")
+	}
+}
+
+func TestExecuteStreamBackendError(t *testing.T) {
+	backendErr := errors.New("boom")
+	backend := &streamBackend{
+		mockBackend: mockBackend{kind: BackendDocker},
+		events: []StreamEvent{
+			{Type: StreamEventStderr, Data: []byte("partial")},
+			{Type: StreamEventError, Error: backendErr},
+		},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile: ProfileStandard,
+	})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	last := events[len(events)-1]
+	if last.Type != StreamEventError {
+		t.Fatalf("last event Type = %v, want %v", last.Type, StreamEventError)
+	}
+	if !errors.Is(last.Error, backendErr) {
+		t.Errorf("last event Error = %v, want %v", last.Error, backendErr)
+	}
+}
+
+func TestExecuteStreamFallbackEmitsRetry(t *testing.T) {
+	unavailable := NewRuntimeError(BackendDocker, "run", ErrRuntimeUnavailable, false)
+	primary := &streamBackend{
+		mockBackend: mockBackend{kind: BackendDocker},
+		events: []StreamEvent{
+			{Type: StreamEventStdout, Data: []byte("partial")},
+			{Type: StreamEventError, Error: unavailable},
+		},
+	}
+	final := ExecuteResult{Stdout: "ok"}
+	fallback := &streamBackend{
+		mockBackend: mockBackend{kind: BackendGVisor},
+		events: []StreamEvent{
+			{Type: StreamEventStdout, Data: []byte("ok")},
+			{Type: StreamEventExit, Result: &final},
+		},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+		DefaultProfile: ProfileStandard,
+	})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	// The output of the failed attempt is followed by a retry event
+	var got []StreamEventType
+	for _, ev := range collectEvents(t, ch) {
+		got = append(got, ev.Type)
+	}
+	want := []StreamEventType{StreamEventStdout, StreamEventRetry, StreamEventStdout, StreamEventExit}
+	if !slices.Equal(got, want) {
+		t.Errorf("event types = %v, want %v", got, want)
+	}
+}
+
+func TestExecuteStreamEmitsToolCalls(t *testing.T) {
+	backend := &toolCallingBackend{
+		mockBackend: mockBackend{kind: BackendUnsafeHost},
+		toolID:      "ns:tool",
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+		DefaultProfile: ProfileDev,
+	})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	if events[0].Type != StreamEventToolCall {
+		t.Fatalf("events[0].Type = %v, want %v", events[0].Type, StreamEventToolCall)
+	}
+	if events[0].ToolCall.ToolID != "ns:tool" {
+		t.Errorf("ToolCall.ToolID = %q, want %q", events[0].ToolCall.ToolID, "ns:tool")
+	}
+}
+
+func TestExecuteStreamValidatesRequest(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{})
+
+	_, err := rt.ExecuteStream(context.Background(), ExecuteRequest{Code: "test"})
+	if !errors.Is(err, ErrMissingGateway) {
+		t.Errorf("ExecuteStream() error = %v, want %v", err, ErrMissingGateway)
+	}
+}
+
+func TestExecuteStreamMissingBackend(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{})
+
+	ch, err := rt.ExecuteStream(context.Background(), ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockToolGateway{},
+		Profile: ProfileHardened,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	if len(events) != 1 || !errors.Is(events[0].Error, ErrRuntimeUnavailable) {
+		t.Errorf("events = %+v, want single error event wrapping %v", events, ErrRuntimeUnavailable)
+	}
+}
+
+func TestStreamResult(t *testing.T) {
+	events := collectEvents(t, StreamResult(ExecuteResult{Stdout: "hi"}, nil))
+	if len(events) != 2 || events[0].Type != StreamEventStdout || events[1].Type != StreamEventExit {
+		t.Errorf("StreamResult() events = %+v, want stdout then exit", events)
+	}
+
+	events = collectEvents(t, StreamResult(ExecuteResult{}, ErrTimeout))
+	if len(events) != 1 || !errors.Is(events[0].Error, ErrTimeout) {
+		t.Errorf("StreamResult() events = %+v, want single error event", events)
+	}
+}
+
+func TestStreamingGatewayRunChain(t *testing.T) {
+	emitter := newStreamEmitter(context.Background())
+	gw := &streamingGateway{ToolGateway: &chainGateway{}, emitter: emitter}
+
+	_, _, err := gw.RunChain(context.Background(), []toolrun.ChainStep{{ToolID: "a"}, {ToolID: "b"}})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	emitter.close()
+
+	events := collectEvents(t, emitter.ch)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[1].ToolCall.ToolID != "b" {
+		t.Errorf("events[1].ToolCall.ToolID = %q, want %q", events[1].ToolCall.ToolID, "b")
+	}
+}
+
+// chainGateway echoes chain steps back as step results
+type chainGateway struct {
+	mockToolGateway
+}
+
+func (g *chainGateway) RunChain(_ context.Context, steps []toolrun.ChainStep) (toolrun.RunResult, []toolrun.StepResult, error) {
+	results := make([]toolrun.StepResult, len(steps))
+	for i, s := range steps {
+		results[i] = toolrun.StepResult{ToolID: s.ToolID}
+	}
+	return toolrun.RunResult{}, results, nil
+}
@@ -0,0 +1,79 @@
+package toolruntime
+
+import "context"
+
+// Attribute is a key/value pair attached to a span or span event.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Attr creates an Attribute.
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer creates spans describing the phases of an execution.
+// The runtime, backends and gateways start spans through StartSpan, which
+// uses the Tracer carried in the context.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: the returned context carries the new span so that spans started
+//   from it become its children.
+// - Errors: tracing must be best-effort and must not panic.
+type Tracer interface {
+	// Start begins a span named name as a child of the span in ctx, if any.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a single timed operation.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Lifecycle: End must be called exactly once; calls after End are ignored.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs ...Attribute)
+
+	// AddEvent records a point-in-time event on the span.
+	AddEvent(name string, attrs ...Attribute)
+
+	// RecordError marks the span as failed. A nil err is ignored.
+	RecordError(err error)
+
+	// End completes the span.
+	End()
+}
+
+// tracerKey is the context key for the active Tracer.
+type tracerKey struct{}
+
+// ContextWithTracer returns a context that carries tracer.
+func ContextWithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// TracerFromContext returns the Tracer carried by ctx, or nil.
+func TracerFromContext(ctx context.Context) Tracer {
+	t, _ := ctx.Value(tracerKey{}).(Tracer)
+	return t
+}
+
+// StartSpan starts a span using the Tracer carried by ctx. When ctx carries
+// no Tracer, it returns ctx unchanged and a no-op Span.
+func StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t := TracerFromContext(ctx)
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	return t.Start(ctx, name, attrs...)
+}
+
+// noopSpan is the Span returned when tracing is disabled.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute)     {}
+func (noopSpan) AddEvent(string, ...Attribute) {}
+func (noopSpan) RecordError(error)             {}
+func (noopSpan) End()                          {}
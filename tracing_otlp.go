@@ -0,0 +1,282 @@
+package toolruntime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLP status codes.
+const (
+	otlpStatusUnset = 0
+	otlpStatusError = 2
+)
+
+// OTLPJSONTracer is a Tracer that writes each completed span to w as one line
+// of OTLP/JSON (an ExportTraceServiceRequest), the format read by the
+// OpenTelemetry Collector's otlpjsonfile receiver. It is intended for local
+// testing and debugging.
+type OTLPJSONTracer struct {
+	serviceName string
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewOTLPJSONTracer creates a tracer that writes spans to w.
+// serviceName is reported as the service.name resource attribute.
+func NewOTLPJSONTracer(w io.Writer, serviceName string) *OTLPJSONTracer {
+	if serviceName == "" {
+		serviceName = "toolruntime"
+	}
+	return &OTLPJSONTracer{
+		serviceName: serviceName,
+		enc:         json.NewEncoder(w),
+	}
+}
+
+// Err returns the first error encountered while writing spans, if any.
+func (t *OTLPJSONTracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// otlpSpanKey is the context key for the active OTLP span.
+type otlpSpanKey struct{}
+
+// Start implements Tracer.
+func (t *OTLPJSONTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &otlpSpan{
+		tracer: t,
+		name:   name,
+		spanID: randomHex(8),
+		start:  time.Now(),
+		attrs:  append([]Attribute(nil), attrs...),
+	}
+	if parent, ok := ctx.Value(otlpSpanKey{}).(*otlpSpan); ok {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, otlpSpanKey{}, s), s
+}
+
+// export writes a completed span.
+func (t *OTLPJSONTracer) export(s otlpJSONSpan) {
+	doc := otlpJSONRequest{
+		ResourceSpans: []otlpJSONResourceSpans{{
+			Resource: otlpJSONResource{
+				Attributes: []otlpJSONKeyValue{otlpKeyValue(Attr("service.name", t.serviceName))},
+			},
+			ScopeSpans: []otlpJSONScopeSpans{{
+				Scope: otlpJSONScope{Name: "github.com/jonwraymond/toolruntime"},
+				Spans: []otlpJSONSpan{s},
+			}},
+		}},
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.enc.Encode(doc); err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// otlpSpan is the Span implementation of OTLPJSONTracer.
+type otlpSpan struct {
+	tracer   *OTLPJSONTracer
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+
+	mu     sync.Mutex
+	ended  bool
+	attrs  []Attribute
+	events []otlpJSONEvent
+	err    error
+}
+
+func (s *otlpSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.attrs = append(s.attrs, attrs...)
+	}
+}
+
+func (s *otlpSpan) AddEvent(name string, attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.events = append(s.events, otlpJSONEvent{
+			TimeUnixNano: unixNano(time.Now()),
+			Name:         name,
+			Attributes:   otlpKeyValues(attrs),
+		})
+	}
+}
+
+func (s *otlpSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.err = err
+		s.events = append(s.events, otlpJSONEvent{
+			TimeUnixNano: unixNano(time.Now()),
+			Name:         "exception",
+			Attributes:   otlpKeyValues([]Attribute{Attr("exception.message", err.Error())}),
+		})
+	}
+}
+
+func (s *otlpSpan) End() {
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	out := otlpJSONSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              1, // SPAN_KIND_INTERNAL
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        otlpKeyValues(s.attrs),
+		Events:            s.events,
+		Status:            otlpJSONStatus{Code: otlpStatusUnset},
+	}
+	if s.err != nil {
+		out.Status = otlpJSONStatus{Code: otlpStatusError, Message: s.err.Error()}
+	}
+	s.mu.Unlock()
+
+	s.tracer.export(out)
+}
+
+// OTLP/JSON wire types. Field names follow the protobuf JSON mapping:
+// 64-bit integers are strings and IDs are lowercase hex.
+type (
+	otlpJSONRequest struct {
+		ResourceSpans []otlpJSONResourceSpans `json:"resourceSpans"`
+	}
+	otlpJSONResourceSpans struct {
+		Resource   otlpJSONResource     `json:"resource"`
+		ScopeSpans []otlpJSONScopeSpans `json:"scopeSpans"`
+	}
+	otlpJSONResource struct {
+		Attributes []otlpJSONKeyValue `json:"attributes"`
+	}
+	otlpJSONScopeSpans struct {
+		Scope otlpJSONScope  `json:"scope"`
+		Spans []otlpJSONSpan `json:"spans"`
+	}
+	otlpJSONScope struct {
+		Name string `json:"name"`
+	}
+	otlpJSONSpan struct {
+		TraceID           string             `json:"traceId"`
+		SpanID            string             `json:"spanId"`
+		ParentSpanID      string             `json:"parentSpanId,omitempty"`
+		Name              string             `json:"name"`
+		Kind              int                `json:"kind"`
+		StartTimeUnixNano string             `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string             `json:"endTimeUnixNano"`
+		Attributes        []otlpJSONKeyValue `json:"attributes,omitempty"`
+		Events            []otlpJSONEvent    `json:"events,omitempty"`
+		Status            otlpJSONStatus     `json:"status"`
+	}
+	otlpJSONEvent struct {
+		TimeUnixNano string             `json:"timeUnixNano"`
+		Name         string             `json:"name"`
+		Attributes   []otlpJSONKeyValue `json:"attributes,omitempty"`
+	}
+	otlpJSONStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpJSONKeyValue struct {
+		Key   string        `json:"key"`
+		Value otlpJSONValue `json:"value"`
+	}
+	otlpJSONValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+func otlpKeyValues(attrs []Attribute) []otlpJSONKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpJSONKeyValue, len(attrs))
+	for i, a := range attrs {
+		out[i] = otlpKeyValue(a)
+	}
+	return out
+}
+
+// otlpKeyValue converts an attribute to its OTLP/JSON form. Durations are
+// reported as integer nanoseconds; unsupported types are formatted as strings.
+func otlpKeyValue(a Attribute) otlpJSONKeyValue {
+	var v otlpJSONValue
+	setInt := func(i int64) {
+		s := strconv.FormatInt(i, 10)
+		v.IntValue = &s
+	}
+	switch x := a.Value.(type) {
+	case string:
+		v.StringValue = &x
+	case bool:
+		v.BoolValue = &x
+	case int:
+		setInt(int64(x))
+	case int32:
+		setInt(int64(x))
+	case int64:
+		setInt(x)
+	case time.Duration:
+		setInt(int64(x))
+	case float32:
+		f := float64(x)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &x
+	default:
+		s := fmt.Sprint(x)
+		v.StringValue = &s
+	}
+	return otlpJSONKeyValue{Key: a.Key, Value: v}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomHex returns n random bytes encoded as lowercase hex.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var _ Tracer = (*OTLPJSONTracer)(nil)
@@ -0,0 +1,169 @@
+package toolruntime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// decodedSpan is the subset of an OTLP/JSON span checked by tests
+type decodedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	} `json:"attributes"`
+	Events []struct {
+		Name string `json:"name"`
+	} `json:"events"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+func decodeSpans(t *testing.T, buf *bytes.Buffer) map[string]decodedSpan {
+	t.Helper()
+	spans := make(map[string]decodedSpan)
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var doc struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []decodedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid OTLP JSON line %q: %v", scanner.Text(), err)
+		}
+		for _, rs := range doc.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans[s.Name] = s
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func TestStartSpanWithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	got, span := StartSpan(ctx, "noop")
+	if got != ctx {
+		t.Error("StartSpan() without tracer should return ctx unchanged")
+	}
+	span.SetAttributes(Attr("k", "v"))
+	span.AddEvent("e")
+	span.RecordError(errors.New("ignored"))
+	span.End()
+}
+
+func TestOTLPJSONTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewOTLPJSONTracer(&buf, "test")
+	ctx := ContextWithTracer(context.Background(), tracer)
+
+	ctx, parent := StartSpan(ctx, "parent", Attr("str", "v"), Attr("int", 3), Attr("dur", time.Second))
+	_, child := StartSpan(ctx, "child")
+	child.AddEvent("step", Attr("ok", true))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	child.End() // ignored
+	parent.End()
+
+	if err := tracer.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 2 {
+		t.Fatalf("wrote %d lines, want 2", lines)
+	}
+
+	spans := decodeSpans(t, &buf)
+	p, c := spans["parent"], spans["child"]
+	if len(p.TraceID) != 32 || len(p.SpanID) != 16 {
+		t.Errorf("parent ids = %q/%q, want 32/16 hex chars", p.TraceID, p.SpanID)
+	}
+	if c.TraceID != p.TraceID || c.ParentSpanID != p.SpanID {
+		t.Errorf("child trace/parent = %q/%q, want %q/%q", c.TraceID, c.ParentSpanID, p.TraceID, p.SpanID)
+	}
+	if c.Status.Code != otlpStatusError || c.Status.Message != "boom" {
+		t.Errorf("child status = %+v, want error %q", c.Status, "boom")
+	}
+	if len(c.Events) != 2 || c.Events[0].Name != "step" || c.Events[1].Name != "exception" {
+		t.Errorf("child events = %+v, want step and exception", c.Events)
+	}
+
+	wantAttrs := map[string]string{"str": "stringValue", "int": "intValue", "dur": "intValue"}
+	for _, a := range p.Attributes {
+		if kind, ok := wantAttrs[a.Key]; ok {
+			if _, ok := a.Value[kind]; !ok {
+				t.Errorf("attribute %q = %v, want %s", a.Key, a.Value, kind)
+			}
+			delete(wantAttrs, a.Key)
+		}
+	}
+	if len(wantAttrs) != 0 {
+		t.Errorf("missing attributes %v", wantAttrs)
+	}
+}
+
+func TestDefaultRuntimeTracing(t *testing.T) {
+	var buf bytes.Buffer
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &mockBackend{kind: BackendUnsafeHost},
+		},
+		Tracer:         NewOTLPJSONTracer(&buf, ""),
+		DefaultProfile: ProfileDev,
+	})
+
+	_, err := rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	spans := decodeSpans(t, &buf)
+	root, ok := spans["toolruntime.execute"]
+	if !ok {
+		t.Fatalf("missing toolruntime.execute span; got %v", spans)
+	}
+	for _, name := range []string{"toolruntime.validate", "toolruntime.admission", "toolruntime.route", "toolruntime.backend"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("missing %s span", name)
+			continue
+		}
+		if s.ParentSpanID != root.SpanID {
+			t.Errorf("%s parent = %q, want %q", name, s.ParentSpanID, root.SpanID)
+		}
+	}
+}
+
+func TestDefaultRuntimeTracingRecordsError(t *testing.T) {
+	var buf bytes.Buffer
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileDev: &mockBackend{kind: BackendUnsafeHost, executeErr: ErrTimeout},
+		},
+		Tracer:         NewOTLPJSONTracer(&buf, ""),
+		DefaultProfile: ProfileDev,
+	})
+
+	_, _ = rt.Execute(context.Background(), ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}})
+
+	spans := decodeSpans(t, &buf)
+	for _, name := range []string{"toolruntime.execute", "toolruntime.backend"} {
+		if spans[name].Status.Code != otlpStatusError {
+			t.Errorf("%s status = %+v, want error", name, spans[name].Status)
+		}
+	}
+}
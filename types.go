@@ -38,9 +38,72 @@ func (p SecurityProfile) IsValid() bool {
 	}
 }
 
+// MinIsolation returns the weakest isolation level a fallback backend may
+// provide for this profile.
+func (p SecurityProfile) MinIsolation() IsolationLevel {
+	switch p {
+	case ProfileStandard:
+		return IsolationContainer
+	case ProfileHardened:
+		return IsolationSandbox
+	default:
+		return IsolationNone
+	}
+}
+
+// IsolationLevel orders backends by the strength of the isolation they provide.
+// Higher values provide stronger isolation.
+type IsolationLevel int
+
+const (
+	// IsolationNone provides no isolation from the host.
+	IsolationNone IsolationLevel = iota
+
+	// IsolationContainer provides namespace/cgroup container isolation.
+	IsolationContainer
+
+	// IsolationSandbox provides a user-space kernel or in-process sandbox.
+	IsolationSandbox
+
+	// IsolationVM provides hardware virtualization.
+	IsolationVM
+)
+
+// String returns the isolation level name.
+func (l IsolationLevel) String() string {
+	switch l {
+	case IsolationNone:
+		return "none"
+	case IsolationContainer:
+		return "container"
+	case IsolationSandbox:
+		return "sandbox"
+	case IsolationVM:
+		return "vm"
+	default:
+		return fmt.Sprintf("IsolationLevel(%d)", int(l))
+	}
+}
+
 // BackendKind identifies the type of execution backend.
 type BackendKind string
 
+// Isolation returns the isolation level provided by the backend kind.
+// Temporal and remote backends report IsolationNone because their isolation
+// depends on what they compose with; unknown kinds are treated the same way.
+func (k BackendKind) Isolation() IsolationLevel {
+	switch k {
+	case BackendDocker, BackendContainerd, BackendKubernetes:
+		return IsolationContainer
+	case BackendGVisor, BackendWASM:
+		return IsolationSandbox
+	case BackendKata, BackendFirecracker:
+		return IsolationVM
+	default:
+		return IsolationNone
+	}
+}
+
 const (
 	// BackendUnsafeHost runs code directly on the host.
 	// WARNING: No isolation - use only for trusted code in development.
@@ -110,6 +173,23 @@ type Limits struct {
 	// DiskBytes limits disk usage in bytes.
 	// Zero means unlimited.
 	DiskBytes int64
+
+	// MaxStdoutBytes limits the stdout kept in ExecuteResult.Stdout. Output
+	// beyond it is dropped, or spilled to a file with
+	// ExecuteRequest.SpillOutput, and replaced by a truncation marker.
+	// Zero means unlimited.
+	MaxStdoutBytes int64
+
+	// MaxStderrBytes limits the stderr kept in ExecuteResult.Stderr, like
+	// MaxStdoutBytes.
+	// Zero means unlimited.
+	MaxStderrBytes int64
+
+	// MaxValueBytes limits the JSON-encoded size of ExecuteResult.Value.
+	// Larger values are replaced by a string holding the truncated encoding
+	// and a truncation marker.
+	// Zero means unlimited.
+	MaxValueBytes int64
 }
 
 // Validate checks that all limit values are valid (non-negative).
@@ -132,6 +212,15 @@ func (l Limits) Validate() error {
 	if l.DiskBytes < 0 {
 		return fmt.Errorf("%w: DiskBytes cannot be negative", ErrInvalidLimits)
 	}
+	if l.MaxStdoutBytes < 0 {
+		return fmt.Errorf("%w: MaxStdoutBytes cannot be negative", ErrInvalidLimits)
+	}
+	if l.MaxStderrBytes < 0 {
+		return fmt.Errorf("%w: MaxStderrBytes cannot be negative", ErrInvalidLimits)
+	}
+	if l.MaxValueBytes < 0 {
+		return fmt.Errorf("%w: MaxValueBytes cannot be negative", ErrInvalidLimits)
+	}
 	return nil
 }
 
@@ -145,6 +234,36 @@ type ExecuteRequest struct {
 	// Required.
 	Code string
 
+	// Files are additional workspace files, such as helper modules, data
+	// files or a go.mod, keyed by slash-separated path relative to the
+	// workspace root. Backends that support files (see Capabilities.Files)
+	// make them available in the working directory of the executed code.
+	Files map[string]File
+
+	// Stdin is written to the standard input of the executed code.
+	Stdin []byte
+
+	// Inputs are structured values made available to the executed code as
+	// the __in variable, mirroring the __out convention for results. They
+	// are passed JSON-encoded in the InputsEnv environment variable, so they
+	// must be JSON-encodable.
+	Inputs map[string]any
+
+	// Env holds environment variables set for the executed code.
+	Env map[string]string
+
+	// Secrets holds environment variables set for the executed code whose
+	// values are redacted, as Redacted, from Stdout, Stderr, Value, errors
+	// and stream events before they leave the runtime. Names may not also
+	// appear in Env, and names starting with TOOLRUNTIME_ are reserved.
+	Secrets map[string]string
+
+	// Artifacts, if set, collects the files the executed code writes to the
+	// output directory, named by the OutputDirEnv environment variable, into
+	// ExecuteResult.Artifacts. Backends that support artifacts (see
+	// Capabilities.Artifacts) enforce its size caps.
+	Artifacts *ArtifactSpec
+
 	// Timeout specifies the maximum duration for execution.
 	// If zero, the backend's default timeout is used.
 	Timeout time.Duration
@@ -152,6 +271,11 @@ type ExecuteRequest struct {
 	// Limits specifies resource limits for execution.
 	Limits Limits
 
+	// SpillOutput writes the full stdout or stderr to a temporary file when
+	// it exceeds Limits.MaxStdoutBytes or MaxStderrBytes. The files are named
+	// in ExecuteResult.StdoutFile and StderrFile.
+	SpillOutput bool
+
 	// Profile specifies the security profile to use.
 	// If empty, the runtime's default profile is used.
 	Profile SecurityProfile
@@ -162,6 +286,17 @@ type ExecuteRequest struct {
 
 	// Metadata contains arbitrary metadata for the execution.
 	Metadata map[string]any
+
+	// StrictLimits refuses, with ErrBackendDenied, to run the request on a
+	// backend that cannot enforce every requested limit (including Timeout),
+	// instead of running it with the limit silently unenforced.
+	StrictLimits bool
+
+	// ExecutionID identifies the execution for correlation across logs,
+	// traces, container labels and tool call records.
+	// If empty, the runtime uses the ID carried by the context, if any,
+	// or generates a new one.
+	ExecutionID string
 }
 
 // Validate checks that the request is valid.
@@ -175,27 +310,65 @@ func (r ExecuteRequest) Validate() error {
 	if err := r.Limits.Validate(); err != nil {
 		return err
 	}
+	if err := validateFiles(r.Files); err != nil {
+		return err
+	}
+	if r.Artifacts != nil {
+		if err := r.Artifacts.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateEnv(r.Env, r.Secrets); err != nil {
+		return err
+	}
+	if r.Inputs != nil {
+		if _, err := EncodeInputs(r.Inputs); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // ExecuteResult contains the outcome of code execution.
 type ExecuteResult struct {
+	// ExecutionID identifies the execution that produced this result.
+	ExecutionID string
+
 	// Value is the final result of the code execution.
-	// Typically captured via the __out variable convention.
+	// Typically captured via the __out variable convention and reported
+	// in the result envelope.
 	Value any
 
+	// Metadata is the metadata reported by the code in its result envelope.
+	Metadata map[string]any
+
 	// Stdout contains any output written to stdout.
 	Stdout string
 
 	// Stderr contains any output written to stderr.
 	Stderr string
 
+	// StdoutFile and StderrFile name temporary files holding the full stdout
+	// and stderr when they were truncated and ExecuteRequest.SpillOutput is
+	// set. The caller owns the files and should remove them, for example
+	// with RemoveSpillFiles.
+	StdoutFile string
+	StderrFile string
+
 	// ToolCalls records all tool invocations made during execution.
 	ToolCalls []ToolCallRecord
 
+	// Artifacts are the files collected from the output directory when
+	// ExecuteRequest.Artifacts is set, in path order.
+	Artifacts []Artifact
+
 	// Duration is the total execution time.
 	Duration time.Duration
 
+	// QueueWait is the time spent waiting for admission before execution,
+	// summed across profile and backend concurrency limits.
+	QueueWait time.Duration
+
 	// Backend contains information about the backend that executed the code.
 	Backend BackendInfo
 
@@ -228,6 +401,10 @@ type LimitsEnforced struct {
 
 	// Disk indicates whether disk limits were enforced.
 	Disk bool
+
+	// Output indicates whether stdout, stderr and value size limits were
+	// enforced.
+	Output bool
 }
 
 // ToolCallRecord captures information about a single tool invocation.
@@ -235,6 +412,9 @@ type ToolCallRecord struct {
 	// ToolID is the canonical identifier of the tool that was called.
 	ToolID string
 
+	// ExecutionID identifies the execution that made the call.
+	ExecutionID string
+
 	// BackendKind indicates which backend executed the tool.
 	BackendKind string
 
@@ -153,6 +153,21 @@ func TestLimitsValidate(t *testing.T) {
 			limits:  Limits{DiskBytes: -1},
 			wantErr: true,
 		},
+		{
+			name:    "negative MaxStdoutBytes invalid",
+			limits:  Limits{MaxStdoutBytes: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative MaxStderrBytes invalid",
+			limits:  Limits{MaxStderrBytes: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative MaxValueBytes invalid",
+			limits:  Limits{MaxValueBytes: -1},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -318,3 +333,49 @@ func TestBackendInfo(t *testing.T) {
 func errorIs(err, target error) bool {
 	return errors.Is(err, target)
 }
+
+func TestBackendKindIsolation(t *testing.T) {
+	tests := []struct {
+		kind BackendKind
+		want IsolationLevel
+	}{
+		{BackendUnsafeHost, IsolationNone},
+		{BackendDocker, IsolationContainer},
+		{BackendContainerd, IsolationContainer},
+		{BackendKubernetes, IsolationContainer},
+		{BackendGVisor, IsolationSandbox},
+		{BackendWASM, IsolationSandbox},
+		{BackendKata, IsolationVM},
+		{BackendFirecracker, IsolationVM},
+		{BackendTemporal, IsolationNone},
+		{BackendRemote, IsolationNone},
+		{BackendKind("unknown"), IsolationNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			if got := tt.kind.Isolation(); got != tt.want {
+				t.Errorf("BackendKind(%q).Isolation() = %v, want %v", tt.kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityProfileMinIsolation(t *testing.T) {
+	tests := []struct {
+		profile SecurityProfile
+		want    IsolationLevel
+	}{
+		{ProfileDev, IsolationNone},
+		{ProfileStandard, IsolationContainer},
+		{ProfileHardened, IsolationSandbox},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.profile), func(t *testing.T) {
+			if got := tt.profile.MinIsolation(); got != tt.want {
+				t.Errorf("SecurityProfile(%q).MinIsolation() = %v, want %v", tt.profile, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package toolruntime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultFileMode is the mode of workspace files that do not set one.
+const DefaultFileMode fs.FileMode = 0o644
+
+// File is a workspace file sent with an ExecuteRequest.
+type File struct {
+	// Content is the file content.
+	Content []byte
+
+	// Mode holds the file's permission bits.
+	// Default: 0644
+	Mode fs.FileMode
+}
+
+// Perm returns the file's permission bits, or DefaultFileMode if unset.
+func (f File) Perm() fs.FileMode {
+	if f.Mode == 0 {
+		return DefaultFileMode
+	}
+	return f.Mode
+}
+
+// FilesSize returns the total content size of files in bytes.
+func FilesSize(files map[string]File) int64 {
+	var size int64
+	for _, f := range files {
+		size += int64(len(f.Content))
+	}
+	return size
+}
+
+// validateFiles checks that every path is a clean, relative, slash-separated
+// path that stays inside the workspace, that no file is also used as a
+// directory, and that modes only hold permission bits.
+func validateFiles(files map[string]File) error {
+	for name, f := range files {
+		if !fs.ValidPath(name) || name == "." || strings.ContainsAny(name, "\\\x00") {
+			return fmt.Errorf("%w: path %q must be relative, clean and inside the workspace", ErrInvalidFile, name)
+		}
+		if f.Mode&^fs.ModePerm != 0 {
+			return fmt.Errorf("%w: %s: mode %v is not a permission mode", ErrInvalidFile, name, f.Mode)
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := files[dir]; ok {
+				return fmt.Errorf("%w: %s is both a file and the parent of %s", ErrInvalidFile, dir, name)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteFiles writes files into the existing directory dir, creating parent
+// directories as needed. It validates the paths first and writes through an
+// os.Root, so no file can be written outside dir, even through symlinks.
+// Backends that run code on a host filesystem use it to materialize
+// ExecuteRequest.Files.
+func WriteFiles(dir string, files map[string]File) error {
+	if err := validateFiles(files); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	// Write in path order so runs are deterministic
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if err := mkdirAll(root, path.Dir(name)); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		if err := writeFile(root, name, files[name]); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// mkdirAll creates dir and its parents inside root.
+func mkdirAll(root *os.Root, dir string) error {
+	if dir == "." {
+		return nil
+	}
+	if err := mkdirAll(root, path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := root.Mkdir(dir, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// writeFile creates name inside root with the file's content and mode.
+func writeFile(root *os.Root, name string, file File) error {
+	f, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(file.Content)
+	if err == nil {
+		// Apply the exact mode regardless of the umask
+		err = f.Chmod(file.Perm())
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
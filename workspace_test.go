@@ -0,0 +1,118 @@
+package toolruntime
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecuteRequestValidateFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]File
+		wantErr bool
+	}{
+		{"no files", nil, false},
+		{"nested files", map[string]File{"go.mod": {}, "lib/util/util.go": {Mode: 0o755}}, false},
+		{"empty path", map[string]File{"": {}}, true},
+		{"dot", map[string]File{".": {}}, true},
+		{"absolute", map[string]File{"/etc/passwd": {}}, true},
+		{"parent", map[string]File{"../escape": {}}, true},
+		{"inner parent", map[string]File{"lib/../../escape": {}}, true},
+		{"unclean", map[string]File{"lib//util.go": {}}, true},
+		{"trailing slash", map[string]File{"lib/": {}}, true},
+		{"backslash", map[string]File{`..\escape`: {}}, true},
+		{"file is also a directory", map[string]File{"lib": {}, "lib/util.go": {}}, true},
+		{"non-permission mode", map[string]File{"link": {Mode: fs.ModeSymlink | 0o777}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Files: tt.files}
+			err := req.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Validate() error = %v, want ErrInvalidFile", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]File{
+		"go.mod":           {Content: []byte("module x\n")},
+		"lib/util/util.go": {Content: []byte("package util\n"), Mode: 0o600},
+		"run.sh":           {Content: []byte("#!/bin/sh\n"), Mode: 0o755},
+	}
+	if err := WriteFiles(dir, files); err != nil {
+		t.Fatalf("WriteFiles() error = %v", err)
+	}
+
+	for name, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		data, err := os.ReadFile(p)
+		if err != nil || string(data) != string(f.Content) {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, f.Content)
+		}
+		if info, err := os.Stat(p); err != nil || info.Mode().Perm() != f.Perm() {
+			t.Errorf("%s mode = %v, want %v", name, info.Mode().Perm(), f.Perm())
+		}
+	}
+	if got := FilesSize(files); got != 32 {
+		t.Errorf("FilesSize() = %d, want 32", got)
+	}
+}
+
+func TestWriteFilesStaysInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	err := WriteFiles(dir, map[string]File{"link/escape.txt": {Content: []byte("x")}})
+	if err == nil {
+		t.Fatal("WriteFiles() through a symlink out of dir should fail")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("file written outside dir: %v", err)
+	}
+
+	if err := WriteFiles(dir, map[string]File{"../escape.txt": {}}); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("WriteFiles(../escape.txt) error = %v, want ErrInvalidFile", err)
+	}
+}
+
+func TestDefaultRuntimeSkipsBackendsWithoutFiles(t *testing.T) {
+	primary := &capableBackend{countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendDocker}}}
+	fallback := &capableBackend{
+		countingBackend: countingBackend{mockBackend: mockBackend{kind: BackendGVisor}},
+		caps:            Capabilities{Files: true},
+	}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: primary},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {fallback}},
+		DefaultProfile: ProfileStandard,
+	})
+
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}, Files: map[string]File{"data.txt": {}}}
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Backend.Kind != BackendGVisor || primary.calls != 0 {
+		t.Errorf("backend = %q, primary calls = %d; want gvisor and 0", result.Backend.Kind, primary.calls)
+	}
+
+	// Requests without files still use the primary
+	req.Files = nil
+	if _, err := rt.Execute(context.Background(), req); err != nil || primary.calls != 1 {
+		t.Errorf("Execute() without files = %v, primary calls = %d; want primary", err, primary.calls)
+	}
+}